@@ -0,0 +1,96 @@
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+
+	"docsgpt-cli/internal/approvals"
+	"docsgpt-cli/internal/display"
+
+	"github.com/spf13/cobra"
+)
+
+var approvalsRevokeAll bool
+
+var approvalsCmd = &cobra.Command{
+	Use:   "approvals",
+	Short: "List and revoke stored \"always allow\" approval rules",
+	Long: `Answering [4] Always… on a tool approval card stores a rule in
+~/.docsgpt/approvals.json: allow this exact command, commands starting with
+a prefix, or every call to the tool — for the current session, the current
+project directory (and below), or everywhere. Calls a rule covers run
+without asking; the command policy's deny rules still apply.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		cmd.Help()
+	},
+}
+
+var approvalsListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List approval rules",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		rules, err := approvals.List()
+		if err != nil {
+			return err
+		}
+		if len(rules) == 0 {
+			fmt.Println("No approval rules.")
+			return nil
+		}
+		for _, r := range rules {
+			fmt.Printf("%s  %s  %s\n",
+				display.Accent(fmt.Sprintf("%3d", r.ID)), r.Describe(),
+				display.Muted("("+r.Place()+", added "+r.Created.Local().Format("2006-01-02")+")"))
+		}
+		return nil
+	},
+}
+
+var approvalsRevokeCmd = &cobra.Command{
+	Use:   "revoke [id...]",
+	Short: "Remove approval rules",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var ids []int
+		if approvalsRevokeAll {
+			if len(args) > 0 {
+				return fmt.Errorf("give rule ids or --all, not both")
+			}
+			rules, err := approvals.List()
+			if err != nil {
+				return err
+			}
+			for _, r := range rules {
+				ids = append(ids, r.ID)
+			}
+		} else {
+			if len(args) == 0 {
+				return fmt.Errorf("give the ids of the rules to revoke (see 'docsgpt-cli approvals list'), or --all")
+			}
+			for _, a := range args {
+				id, err := strconv.Atoi(a)
+				if err != nil {
+					return fmt.Errorf("invalid rule id %q", a)
+				}
+				ids = append(ids, id)
+			}
+		}
+		removed, err := approvals.Revoke(ids...)
+		if err != nil {
+			return err
+		}
+		for _, r := range removed {
+			fmt.Println(display.Success(fmt.Sprintf("Revoked #%d: %s", r.ID, r.Describe())) + display.Muted(" ("+r.Place()+")"))
+		}
+		if len(removed) == 0 {
+			fmt.Println("No approval rules.")
+		}
+		return nil
+	},
+}
+
+func init() {
+	approvalsRevokeCmd.Flags().BoolVar(&approvalsRevokeAll, "all", false, "Revoke every rule")
+	approvalsCmd.AddCommand(approvalsListCmd)
+	approvalsCmd.AddCommand(approvalsRevokeCmd)
+}
@@ -1,33 +1,73 @@
 package cmd
 
 import (
-	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"strings"
 	"time"
 
 	"docsgpt-cli/internal/api"
+	"docsgpt-cli/internal/approvals"
 	"docsgpt-cli/internal/config"
 	ctxenrich "docsgpt-cli/internal/context"
 	"docsgpt-cli/internal/display"
+	"docsgpt-cli/internal/policy"
+	"docsgpt-cli/internal/session"
 	"docsgpt-cli/internal/tools"
+	"docsgpt-cli/internal/undo"
 
 	"github.com/spf13/cobra"
 )
 
+var (
+	askOutput  string
+	askFiles   []string
+	askSources bool
+	askNoStdin bool
+)
+
+// Output formats for ask.
+const (
+	askOutputText = "text"
+	askOutputJSON = "json"
+)
+
+// maxStdinBytes bounds what ask reads from a pipe.
+const maxStdinBytes = 2 << 20
+
 var askCmd = &cobra.Command{
-	Use:   "ask",
+	Use:   "ask [question]",
 	Short: "Ask a question to DocsGPT",
 	Long: `Ask a question to DocsGPT, and instantly find answers about anything.
 
 Example usage:
     docsgpt-cli ask "How do I open a file in Python?"
+    git diff | docsgpt-cli ask "review this"
+    docsgpt-cli ask -f crash.log -f config.yaml "why does it fail to start?"
+    docsgpt-cli ask --output json "list the open ports" | jq -r .answer
+
+Piped stdin is appended to the question (or is the question, when no
+arguments are given). Only a pipe or a redirected file is read, never an
+inherited terminal or socket; --no-stdin ignores stdin altogether, as in a
+git hook that receives refs on stdin. When stdout is not a terminal only the raw answer is
+printed: no header, no markdown rendering, no clipboard. --output json
+prints one JSON document with the answer, sources, tool calls, usage and
+conversation id.
 
-This command will provide a contextual answer and, if applicable, copy a relevant code snippet to your clipboard.`,
+Retrieved sources are listed under the answer in a terminal; --sources
+shows their full text, and adds the list to piped output too.
+
+In a terminal, this command will provide a contextual answer and, if applicable, copy a relevant code snippet to your clipboard.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		if len(args) == 0 {
-			return fmt.Errorf("please provide a question")
+		if askOutput != askOutputText && askOutput != askOutputJSON {
+			return fmt.Errorf("invalid --output %q (use text or json)", askOutput)
+		}
+		question, err := askQuestion(args)
+		if err != nil {
+			return err
 		}
 
 		cfg, err := config.Load()
@@ -35,29 +75,58 @@ This command will provide a contextual answer and, if applicable, copy a relevan
 			return err
 		}
 
+		if err := applyGitContextFlags(cmd, &cfg.Settings); err != nil {
+			return err
+		}
+
 		keyName, apiKey, err := cfg.ResolveKey(globalKey)
 		if err != nil {
 			return err
 		}
 
 		baseURL := cfg.ResolveURL(globalURL)
-		client := api.NewClient(baseURL, apiKey)
+		client := newAPIClient(cfg, baseURL, apiKey)
+		if err := configureTools(cfg); err != nil {
+			return err
+		}
 
-		question := strings.Join(args, " ")
 		includeContext := !globalNoContext
 		fullQuestion := ctxenrich.BuildQuestion(question, cfg.Settings, includeContext)
 
+		// Ctrl-C cancels the request (and any upload) instead of killing
+		// the process, so what has streamed so far is kept. During a
+		// run_command it stops just the command.
+		ctx, stop := interruptContext()
+		defer stop()
+
 		messages := []api.Message{
 			{Role: "user", Content: fullQuestion},
 		}
+		if len(askFiles) > 0 {
+			atts, err := uploadFiles(ctx, baseURL, apiKey, askFiles)
+			if err != nil {
+				return err
+			}
+			messages[0].Attachments = atts
+		}
 
-		cwd, _ := os.Getwd()
-		fmt.Println(display.RenderHeader(keyName, baseURL, cwd))
-		fmt.Print(display.Prompt("❯ "))
+		// Decorations only when a person is reading stdout; otherwise the
+		// answer is the whole output and everything else goes to stderr.
+		interactive := askOutput == askOutputText && stdoutIsTTY()
+		if !interactive {
+			tools.Prompts = os.Stderr
+		}
+
+		if interactive {
+			cwd, _ := os.Getwd()
+			fmt.Println(display.RenderHeader(keyName, baseURL, cwd))
+			fmt.Print(display.Prompt("❯ "))
+		}
 
-		ctx := context.Background()
 		var toolDefs []api.Tool
 		if !globalNoContext {
+			loadCustomTools()
+			defer startMCP()()
 			toolDefs = tools.ToolDefinitions()
 		}
 		timeout := time.Duration(globalTimeout) * time.Second
@@ -65,32 +134,243 @@ This command will provide a contextual answer and, if applicable, copy a relevan
 		renderer := display.NewStreamRenderer()
 
 		onDelta := func(delta api.Delta, finishReason string) {
+			if askOutput == askOutputJSON {
+				return
+			}
 			renderer.Delta(delta)
 		}
 
-		onToolCall := func(tc api.ToolCall) string {
-			return handleToolCall(ctx, tc, timeout)
+		// Piped stdin leaves nobody to answer an approval prompt; only
+		// commands the policy allows and calls covered by a stored
+		// approval rule still run.
+		canApprove := globalAutoApprove || stdinIsTTY()
+		onToolCalls := func(calls []api.ToolCall) []string {
+			if canApprove {
+				return handleToolCalls(ctx, calls, timeout)
+			}
+			results := make([]string, len(calls))
+			for i, tc := range calls {
+				name := tools.NormalizeName(tc.Function.Name)
+				allowed := tools.PreApproved(name, tc.Function.Arguments) != nil
+				_, d := tools.CallPolicy(name, tc.Function.Arguments)
+				allowed = d.Verdict == policy.Allow || (allowed && d.Verdict != policy.Deny)
+				if allowed {
+					results[i] = handleToolCall(ctx, tc, timeout)
+					continue
+				}
+				fmt.Fprintln(os.Stderr, display.Muted("Skipped tool call "+tc.Function.Name+": no terminal to approve it (use --auto-approve)."))
+				results[i] = "The user cannot approve tool calls in this non-interactive run, so this call was skipped. Answer from what you already know."
+			}
+			return results
 		}
 
-		_, err = client.RunWithTools(
-			ctx, messages, toolDefs, !globalNoStream, onDelta, onToolCall,
+		// ask is not a saved session, but its file writes still get an
+		// undo journal of their own.
+		journal := undo.Open(session.NewID())
+		tools.Journal = journal
+		cwd, _ := os.Getwd()
+		tools.ApprovalScope = approvals.Where{Session: journal.ID, Project: cwd, Transient: true}
+		defer func() {
+			if entries, _ := journal.Entries(); len(entries) > 0 {
+				fmt.Fprintln(os.Stderr, display.Muted("Tools changed files; revert with: docsgpt-cli undo --session "+journal.ID))
+			}
+		}()
+
+		turn, err := client.RunWithToolBatches(
+			ctx, "", messages, toolDefs, !globalNoStream, onDelta, onToolCalls,
 		)
-		if err != nil {
-			return err
+		interrupted := ctx.Err() != nil
+		incomplete := errors.Is(err, api.ErrIncompleteStream)
+		if err != nil && !incomplete && !interrupted {
+			return errors.New(friendlyError(err))
 		}
-		fmt.Println()
-
-		// Render markdown for the final answer if it contains formatting
-		if rendered := renderer.Finish(); rendered != "" {
-			fmt.Print(rendered)
+		answer := lastAssistantContent(turn.History)
+		sources := answerSources(turn.History)
+		var cost *turnCost
+		if turn.Usage != nil && !interrupted && (interactive || askOutput == askOutputJSON) {
+			tc := priceUsage(*turn.Usage, fetchModelList(client), turn.Model, client.Model)
+			cost = &tc
 		}
 
-		answer := renderer.Content()
-		command := extractCommand(answer)
-		if command != "" {
-			copyToClipboard(command)
+		switch {
+		case askOutput == askOutputJSON:
+			if werr := writeAskJSON(os.Stdout, turn, client.Model, answer, cost, incomplete || interrupted); werr != nil {
+				return werr
+			}
+		case !interactive:
+			// Streamed content is already out; a non-streamed answer is not.
+			if renderer.Content() == "" {
+				fmt.Print(answer)
+			}
+			if answer != "" && !strings.HasSuffix(answer, "\n") {
+				fmt.Println()
+			}
+			if askSources && len(sources) > 0 {
+				fmt.Println()
+				fmt.Print(display.RenderSources(sources, true))
+			}
+		case incomplete || interrupted:
+			// Everything that streamed is already on screen; a non-streamed
+			// partial answer is not.
+			fmt.Println()
+			if renderer.Content() == "" && answer != "" {
+				fmt.Print(display.RenderMarkdown(answer))
+			}
+			fmt.Print(display.RenderSources(sources, askSources))
+		default:
+			fmt.Println()
+
+			// Render markdown for the final answer if it contains formatting
+			if renderer.Content() == "" {
+				fmt.Print(display.RenderMarkdown(answer))
+			} else if rendered := renderer.Finish(); rendered != "" {
+				fmt.Print(rendered)
+			}
+			if footer := display.RenderSources(sources, askSources); footer != "" {
+				fmt.Println()
+				fmt.Print(footer)
+			}
+			if cost != nil {
+				fmt.Println()
+				fmt.Println(usageStatusLine(*cost, nil, 0))
+			}
+
+			command := extractCommand(answer)
+			if command != "" {
+				copyToClipboard(command)
+			}
 		}
 
+		switch {
+		case interrupted && answer != "":
+			return errors.New("interrupted; the answer above is incomplete")
+		case interrupted:
+			return errors.New("interrupted")
+		case incomplete && errors.Is(err, api.ErrRequestTimeout):
+			return fmt.Errorf("the request timed out after %s before the answer finished; the answer above is incomplete (see --request-timeout)", client.RequestTimeout)
+		case incomplete:
+			return fmt.Errorf("the connection dropped before the answer finished; the answer above is incomplete")
+		}
 		return nil
 	},
 }
+
+func init() {
+	askCmd.Flags().StringVarP(&askOutput, "output", "o", askOutputText, "Output format: text or json")
+	askCmd.Flags().StringArrayVarP(&askFiles, "file", "f", nil, "Attach a file to the question (repeatable)")
+	askCmd.Flags().BoolVar(&askSources, "sources", false, "Show the full text of retrieved sources (also in piped output)")
+	askCmd.Flags().BoolVar(&askNoStdin, "no-stdin", false, "Do not read piped stdin into the question")
+	addGitContextFlags(askCmd)
+}
+
+// askQuestion joins the arguments and any piped stdin into the question.
+func askQuestion(args []string) (string, error) {
+	question := strings.Join(args, " ")
+	var input string
+	if !askNoStdin && stdinIsPiped() {
+		data, err := io.ReadAll(io.LimitReader(os.Stdin, maxStdinBytes+1))
+		if err != nil {
+			return "", fmt.Errorf("read stdin: %w", err)
+		}
+		if len(data) > maxStdinBytes {
+			return "", fmt.Errorf("stdin input is larger than %d MB", maxStdinBytes>>20)
+		}
+		input = strings.TrimRight(string(data), "\n")
+	}
+	switch {
+	case strings.TrimSpace(question) == "" && strings.TrimSpace(input) == "":
+		return "", fmt.Errorf("please provide a question")
+	case strings.TrimSpace(input) == "":
+		return question, nil
+	case strings.TrimSpace(question) == "":
+		return input, nil
+	}
+	fence := "```"
+	for strings.Contains(input, fence) {
+		fence += "`"
+	}
+	return question + "\n\n" + fence + "\n" + input + "\n" + fence, nil
+}
+
+// stdinIsPiped reports whether stdin is a pipe or a regular file, the only
+// kinds ask reads. A terminal, /dev/null or a socket inherited from a
+// parent process is left alone, so ask never waits on input nobody sends.
+func stdinIsPiped() bool {
+	info, err := os.Stdin.Stat()
+	if err != nil {
+		return false
+	}
+	mode := info.Mode()
+	return mode&os.ModeNamedPipe != 0 || mode.IsRegular()
+}
+
+// askJSON is the --output json document.
+type askJSON struct {
+	Answer         string           `json:"answer"`
+	ConversationID string           `json:"conversation_id,omitempty"`
+	Model          string           `json:"model,omitempty"`
+	Attachments    []api.Attachment `json:"attachments,omitempty"`
+	Sources        json.RawMessage  `json:"sources"`
+	ToolCalls      []askToolCall    `json:"tool_calls"`
+	Usage          *api.Usage       `json:"usage"`
+	CostUSD        *float64         `json:"cost_usd,omitempty"`
+	Incomplete     bool             `json:"incomplete,omitempty"`
+}
+
+type askToolCall struct {
+	ID        string          `json:"id"`
+	Name      string          `json:"name"`
+	Arguments json.RawMessage `json:"arguments"`
+	Result    string          `json:"result"`
+}
+
+func writeAskJSON(w io.Writer, turn api.TurnResult, model, answer string, cost *turnCost, incomplete bool) error {
+	doc := askJSON{
+		Answer:         answer,
+		ConversationID: turn.ConversationID,
+		Model:          model,
+		Attachments:    turn.History[0].Attachments,
+		Sources:        turn.Sources,
+		ToolCalls:      collectToolCalls(turn.History),
+		Usage:          turn.Usage,
+		Incomplete:     incomplete,
+	}
+	if turn.Model != "" {
+		doc.Model = turn.Model
+	}
+	if cost != nil && cost.Priced {
+		doc.CostUSD = &cost.Cost
+	}
+	if len(doc.Sources) == 0 {
+		doc.Sources = json.RawMessage("[]")
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(doc)
+}
+
+// collectToolCalls pairs each tool call in history with its result.
+func collectToolCalls(history []api.Message) []askToolCall {
+	results := map[string]string{}
+	for _, m := range history {
+		if m.Role == "tool" {
+			results[m.ToolCallID] = m.Content
+		}
+	}
+	calls := []askToolCall{}
+	for _, m := range history {
+		for _, tc := range m.ToolCalls {
+			args := json.RawMessage(tc.Function.Arguments)
+			if !json.Valid(args) {
+				args, _ = json.Marshal(tc.Function.Arguments)
+			}
+			calls = append(calls, askToolCall{
+				ID:        tc.ID,
+				Name:      tc.Function.Name,
+				Arguments: args,
+				Result:    results[tc.ID],
+			})
+		}
+	}
+	return calls
+}
@@ -0,0 +1,137 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"docsgpt-cli/internal/api"
+)
+
+// withStdin replaces os.Stdin with a pipe carrying input for the test.
+func withStdin(t *testing.T, input string) {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.WriteString(input)
+	w.Close()
+	orig := os.Stdin
+	os.Stdin = r
+	t.Cleanup(func() { os.Stdin = orig; r.Close() })
+}
+
+func TestAskQuestion(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		stdin   string
+		want    string
+		wantErr bool
+	}{
+		{"args only", []string{"what", "is", "this"}, "", "what is this", false},
+		{"stdin only", nil, "explain me\n", "explain me", false},
+		{"args and stdin", []string{"review"}, "+added\n", "review\n\n```\n+added\n```", false},
+		{"stdin with fences", []string{"review"}, "```go\nx\n```", "review\n\n````\n```go\nx\n```\n````", false},
+		{"nothing", nil, "  \n", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			withStdin(t, tt.stdin)
+			got, err := askQuestion(tt.args)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("askQuestion = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAskQuestionStdinKinds(t *testing.T) {
+	withStdin(t, "refs/heads/main 1234\n")
+	askNoStdin = true
+	got, err := askQuestion([]string{"why"})
+	askNoStdin = false
+	if err != nil || got != "why" {
+		t.Errorf("--no-stdin: askQuestion = %q, %v; want the arguments only", got, err)
+	}
+
+	devNull, err := os.Open(os.DevNull)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer devNull.Close()
+	orig := os.Stdin
+	os.Stdin = devNull
+	t.Cleanup(func() { os.Stdin = orig })
+	if stdinIsPiped() {
+		t.Error("a character device is not piped input")
+	}
+
+	file := filepath.Join(t.TempDir(), "in.txt")
+	os.WriteFile(file, []byte("from a file"), 0o600)
+	f, _ := os.Open(file)
+	defer f.Close()
+	os.Stdin = f
+	if got, _ := askQuestion(nil); got != "from a file" {
+		t.Errorf("redirected file: askQuestion = %q", got)
+	}
+}
+
+func TestWriteAskJSON(t *testing.T) {
+	turn := api.TurnResult{
+		ConversationID: "conv-1",
+		Usage:          &api.Usage{PromptTokens: 3, CompletionTokens: 1, TotalTokens: 4},
+		History: []api.Message{
+			{Role: "user", Content: "q"},
+			{Role: "assistant", ToolCalls: []api.ToolCall{
+				{ID: "c1", Function: api.FunctionCall{Name: "read_file", Arguments: `{"path":"go.mod"}`}},
+				{ID: "c2", Function: api.FunctionCall{Name: "run_command", Arguments: `{"command":`}},
+			}},
+			{Role: "tool", ToolCallID: "c1", Content: "module x"},
+			{Role: "tool", ToolCallID: "c2", Content: "denied"},
+			{Role: "assistant", Content: "It is x."},
+		},
+	}
+	var buf bytes.Buffer
+	if err := writeAskJSON(&buf, turn, "m1", "It is x.", &turnCost{Cost: 0.5, Priced: true}, false); err != nil {
+		t.Fatal(err)
+	}
+	var doc struct {
+		Answer         string
+		ConversationID string `json:"conversation_id"`
+		Model          string
+		Sources        []any
+		ToolCalls      []struct {
+			ID        string
+			Name      string
+			Arguments any
+			Result    string
+		} `json:"tool_calls"`
+		Usage   api.Usage
+		CostUSD float64 `json:"cost_usd"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("invalid JSON: %v\n%s", err, buf.String())
+	}
+	if doc.Answer != "It is x." || doc.ConversationID != "conv-1" || doc.Model != "m1" || doc.Usage.TotalTokens != 4 || doc.CostUSD != 0.5 {
+		t.Errorf("doc = %+v", doc)
+	}
+	if doc.Sources == nil || len(doc.Sources) != 0 {
+		t.Errorf("sources = %v, want an empty array", doc.Sources)
+	}
+	if len(doc.ToolCalls) != 2 || doc.ToolCalls[0].Result != "module x" || doc.ToolCalls[1].Result != "denied" {
+		t.Fatalf("tool calls = %+v", doc.ToolCalls)
+	}
+	if args, ok := doc.ToolCalls[0].Arguments.(map[string]any); !ok || args["path"] != "go.mod" {
+		t.Errorf("valid arguments should be embedded as JSON, got %#v", doc.ToolCalls[0].Arguments)
+	}
+	if doc.ToolCalls[1].Arguments != `{"command":` {
+		t.Errorf("invalid arguments should be kept as a string, got %#v", doc.ToolCalls[1].Arguments)
+	}
+}
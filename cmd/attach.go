@@ -0,0 +1,156 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"docsgpt-cli/internal/api"
+	"docsgpt-cli/internal/bench/target"
+	ctxenrich "docsgpt-cli/internal/context"
+	"docsgpt-cli/internal/display"
+
+	prompt "github.com/elk-language/go-prompt"
+)
+
+// attachPollInterval is how often a pending attachment's parse task is
+// checked. Interactive use favours a shorter wait than bench's default.
+const attachPollInterval = time.Second
+
+// attachTimeout bounds one file's upload and server-side parsing.
+const attachTimeout = 5 * time.Minute
+
+// uploadFiles uploads each file to the server's attachment store, reporting
+// progress on stderr, and returns the attachments in the order given.
+func uploadFiles(ctx context.Context, baseURL, apiKey string, paths []string) ([]api.Attachment, error) {
+	var out []api.Attachment
+	for _, p := range paths {
+		path, err := checkAttachmentPath(p)
+		if err != nil {
+			return nil, err
+		}
+		name := filepath.Base(path)
+		upload, cleanup, err := redactedAttachment(path)
+		if err != nil {
+			return nil, err
+		}
+		fmt.Fprintln(os.Stderr, display.Muted("Uploading "+name+"..."))
+
+		uctx, cancel := context.WithTimeout(ctx, attachTimeout)
+		ids, err := target.UploadAttachments(uctx, baseURL, apiKey, []string{upload}, attachPollInterval)
+		cancel()
+		cleanup()
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, api.Attachment{ID: ids[0], Name: name})
+	}
+	return out, nil
+}
+
+// redactedAttachment returns the file to upload for path: path itself when
+// there is nothing to mask, or a temporary copy under the same name with
+// its secrets masked, which cleanup removes. A binary file, such as a PDF,
+// cannot be masked; it is uploaded as it is, with a warning.
+func redactedAttachment(path string) (upload string, cleanup func(), err error) {
+	noop := func() {}
+	if ctxenrich.Redactor == nil {
+		return path, noop, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", noop, fmt.Errorf("cannot attach %s: %w", path, err)
+	}
+	name := filepath.Base(path)
+	if !utf8.Valid(data) || bytes.IndexByte(data, 0) >= 0 {
+		fmt.Fprintln(os.Stderr, display.Warn("warning:"), name+" is not a text file; it is uploaded without masking secrets")
+		return path, noop, nil
+	}
+	masked := ctxenrich.Redactor.Redact("attachment "+name, string(data))
+	if masked == string(data) {
+		return path, noop, nil
+	}
+	dir, err := os.MkdirTemp("", "docsgpt-attach-*")
+	if err != nil {
+		return "", noop, err
+	}
+	cleanup = func() { os.RemoveAll(dir) }
+	upload = filepath.Join(dir, name)
+	if err := os.WriteFile(upload, []byte(masked), 0o600); err != nil {
+		cleanup()
+		return "", noop, err
+	}
+	return upload, cleanup, nil
+}
+
+// checkAttachmentPath expands a leading ~ and makes sure path is a regular
+// file, so a typo fails before anything is uploaded.
+func checkAttachmentPath(path string) (string, error) {
+	if rest, ok := strings.CutPrefix(path, "~/"); ok {
+		if home, err := os.UserHomeDir(); err == nil {
+			path = filepath.Join(home, rest)
+		}
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		var pathErr *fs.PathError
+		if errors.As(err, &pathErr) {
+			err = pathErr.Err
+		}
+		return "", fmt.Errorf("cannot attach %s: %w", path, err)
+	}
+	if !info.Mode().IsRegular() {
+		return "", fmt.Errorf("cannot attach %s: not a regular file", path)
+	}
+	return path, nil
+}
+
+// attachmentNames lists the names of atts for display.
+func attachmentNames(atts []api.Attachment) string {
+	names := make([]string, len(atts))
+	for i, a := range atts {
+		names[i] = a.Name
+	}
+	return strings.Join(names, ", ")
+}
+
+// pathSuggestions completes a partially typed file path from the directory
+// it names. Hidden entries are only offered once the user types a dot.
+func pathSuggestions(partial string) []prompt.Suggest {
+	dir, base := filepath.Split(partial)
+	lookup := dir
+	if lookup == "" {
+		lookup = "."
+	} else if rest, ok := strings.CutPrefix(lookup, "~/"); ok {
+		if home, err := os.UserHomeDir(); err == nil {
+			lookup = filepath.Join(home, rest)
+		}
+	}
+	entries, err := os.ReadDir(lookup)
+	if err != nil {
+		return nil
+	}
+	var out []prompt.Suggest
+	for _, e := range entries {
+		name := e.Name()
+		if !strings.HasPrefix(name, base) || (strings.HasPrefix(name, ".") && !strings.HasPrefix(base, ".")) {
+			continue
+		}
+		s := prompt.Suggest{Text: dir + name}
+		if e.IsDir() {
+			s.Text += string(filepath.Separator)
+			s.Description = "directory"
+		}
+		out = append(out, s)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Text < out[j].Text })
+	return out
+}
@@ -5,81 +5,313 @@ import (
 	"errors"
 	"fmt"
 	"os"
-	"os/signal"
 	"strings"
+	"sync"
 	"time"
 
 	"docsgpt-cli/internal/api"
+	"docsgpt-cli/internal/approvals"
 	"docsgpt-cli/internal/config"
 	ctxenrich "docsgpt-cli/internal/context"
 	"docsgpt-cli/internal/display"
+	"docsgpt-cli/internal/export"
+	"docsgpt-cli/internal/policy"
+	"docsgpt-cli/internal/session"
 	"docsgpt-cli/internal/tools"
+	"docsgpt-cli/internal/undo"
 
 	prompt "github.com/elk-language/go-prompt"
 	pstrings "github.com/elk-language/go-prompt/strings"
 	"github.com/spf13/cobra"
 )
 
+var chatResume string
+
 var chatCmd = &cobra.Command{
 	Use:   "chat",
 	Short: "Start an interactive chat session",
 	Long: `Start an interactive multi-turn chat session with DocsGPT.
 
+Every session is saved under ~/.docsgpt/sessions. Pick one up again with
+--resume (the most recent session) or --resume <id>; see 'docsgpt-cli
+sessions' to browse them.
+
 Special commands:
-    /quit   - Exit the chat session
-    /clear  - Clear conversation history
-    /copy   - Copy the last code block to clipboard
-    /think  - Toggle reasoning visibility
+    /quit      - Exit the chat session
+    /clear     - Clear conversation history (starts a new session)
+    /copy      - Copy the last code block to clipboard
+    /think     - Toggle reasoning visibility
+    /sessions  - List saved sessions, or switch with /sessions <id>
+    /export    - Write the transcript to a file (.md, .json or .html)
+    /retry     - Regenerate the last answer (e.g. after a dropped connection)
+    /model     - Show the model and the server's catalog, or switch with /model <id>
+    /attach    - Attach a file to your next message (/attach <path>)
+    /sources   - Show the full sources behind the last answer
+    /usage     - Show token usage and estimated cost for the last answer and the session
+    /undo      - Revert the last file change made by a tool (/undo --force if edited since)
+    /changes   - List the files tools changed in this session, with a diff
 
 Keys: Ctrl+C interrupts a streaming answer (or clears the input line),
 Ctrl+D on an empty line exits. Type "/" to see available commands with
 live autocomplete.`,
+	Args: cobra.MaximumNArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		cfg, err := config.Load()
 		if err != nil {
 			return err
 		}
+		if err := applyGitContextFlags(cmd, &cfg.Settings); err != nil {
+			return err
+		}
 
-		keyName, apiKey, err := cfg.ResolveKey(globalKey)
+		// --resume has an optional value, so `--resume <id>` arrives as a
+		// positional argument; fold it back in.
+		var sess *session.Session
+		if cmd.Flags().Changed("resume") {
+			ref := chatResume
+			if ref == "last" && len(args) == 1 {
+				ref = args[0]
+			}
+			sess, err = session.Load(ref)
+			if err != nil {
+				return err
+			}
+		} else if len(args) > 0 {
+			return fmt.Errorf("unexpected argument %q (did you mean --resume %s?)", args[0], args[0])
+		}
+
+		// A resumed session keeps its key and server unless overridden.
+		keyRef := globalKey
+		if keyRef == "" && sess != nil {
+			if _, ok := cfg.Keys[sess.KeyName]; ok {
+				keyRef = sess.KeyName
+			}
+		}
+		keyName, apiKey, err := cfg.ResolveKey(keyRef)
 		if err != nil {
 			return err
 		}
 
 		baseURL := cfg.ResolveURL(globalURL)
-		client := api.NewClient(baseURL, apiKey)
+		if sess != nil && globalURL == "" && sess.BaseURL != "" {
+			baseURL = sess.BaseURL
+		}
+		client := newAPIClient(cfg, baseURL, apiKey)
+		if sess != nil && globalModel == "" && sess.Model != "" {
+			client.Model = sess.Model
+		}
+		if err := configureTools(cfg); err != nil {
+			return err
+		}
 
 		cwd, _ := os.Getwd()
 		fmt.Println(display.RenderHeader(keyName, baseURL, cwd))
+		if tools.Sandbox != nil {
+			fmt.Println(display.Muted(describeSandbox(tools.Sandbox)))
+		}
+		if !globalNoContext {
+			loadCustomTools()
+			defer startMCP()()
+		}
 		if hints := display.RenderHints("chat"); hints != "" {
 			fmt.Println(hints)
 		}
 		fmt.Println()
 
-		var history []api.Message
+		if sess != nil {
+			sess.KeyName, sess.BaseURL = keyName, baseURL
+			printResumeRecap(sess)
+			return runChatLoop(client, sess)
+		}
+
+		sess = session.New(keyName, baseURL)
+		sess.Model = client.Model
 
 		// Optionally add context as system message
 		if !globalNoContext {
 			ctx := ctxenrich.BuildContext(cfg.Settings)
 			if ctx != "" {
-				history = append(history, api.Message{
+				sess.Messages = append(sess.Messages, api.Message{
 					Role:    "system",
 					Content: "Here is context about the user's environment:\n" + ctx,
 				})
 			}
 		}
 
-		return runChatLoop(client, history)
+		return runChatLoop(client, sess)
 	},
 }
 
+func init() {
+	chatCmd.Flags().StringVar(&chatResume, "resume", "", "Resume a saved session by id (default: the most recent)")
+	chatCmd.Flags().Lookup("resume").NoOptDefVal = "last"
+	addGitContextFlags(chatCmd)
+}
+
+// resumeRecapTurns is how many trailing user turns are replayed on resume.
+const resumeRecapTurns = 1
+
+// printResumeRecap reminds the user where a resumed session left off by
+// replaying its last exchange.
+func printResumeRecap(sess *session.Session) {
+	fmt.Println(display.Muted(fmt.Sprintf("Resumed session %s (%d turns, last active %s ago)",
+		sess.ID, sess.UserTurns(), humanDuration(time.Since(sess.UpdatedAt)))))
+	fmt.Println()
+
+	start := len(sess.Messages)
+	for seen := 0; start > 0 && seen < resumeRecapTurns; {
+		start--
+		if sess.Messages[start].Role == "user" {
+			seen++
+		}
+	}
+	printTranscript(sess.Messages[start:])
+	fmt.Println()
+}
+
 // chatSession holds the mutable state for an interactive chat.
 type chatSession struct {
 	client        *api.Client
+	session       *session.Session
 	history       []api.Message
 	lastAnswer    string
 	showReasoning bool
 	toolDefs      []api.Tool
 	timeout       time.Duration
+
+	modelsOnce sync.Once
+	modelList  *api.ModelList
+
+	// pending holds files uploaded with /attach, sent with the next message.
+	pending []api.Attachment
+	// lastCost is the priced usage of the last answer (nil until the server
+	// reports usage).
+	lastCost *turnCost
+}
+
+// save persists the current history to the session file. Failures are
+// reported but never interrupt the chat.
+func (s *chatSession) save() {
+	s.session.Messages = s.history
+	s.session.Model = s.client.Model
+	if err := s.session.Save(); err != nil {
+		fmt.Println(display.Warn("Could not save session: " + err.Error()))
+	}
+}
+
+// switchSession replaces the active conversation with a saved one.
+func (s *chatSession) switchSession(ref string) {
+	sess, err := session.Load(ref)
+	if err != nil {
+		printError(err.Error())
+		return
+	}
+	if sess.BaseURL != s.session.BaseURL {
+		fmt.Println(display.Warn("Session was recorded against " + sess.BaseURL +
+			"; continuing on " + s.session.BaseURL + "."))
+	}
+	sess.KeyName, sess.BaseURL = s.session.KeyName, s.session.BaseURL
+	if globalModel == "" && sess.Model != "" {
+		s.client.Model = sess.Model
+	}
+	s.session = sess
+	s.history = sess.Messages
+	s.lastAnswer = lastAssistantContent(sess.Messages)
+	// Files queued with /attach and the last turn's cost belong to the
+	// conversation being left.
+	s.pending = nil
+	s.lastCost = nil
+	printResumeRecap(sess)
+}
+
+// exportTranscript writes the current conversation to path, inferring the
+// format from its extension. An empty path defaults to <session-id>.md in
+// the working directory.
+func (s *chatSession) exportTranscript(path string) {
+	if path == "" {
+		path = s.session.ID + ".md"
+	}
+	s.session.Messages = s.history
+	if err := writeExportFile(path, export.FormatForPath(path), s.session); err != nil {
+		printError(err.Error())
+		return
+	}
+	fmt.Println(display.Success("Transcript exported to " + path))
+}
+
+// models returns the server's model catalog, fetched once per chat. It
+// returns nil when the catalog is unavailable.
+func (s *chatSession) models() *api.ModelList {
+	s.modelsOnce.Do(func() {
+		s.modelList = fetchModelList(s.client)
+	})
+	return s.modelList
+}
+
+// setModel handles /model: without an argument it shows the current model
+// and the catalog; "default" hands the choice back to the server.
+func (s *chatSession) setModel(arg string) {
+	list := s.models()
+	if arg == "" {
+		fmt.Println(display.KeyValue("model:", modelLabel(s.client.Model, list)))
+		if list == nil || len(list.Models) == 0 {
+			fmt.Println(display.Muted("The server did not return a model catalog."))
+			return
+		}
+		for _, m := range list.Models {
+			marker := "  "
+			if m.ID == s.client.Model {
+				marker = display.Accent("● ")
+			}
+			line := marker + m.ID
+			if m.Name != "" {
+				line += display.Muted("  " + m.Name)
+			}
+			fmt.Println(line)
+		}
+		fmt.Println(display.Muted("Switch with /model <id>, or /model default."))
+		return
+	}
+
+	if arg == "default" {
+		arg = ""
+	} else if list != nil && len(list.Models) > 0 && !list.Has(arg) {
+		fmt.Println(display.Warn("Model " + arg + " is not in the server's catalog; using it anyway."))
+	}
+	s.client.Model = arg
+	s.session.Model = arg
+	fmt.Println(display.Success("Model: " + modelLabel(arg, list)))
+}
+
+// modelLabel names a model for display; "" is the server's default.
+func modelLabel(id string, list *api.ModelList) string {
+	if id != "" {
+		return id
+	}
+	if list != nil && list.DefaultID != "" {
+		return "server default (" + list.DefaultID + ")"
+	}
+	return "server default"
+}
+
+// answerSources returns the sources cited by the most recent answer.
+func answerSources(msgs []api.Message) []api.Source {
+	for i := len(msgs) - 1; i >= 0; i-- {
+		if msgs[i].Role == "assistant" && (msgs[i].Content != "" || len(msgs[i].Sources) > 0) {
+			return msgs[i].Sources
+		}
+	}
+	return nil
+}
+
+// lastAssistantContent returns the most recent non-empty assistant answer.
+func lastAssistantContent(msgs []api.Message) string {
+	for i := len(msgs) - 1; i >= 0; i-- {
+		if msgs[i].Role == "assistant" && msgs[i].Content != "" {
+			return msgs[i].Content
+		}
+	}
+	return ""
 }
 
 func (s *chatSession) executor(input string) {
@@ -88,7 +320,10 @@ func (s *chatSession) executor(input string) {
 		return
 	}
 
-	switch input {
+	command, arg, _ := strings.Cut(input, " ")
+	arg = strings.TrimSpace(arg)
+
+	switch command {
 	case "/quit":
 		fmt.Println("Goodbye!")
 		os.Exit(0)
@@ -97,10 +332,31 @@ func (s *chatSession) executor(input string) {
 		if len(s.history) > 0 && s.history[0].Role == "system" {
 			newHistory = append(newHistory, s.history[0])
 		}
+		// The old session stays on disk; carry on in a fresh one.
+		s.session = session.New(s.session.KeyName, s.session.BaseURL)
 		s.history = newHistory
 		s.lastAnswer = ""
+		s.pending = nil
+		s.lastCost = nil
 		fmt.Println("History cleared.")
 		return
+	case "/sessions":
+		if arg != "" {
+			s.switchSession(arg)
+			return
+		}
+		all, err := session.List()
+		if err != nil {
+			printError(err.Error())
+			return
+		}
+		if len(all) == 0 {
+			fmt.Println(display.Muted("No saved sessions yet."))
+			return
+		}
+		printSessionList(all, s.session.ID)
+		fmt.Println(display.Muted("Switch with /sessions <id>."))
+		return
 	case "/copy":
 		if s.lastAnswer == "" {
 			printError("No previous response to copy from.")
@@ -113,6 +369,43 @@ func (s *chatSession) executor(input string) {
 			printError("No code block found in last response.")
 		}
 		return
+	case "/export":
+		s.exportTranscript(arg)
+		return
+	case "/retry":
+		s.retry()
+		return
+	case "/model":
+		s.setModel(arg)
+		return
+	case "/attach":
+		s.attach(arg)
+		return
+	case "/usage":
+		s.showUsage()
+		return
+	case "/undo":
+		if err := undoLast(undo.Open(s.session.ID), arg == "--force"); err != nil {
+			if errors.Is(err, undo.ErrNothingToUndo) {
+				fmt.Println(display.Muted("No tool changes to undo in this session."))
+			} else {
+				printError(err.Error())
+			}
+		}
+		return
+	case "/changes":
+		if err := printChanges(undo.Open(s.session.ID)); err != nil {
+			printError(err.Error())
+		}
+		return
+	case "/sources":
+		sources := answerSources(s.history)
+		if len(sources) == 0 {
+			fmt.Println(display.Muted("The last answer cited no sources."))
+			return
+		}
+		fmt.Print(display.RenderSources(sources, true))
+		return
 	case "/think":
 		s.showReasoning = !s.showReasoning
 		if s.showReasoning {
@@ -123,14 +416,116 @@ func (s *chatSession) executor(input string) {
 		return
 	}
 
-	s.history = append(s.history, api.Message{Role: "user", Content: input})
+	input = ctxenrich.Redactor.Redact("message", input)
+	s.history = append(s.history, api.Message{Role: "user", Content: input, Attachments: s.pending})
+	s.pending = nil
+	s.runTurn()
+}
+
+// attach uploads a file now, so a bad path or a server error shows up at
+// once, and queues it for the next message. Without an argument it lists
+// what is queued.
+func (s *chatSession) attach(path string) {
+	if path == "" {
+		if len(s.pending) == 0 {
+			fmt.Println(display.Muted("Nothing attached. Use /attach <path>."))
+			return
+		}
+		fmt.Println(display.Muted("Attached to your next message: " + attachmentNames(s.pending)))
+		return
+	}
+	atts, err := uploadFiles(context.Background(), s.client.BaseURL, s.client.APIKey, []string{path})
+	if err != nil {
+		printError(err.Error())
+		return
+	}
+	s.pending = append(s.pending, atts...)
+	fmt.Println(display.Success("Attached " + atts[0].Name + "; it will be sent with your next message."))
+}
+
+// recordUsage adds a turn's token usage (summed over its tool loop) to the
+// session totals and prices it. It returns nil when the server reported no
+// usage.
+func (s *chatSession) recordUsage(turn api.TurnResult) *turnCost {
+	if turn.Usage == nil {
+		return nil
+	}
+	tc := priceUsage(*turn.Usage, s.models(), turn.Model, s.client.Model)
+	total := &s.session.Usage
+	total.PromptTokens += tc.Usage.PromptTokens
+	total.CompletionTokens += tc.Usage.CompletionTokens
+	total.TotalTokens += tc.Usage.TotalTokens
+	if tc.Priced {
+		s.session.CostUSD += tc.Cost
+	}
+	s.lastCost = &tc
+	return &tc
+}
+
+// showUsage handles /usage.
+func (s *chatSession) showUsage() {
+	if s.lastCost == nil && s.session.Usage.TotalTokens == 0 {
+		fmt.Println(display.Muted("The server has not reported token usage in this session."))
+		return
+	}
+	if tc := s.lastCost; tc != nil {
+		line := usageSummary(tc.Usage)
+		if tc.Priced {
+			line += " · " + formatUSD(tc.Cost)
+		}
+		fmt.Println(display.KeyValue("last answer:", line))
+	}
+	line := usageSummary(s.session.Usage)
+	if s.session.CostUSD > 0 {
+		line += " · " + formatUSD(s.session.CostUSD)
+	}
+	fmt.Println(display.KeyValue("session:    ", fmt.Sprintf("%s over %d turns", line, s.session.UserTurns())))
+	if tc := s.lastCost; tc != nil {
+		switch {
+		case tc.Priced:
+			fmt.Println(display.KeyValue("pricing:    ", fmt.Sprintf("%s at $%.2f in / $%.2f out per 1M tokens",
+				tc.Model, tc.Price.InputPerMillion, tc.Price.OutputPerMillion)))
+		case tc.Model != "":
+			fmt.Println(display.Muted("No price is known for " + tc.Model + "; costs are not estimated."))
+		default:
+			fmt.Println(display.Muted("The server did not say which model answered; costs are not estimated."))
+		}
+	}
+}
+
+// retry regenerates the answer to the most recent user message, discarding
+// everything after it: a partial answer, tool calls, or a complete answer.
+func (s *chatSession) retry() {
+	i := len(s.history) - 1
+	for i >= 0 && s.history[i].Role != "user" {
+		i--
+	}
+	if i < 0 {
+		printError("Nothing to retry yet.")
+		return
+	}
+	s.history = s.history[:i+1]
+	fmt.Println(display.Muted("Regenerating the last answer..."))
+	s.runTurn()
+}
 
+// runTurn sends the history (ending in a user message) and renders the
+// answer, running any tool calls along the way.
+func (s *chatSession) runTurn() {
 	// The prompt library restores cooked mode (ISIG on) while the executor
 	// runs, so Ctrl-C here is a real SIGINT. Turn it into a cancellation of
-	// the in-flight request instead of letting it kill the whole session.
-	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	// the in-flight request instead of letting it kill the whole session;
+	// during a run_command it stops just the command.
+	ctx, stop := interruptContext()
 	defer stop()
 
+	// File writes by tools are backed up under this session's id, for
+	// /undo and /changes; "always allow" answers are scoped to it and the
+	// working directory.
+	tools.Journal = undo.Open(s.session.ID)
+	cwd, _ := os.Getwd()
+	tools.ApprovalScope = approvals.Where{Session: s.session.ID, Project: cwd}
+
 	renderer := display.NewStreamRenderer()
 	renderer.ShowReasoning = s.showReasoning
 
@@ -138,13 +533,14 @@ func (s *chatSession) executor(input string) {
 		renderer.Delta(delta)
 	}
 
-	onToolCall := func(tc api.ToolCall) string {
-		return handleToolCall(ctx, tc, s.timeout)
+	onToolCalls := func(calls []api.ToolCall) []string {
+		return handleToolCalls(ctx, calls, s.timeout)
 	}
 
-	updatedHistory, err := s.client.RunWithTools(
-		ctx, s.history, s.toolDefs, !globalNoStream, onDelta, onToolCall,
+	turn, err := s.client.RunWithToolBatches(
+		ctx, s.session.ConversationID, s.history, s.toolDefs, !globalNoStream, onDelta, onToolCalls,
 	)
+	cost := s.recordUsage(turn)
 	if err != nil {
 		if errors.Is(err, context.Canceled) || ctx.Err() != nil {
 			// Drop the user turn that never got an answer so the next
@@ -154,7 +550,24 @@ func (s *chatSession) executor(input string) {
 			fmt.Println(display.Muted("Interrupted."))
 			return
 		}
-		printError(err.Error())
+		if errors.Is(err, api.ErrIncompleteStream) {
+			s.history = turn.History
+			s.session.ConversationID = turn.ConversationID
+			s.lastAnswer = renderer.Content()
+			s.save()
+			fmt.Println()
+			if footer := display.RenderSources(answerSources(s.history), false); footer != "" {
+				fmt.Print(footer)
+			}
+			if cost != nil {
+				fmt.Println(usageStatusLine(*cost, &s.session.Usage, s.session.CostUSD))
+			}
+			fmt.Println(display.Warn("The connection dropped before the answer finished; the partial answer was kept."))
+			fmt.Println(display.Muted("Type /retry to regenerate it."))
+			return
+		}
+		printError(friendlyError(err))
+		fmt.Println(display.Muted("Type /retry to try again."))
 		return
 	}
 	fmt.Println()
@@ -163,8 +576,19 @@ func (s *chatSession) executor(input string) {
 		fmt.Print(rendered)
 	}
 
-	s.history = updatedHistory
+	s.history = turn.History
+	s.session.ConversationID = turn.ConversationID
 	s.lastAnswer = renderer.Content()
+	s.save()
+
+	if footer := display.RenderSources(answerSources(s.history), false); footer != "" {
+		fmt.Println()
+		fmt.Print(footer)
+	}
+	if cost != nil {
+		fmt.Println()
+		fmt.Println(usageStatusLine(*cost, &s.session.Usage, s.session.CostUSD))
+	}
 
 	fmt.Println()
 }
@@ -179,33 +603,61 @@ func (s *chatSession) completer(d prompt.Document) ([]prompt.Suggest, pstrings.R
 		return nil, end, end
 	}
 
+	if partial, ok := strings.CutPrefix(text, "/attach "); ok {
+		start := end - pstrings.RuneCountInString(partial)
+		return pathSuggestions(partial), start, end
+	}
+
+	if prefix, ok := strings.CutPrefix(text, "/model "); ok {
+		var suggestions []prompt.Suggest
+		if list := s.models(); list != nil {
+			for _, m := range list.Models {
+				suggestions = append(suggestions, prompt.Suggest{Text: m.ID, Description: m.Name})
+			}
+		}
+		suggestions = append(suggestions, prompt.Suggest{Text: "default", Description: "Let the server choose"})
+		start := end - pstrings.RuneCountInString(prefix)
+		return prompt.FilterHasPrefix(suggestions, prefix, true), start, end
+	}
+
 	suggestions := []prompt.Suggest{
 		{Text: "/quit", Description: "Exit the chat session"},
 		{Text: "/clear", Description: "Clear conversation history"},
 		{Text: "/copy", Description: "Copy last code block to clipboard"},
 		{Text: "/think", Description: "Toggle reasoning visibility"},
+		{Text: "/sessions", Description: "List saved sessions or switch to one"},
+		{Text: "/export", Description: "Export the transcript: /export <file.md|.json|.html>"},
+		{Text: "/retry", Description: "Regenerate the last answer"},
+		{Text: "/model", Description: "Show or switch the model: /model <id>"},
+		{Text: "/attach", Description: "Attach a file to the next message: /attach <path>"},
+		{Text: "/sources", Description: "Show the full sources behind the last answer"},
+		{Text: "/usage", Description: "Show token usage and estimated cost"},
+		{Text: "/undo", Description: "Revert the last file change made by a tool"},
+		{Text: "/changes", Description: "List files changed by tools, with a diff"},
 	}
 
 	start := end - pstrings.RuneCountInString(text)
 	return prompt.FilterHasPrefix(suggestions, text, true), start, end
 }
 
-func runChatLoop(client *api.Client, history []api.Message) error {
+func runChatLoop(client *api.Client, sess *session.Session) error {
 	var toolDefs []api.Tool
 	if !globalNoContext {
 		toolDefs = tools.ToolDefinitions()
 	}
 
-	session := &chatSession{
-		client:   client,
-		history:  history,
-		toolDefs: toolDefs,
-		timeout:  time.Duration(globalTimeout) * time.Second,
+	cs := &chatSession{
+		client:     client,
+		session:    sess,
+		history:    sess.Messages,
+		lastAnswer: lastAssistantContent(sess.Messages),
+		toolDefs:   toolDefs,
+		timeout:    time.Duration(globalTimeout) * time.Second,
 	}
 
 	p := prompt.New(
-		session.executor,
-		prompt.WithCompleter(session.completer),
+		cs.executor,
+		prompt.WithCompleter(cs.completer),
 		prompt.WithPrefix("> "),
 		prompt.WithPrefixTextColor(prompt.Purple),
 		prompt.WithSuggestionBGColor(prompt.DarkGray),
@@ -224,7 +676,131 @@ func runChatLoop(client *api.Client, history []api.Message) error {
 	return nil
 }
 
-// handleToolCall gates a model-requested tool call behind the safety check
+// confirmToolLoop is the client's OnToolLoop hook. It announces the round
+// limit, and asks whether to keep going when the model repeats an identical
+// tool call; without a terminal to ask, the loop is stopped.
+func confirmToolLoop(ev api.ToolLoopEvent) bool {
+	if ev.Limit() {
+		fmt.Fprintf(tools.Prompts, "\n%s Reached the limit of %d tool rounds; asking the model to wrap up.\n", display.Warn("!"), ev.MaxRounds)
+		return false
+	}
+	fmt.Fprintf(tools.Prompts, "\n%s The model has called %s %d times with the same arguments.\n",
+		display.Warn("!"), tools.NormalizeName(ev.Call.Function.Name), ev.Repeats)
+	if !stdinIsTTY() {
+		fmt.Fprintln(tools.Prompts, display.Muted("  No terminal to ask; stopping the tool loop."))
+		return false
+	}
+	ok, err := tools.Confirm("Keep running tools?")
+	if err != nil || !ok {
+		fmt.Fprintln(tools.Prompts, display.Muted("  Stopping the tool loop; the model will answer with what it has."))
+		return false
+	}
+	return true
+}
+
+// handleToolCalls runs one round of tool calls, returning their results in
+// order. Consecutive read-only calls are approved on one batch card and run
+// concurrently; any other call goes through handleToolCall on its own.
+func handleToolCalls(ctx context.Context, calls []api.ToolCall, timeout time.Duration) []string {
+	results := make([]string, len(calls))
+	for i := 0; i < len(calls); {
+		j := i
+		for j < len(calls) && tools.IsReadOnly(calls[j].Function.Name) {
+			j++
+		}
+		if j-i < 2 {
+			results[i] = handleToolCall(ctx, calls[i], timeout)
+			i++
+			continue
+		}
+		copy(results[i:j], handleReadOnlyBatch(ctx, calls[i:j], timeout))
+		i = j
+	}
+	return results
+}
+
+// handleReadOnlyBatch judges a run of read-only calls by the policy one by
+// one, approves the ones left to the user together, and runs the approved
+// calls concurrently. Calls the policy denies never reach the card, nor do
+// ones it or a stored rule allows. Choosing to review each falls back to
+// one card per call, run in order; so does a batch with a single call left
+// to approve.
+func handleReadOnlyBatch(ctx context.Context, calls []api.ToolCall, timeout time.Duration) []string {
+	results := make([]string, len(calls))
+	if ctx.Err() != nil {
+		for i := range results {
+			results[i] = "User interrupted before this tool call ran."
+		}
+		return results
+	}
+	approved := make([]bool, len(calls))
+	var pending []int
+	for i, tc := range calls {
+		name := tools.NormalizeName(tc.Function.Name)
+		subject, d := tools.CallPolicy(name, tc.Function.Arguments)
+		switch {
+		case d.Verdict == policy.Deny:
+			fmt.Fprintf(tools.Prompts, "\n%s Tool call blocked: %s\n", display.Danger("✗"), d.Reason)
+			results[i] = "Tool call was blocked by policy: " + d.Reason
+		case d.Verdict == policy.Allow:
+			fmt.Fprintf(tools.Prompts, "\n%s %s\n", display.Success("✓"), display.Muted("Allowed by policy: "+subject))
+			approved[i] = true
+		case globalAutoApprove:
+			approved[i] = true
+		default:
+			if r := tools.PreApproved(name, tc.Function.Arguments); r != nil {
+				fmt.Fprintf(tools.Prompts, "\n%s %s\n", display.Success("✓"), display.Muted(fmt.Sprintf("Approved by rule #%d: %s", r.ID, r.Describe())))
+				approved[i] = true
+			} else {
+				pending = append(pending, i)
+			}
+		}
+	}
+
+	switch {
+	case len(pending) == 1:
+		i := pending[0]
+		results[i] = handleToolCall(ctx, calls[i], timeout)
+	case len(pending) > 1:
+		batch := make([]api.ToolCall, len(pending))
+		for k, i := range pending {
+			batch[k] = calls[i]
+		}
+		choice, err := tools.RequestBatchApproval(batch)
+		for _, i := range pending {
+			switch {
+			case err != nil:
+				results[i] = "Error during approval: " + err.Error()
+			case choice == tools.Denied:
+				results[i] = "User denied this tool call."
+			case choice == tools.ReviewEach:
+				results[i] = handleToolCall(ctx, calls[i], timeout)
+			default:
+				approved[i] = true
+			}
+		}
+	}
+
+	var wg sync.WaitGroup
+	for i, tc := range calls {
+		if !approved[i] {
+			continue
+		}
+		if ctx.Err() != nil {
+			results[i] = "User interrupted before this tool call ran."
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = tools.Execute(tc.Function.Name, tc.Function.Arguments, timeout).String()
+		}()
+	}
+	wg.Wait()
+	return results
+}
+
+// handleToolCall gates a model-requested tool call behind the command policy
 // and the user's approval, then executes it. A cancelled ctx (Ctrl-C) skips
 // the call: before the approval prompt, and again after it, so a Ctrl-C
 // pressed while the prompt was waiting never runs the command.
@@ -234,18 +810,34 @@ func handleToolCall(ctx context.Context, tc api.ToolCall, timeout time.Duration)
 	}
 	normalizedName := tools.NormalizeName(tc.Function.Name)
 
-	// Check safety for run_command
+	// The policy judges run_command by its command and other tools by
+	// name: deny blocks the call, allow runs it without a prompt, ask
+	// leaves it to the user.
+	needsApproval := !globalAutoApprove
+	what := "Tool call"
 	if normalizedName == "run_command" {
-		safe, reason := tools.IsSafe(tc.Function.Arguments)
-		if !safe {
-			fmt.Printf("\n%s Command blocked: %s\n", display.Danger("✗"), reason)
-			return fmt.Sprintf("Command was blocked for safety: %s", reason)
+		what = "Command"
+	}
+	subject, d := tools.CallPolicy(normalizedName, tc.Function.Arguments)
+	switch d.Verdict {
+	case policy.Deny:
+		fmt.Fprintf(tools.Prompts, "\n%s %s blocked: %s\n", display.Danger("✗"), what, d.Reason)
+		return fmt.Sprintf("%s was blocked by policy: %s", what, d.Reason)
+	case policy.Allow:
+		needsApproval = false
+		fmt.Fprintf(tools.Prompts, "\n%s %s\n", display.Success("✓"), display.Muted("Allowed by policy: "+subject))
+	}
+
+	if needsApproval {
+		if r := tools.PreApproved(normalizedName, tc.Function.Arguments); r != nil {
+			needsApproval = false
+			fmt.Fprintf(tools.Prompts, "\n%s %s\n", display.Success("✓"), display.Muted(fmt.Sprintf("Approved by rule #%d: %s", r.ID, r.Describe())))
 		}
 	}
 
 	// Auto-approve or ask user
 	args := tc.Function.Arguments
-	if !globalAutoApprove {
+	if needsApproval {
 		result, editedArgs, err := tools.RequestApproval(normalizedName, args)
 		if err != nil {
 			return "Error during approval: " + err.Error()
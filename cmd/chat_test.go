@@ -0,0 +1,148 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"docsgpt-cli/internal/api"
+	"docsgpt-cli/internal/display"
+	"docsgpt-cli/internal/session"
+	"docsgpt-cli/internal/tools"
+)
+
+func TestHandleToolCallsKeepsCallOrder(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"a", "b", "c"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("content of "+name), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	display.InitTheme("dark")
+	orig := globalAutoApprove
+	globalAutoApprove = true
+	t.Cleanup(func() { globalAutoApprove = orig })
+
+	call := func(id, name, args string) api.ToolCall {
+		return api.ToolCall{ID: id, Function: api.FunctionCall{Name: name, Arguments: args}}
+	}
+	read := func(id, file string) api.ToolCall {
+		return call(id, "read_file_ct0", fmt.Sprintf(`{"path":%q}`, filepath.Join(dir, file)))
+	}
+	// Two read-only batches split by a write, which must land between them:
+	// the read of "c" after the write sees the new content.
+	calls := []api.ToolCall{
+		read("1", "a"),
+		read("2", "b"),
+		read("3", "c"),
+		call("4", "write_file", fmt.Sprintf(`{"path":%q,"content":"rewritten"}`, filepath.Join(dir, "c"))),
+		read("5", "c"),
+		read("6", "a"),
+	}
+	got := handleToolCalls(context.Background(), calls, time.Second)
+	want := []string{"content of a", "content of b", "content of c", "Successfully wrote", "rewritten", "content of a"}
+	if len(got) != len(want) {
+		t.Fatalf("got %d results, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if !strings.HasPrefix(got[i], want[i]) {
+			t.Errorf("result %d = %q, want prefix %q", i, got[i], want[i])
+		}
+	}
+}
+
+func TestSwitchSessionDropsPendingState(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	display.InitTheme("dark")
+	saved := session.New("k", "http://docs")
+	saved.Messages = []api.Message{{Role: "user", Content: "hi"}, {Role: "assistant", Content: "hello"}}
+	if err := saved.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	s := &chatSession{
+		client:   &api.Client{},
+		session:  session.New("k", "http://docs"),
+		pending:  []api.Attachment{{ID: "att-1", Name: "notes.txt"}},
+		lastCost: &turnCost{},
+	}
+	s.switchSession(saved.ID)
+	if s.session.ID != saved.ID || s.pending != nil || s.lastCost != nil {
+		t.Errorf("after switching: session %s, pending %v, lastCost %v", s.session.ID, s.pending, s.lastCost)
+	}
+}
+
+func TestReadOnlyBatchAppliesPolicy(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	display.InitTheme("dark")
+	os.MkdirAll(filepath.Join(home, ".docsgpt"), 0o700)
+	os.WriteFile(filepath.Join(home, ".docsgpt", "policy.yaml"), []byte(`
+rules:
+  - tool: read_file
+    verdict: ask
+  - tool: file_info
+    verdict: deny
+    reason: no peeking
+`), 0o600)
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "a"), []byte("alpha"), 0o644)
+	var prompts strings.Builder
+	origPrompts := tools.Prompts
+	tools.Prompts = &prompts
+	t.Cleanup(func() { tools.Prompts = origPrompts })
+	// The one call left to the user gets its own card, answered here.
+	withStdin(t, "y\n")
+
+	call := func(id, name, args string) api.ToolCall {
+		return api.ToolCall{ID: id, Function: api.FunctionCall{Name: name, Arguments: args}}
+	}
+	got := handleReadOnlyBatch(context.Background(), []api.ToolCall{
+		call("1", "grep", fmt.Sprintf(`{"pattern":"alp","path":%q}`, dir)),
+		call("2", "file_info", fmt.Sprintf(`{"path":%q}`, filepath.Join(dir, "a"))),
+		call("3", "read_file", fmt.Sprintf(`{"path":%q}`, filepath.Join(dir, "a"))),
+	}, time.Second)
+
+	if !strings.Contains(got[0], "alpha") {
+		t.Errorf("allowed grep result = %q", got[0])
+	}
+	if !strings.Contains(got[1], "blocked by policy: no peeking") {
+		t.Errorf("denied file_info result = %q", got[1])
+	}
+	if got[2] != "alpha" {
+		t.Errorf("approved read_file result = %q", got[2])
+	}
+	out := prompts.String()
+	if !strings.Contains(out, "Allowed by policy: grep") || strings.Count(out, "  > ") != 1 {
+		t.Errorf("want one card, for read_file only; prompts:\n%s", out)
+	}
+}
+
+func TestReadToolsRunWithoutPromptByDefault(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	display.InitTheme("dark")
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("remember the milk"), 0o644)
+	var prompts strings.Builder
+	origPrompts := tools.Prompts
+	tools.Prompts = &prompts
+	t.Cleanup(func() { tools.Prompts = origPrompts })
+	// A card would read this answer and deny the call.
+	withStdin(t, "n\n")
+
+	read := api.ToolCall{ID: "1", Function: api.FunctionCall{Name: "read_file", Arguments: fmt.Sprintf(`{"path":%q}`, filepath.Join(dir, "notes.txt"))}}
+	if got := handleToolCall(context.Background(), read, time.Second); got != "remember the milk" {
+		t.Errorf("read_file result = %q", got)
+	}
+	grep := api.ToolCall{ID: "2", Function: api.FunctionCall{Name: "grep", Arguments: fmt.Sprintf(`{"pattern":"milk","path":%q}`, dir)}}
+	if got := handleToolCall(context.Background(), grep, time.Second); !strings.Contains(got, "notes.txt") {
+		t.Errorf("grep result = %q", got)
+	}
+	if strings.Contains(prompts.String(), "  > ") {
+		t.Errorf("an approval card was shown:\n%s", prompts.String())
+	}
+}
@@ -0,0 +1,203 @@
+package cmd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"docsgpt-cli/internal/api"
+	"docsgpt-cli/internal/config"
+	ctxenrich "docsgpt-cli/internal/context"
+	"docsgpt-cli/internal/display"
+
+	"github.com/spf13/cobra"
+)
+
+var commitMsgYes bool
+
+// commitDiffBytes caps the staged diff sent for a commit message; the file
+// list before it is always complete.
+const commitDiffBytes = 32 * 1024
+
+// commitLogCommits is how many recent commits show the model the
+// repository's message style.
+const commitLogCommits = 10
+
+const commitMsgPrompt = `Write a git commit message for the staged changes below. Follow the style of the repository's recent commits (prefixes, tense, capitalization, length) and any commit conventions in the project instructions.
+
+Answer with the commit message only, without code fences or commentary: a subject line of at most 72 characters and, if the change needs explaining, a blank line and a body wrapped at 72 columns.`
+
+var commitMsgCmd = &cobra.Command{
+	Use:   "commit-msg",
+	Short: "Propose a commit message for the staged changes, and commit with it",
+	Long: `Propose a commit message for the staged changes (git diff --cached),
+written in the style of the repository's recent commits.
+
+In a terminal, the message is shown and you are asked whether to commit
+with it: y commits, e opens it in git's editor first, anything else stops.
+--yes commits without asking. When stdout is not a terminal, only the
+message is printed:
+
+    git commit -m "$(docsgpt-cli commit-msg)"`,
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cwd, _ := os.Getwd()
+		diff, err := ctxenrich.StagedDiff(cwd, commitDiffBytes)
+		if errors.Is(err, ctxenrich.ErrNotGitRepository) {
+			return fmt.Errorf("%s is not in a git repository", cwd)
+		}
+		if err != nil {
+			return err
+		}
+		if diff == "" {
+			return errors.New("nothing is staged; stage changes with git add first")
+		}
+		log, err := ctxenrich.RecentLog(cwd, commitLogCommits)
+		if err != nil {
+			return err
+		}
+
+		cfg, err := config.Load()
+		if err != nil {
+			return err
+		}
+		_, apiKey, err := cfg.ResolveKey(globalKey)
+		if err != nil {
+			return err
+		}
+		client := newAPIClient(cfg, cfg.ResolveURL(globalURL), apiKey)
+		if ctxenrich.Redactor, err = newRedactor(cfg); err != nil {
+			return err
+		}
+
+		question := buildCommitMsgQuestion(diff, log, cfg.Settings, cwd)
+		ctx, stop := interruptContext()
+		defer stop()
+		fmt.Fprintln(os.Stderr, display.Muted("Writing a commit message for the staged changes..."))
+		turn, err := client.RunWithToolBatches(ctx, "", []api.Message{{Role: "user", Content: question}}, nil, !globalNoStream,
+			func(api.Delta, string) {},
+			func(calls []api.ToolCall) []string {
+				results := make([]string, len(calls))
+				for i := range results {
+					results[i] = "No tools are available here. Answer with the commit message only."
+				}
+				return results
+			})
+		if ctx.Err() != nil {
+			return errors.New("interrupted")
+		}
+		if err != nil {
+			return errors.New(friendlyError(err))
+		}
+		message := cleanCommitMessage(lastAssistantContent(turn.History))
+		if message == "" {
+			return errors.New("the model did not propose a commit message")
+		}
+
+		if !stdoutIsTTY() && !commitMsgYes {
+			fmt.Println(message)
+			return nil
+		}
+		fmt.Println()
+		for _, line := range strings.Split(message, "\n") {
+			fmt.Println("  " + line)
+		}
+		fmt.Println()
+
+		edit := false
+		if !commitMsgYes {
+			if !stdinIsTTY() {
+				fmt.Println(display.Muted("No terminal to approve the commit; use --yes to commit without asking."))
+				return nil
+			}
+			fmt.Print("Commit with this message? [y/N/e(dit)] ")
+			input, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+			switch strings.TrimSpace(strings.ToLower(input)) {
+			case "y", "yes":
+			case "e", "edit":
+				edit = true
+			default:
+				fmt.Println(display.Muted("Not committed."))
+				return nil
+			}
+		}
+		return gitCommit(message, edit)
+	},
+}
+
+func init() {
+	commitMsgCmd.Flags().BoolVarP(&commitMsgYes, "yes", "y", false, "Commit with the proposed message without asking")
+}
+
+// buildCommitMsgQuestion puts the instructions, the recent commits, the
+// project context and the staged changes into one question, with their
+// secrets masked.
+func buildCommitMsgQuestion(diff, log string, settings config.Settings, cwd string) string {
+	var b strings.Builder
+	b.WriteString(commitMsgPrompt + "\n\n")
+	if !globalNoContext && settings.SendProjectContext {
+		// Branch and uncommitted files would only distract here.
+		if project, err := ctxenrich.LoadProject(cwd, settings.ContextBudget); project != nil {
+			if err != nil {
+				fmt.Fprintln(os.Stderr, display.Warn("warning:"), err)
+			}
+			project.Git = nil
+			b.WriteString(ctxenrich.Redactor.Redact("project context", project.Render()) + "\n")
+		}
+	}
+	if log != "" {
+		fmt.Fprintf(&b, "RECENT_COMMITS:\n%s\n\n", ctxenrich.Redactor.Redact("git log", log))
+	}
+	fmt.Fprintf(&b, "STAGED_CHANGES:\n%s\n", ctxenrich.Redactor.Redact("staged changes", diff))
+	return b.String()
+}
+
+// cleanCommitMessage strips what models wrap a message in despite being
+// asked not to: surrounding whitespace and a code fence.
+func cleanCommitMessage(answer string) string {
+	message := strings.TrimSpace(answer)
+	if strings.HasPrefix(message, "```") && strings.HasSuffix(message, "```") && len(message) > 6 {
+		message = strings.TrimSuffix(message, "```")
+		if i := strings.IndexByte(message, '\n'); i >= 0 {
+			message = message[i+1:]
+		} else {
+			message = strings.TrimPrefix(message, "```")
+		}
+	}
+	lines := strings.Split(strings.TrimSpace(message), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " \t")
+	}
+	return strings.Join(lines, "\n")
+}
+
+// gitCommit commits the staged changes with message, in git's editor
+// first when edit is set. git's own output and hooks run in the terminal.
+func gitCommit(message string, edit bool) error {
+	f, err := os.CreateTemp("", "docsgpt-commit-msg-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.WriteString(message + "\n"); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	args := []string{"commit", "--file", f.Name()}
+	if edit {
+		args = append(args, "--edit")
+	}
+	c := exec.Command("git", args...)
+	c.Stdin, c.Stdout, c.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := c.Run(); err != nil {
+		return fmt.Errorf("git commit failed: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,39 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+
+	"docsgpt-cli/internal/config"
+)
+
+func TestCleanCommitMessage(t *testing.T) {
+	tests := []struct {
+		answer string
+		want   string
+	}{
+		{"Fix the parser\n", "Fix the parser"},
+		{"  Fix the parser  \n\nIt dropped the last token.   \n", "Fix the parser\n\nIt dropped the last token."},
+		{"```\nFix the parser\n\nBody\n```", "Fix the parser\n\nBody"},
+		{"```text\nFix the parser\n```\n", "Fix the parser"},
+		{"```", "```"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := cleanCommitMessage(tt.answer); got != tt.want {
+			t.Errorf("cleanCommitMessage(%q) = %q, want %q", tt.answer, got, tt.want)
+		}
+	}
+}
+
+func TestBuildCommitMsgQuestion(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	q := buildCommitMsgQuestion(" a.go | 2 +-\n\n-old\n+new", "abc123 t, 2 days ago: Fix x", config.Settings{}, t.TempDir())
+	if !strings.HasPrefix(q, commitMsgPrompt) {
+		t.Errorf("question does not start with the instructions:\n%s", q)
+	}
+	rc, sc := strings.Index(q, "RECENT_COMMITS:\nabc123"), strings.Index(q, "STAGED_CHANGES:\n a.go | 2 +-")
+	if rc < 0 || sc < rc || !strings.HasSuffix(q, "+new\n") {
+		t.Errorf("question = %q", q)
+	}
+}
@@ -3,10 +3,18 @@ package cmd
 import (
 	"encoding/json"
 	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
 	"strings"
 
+	"docsgpt-cli/internal/api"
 	"docsgpt-cli/internal/config"
+	ctxenrich "docsgpt-cli/internal/context"
 	"docsgpt-cli/internal/display"
+	"docsgpt-cli/internal/redact"
+	"docsgpt-cli/internal/sandbox"
+	"docsgpt-cli/internal/tools"
 	"docsgpt-cli/internal/update"
 
 	"github.com/spf13/cobra"
@@ -144,10 +152,410 @@ var configSetAutoUpdateCmd = &cobra.Command{
 	},
 }
 
+var configSetMaxRetriesCmd = &cobra.Command{
+	Use:   "set-max-retries [n]",
+	Short: "Set how often rate-limited or failed API requests are retried (0 disables)",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		n, err := strconv.Atoi(args[0])
+		if err != nil || n < 0 {
+			return fmt.Errorf("invalid value: %s (use a non-negative number)", args[0])
+		}
+		cfg, err := config.Load()
+		if err != nil {
+			return err
+		}
+		cfg.Settings.MaxRetries = n
+		if err := cfg.Save(); err != nil {
+			return err
+		}
+		fmt.Println(display.Success("Max retries set to:"), n)
+		return nil
+	},
+}
+
+var configSetMaxToolRoundsCmd = &cobra.Command{
+	Use:   "set-max-tool-rounds [n|default]",
+	Short: "Set how many tool-calling rounds one answer may take (-1 removes the limit)",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		n := 0
+		if !strings.EqualFold(args[0], "default") {
+			var err error
+			n, err = strconv.Atoi(args[0])
+			if err != nil || n == 0 || n < -1 {
+				return fmt.Errorf("invalid value: %s (use a positive number, -1 or default)", args[0])
+			}
+		}
+		cfg, err := config.Load()
+		if err != nil {
+			return err
+		}
+		cfg.Settings.MaxToolRounds = n
+		if err := cfg.Save(); err != nil {
+			return err
+		}
+		switch n {
+		case 0:
+			fmt.Println(display.Success("Max tool rounds reset to the default:"), api.DefaultMaxToolRounds)
+		case -1:
+			fmt.Println(display.Success("Tool rounds are no longer limited."))
+		default:
+			fmt.Println(display.Success("Max tool rounds set to:"), n)
+		}
+		return nil
+	},
+}
+
+// minOutputBudget keeps room for some output next to the truncation notice.
+const minOutputBudget = 1024
+
+var configSetOutputBudgetCmd = &cobra.Command{
+	Use:   "set-output-budget [bytes|default]",
+	Short: "Set how large one tool result may be before it is truncated or paged",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		n := 0
+		if !strings.EqualFold(args[0], "default") {
+			var err error
+			n, err = strconv.Atoi(args[0])
+			if err != nil || n < minOutputBudget {
+				return fmt.Errorf("invalid value: %s (use a number of bytes, at least %d, or default)", args[0], minOutputBudget)
+			}
+		}
+		cfg, err := config.Load()
+		if err != nil {
+			return err
+		}
+		cfg.Settings.OutputBudget = n
+		if err := cfg.Save(); err != nil {
+			return err
+		}
+		if n == 0 {
+			fmt.Println(display.Success("Output budget reset to the default:"), tools.DefaultOutputBudget, "bytes")
+		} else {
+			fmt.Println(display.Success("Output budget set to:"), n, "bytes")
+		}
+		return nil
+	},
+}
+
+// minContextBudget keeps room for a project's instructions.
+const minContextBudget = 100
+
+var configSetContextBudgetCmd = &cobra.Command{
+	Use:   "set-context-budget [tokens|default]",
+	Short: "Set how many tokens of project context (instructions, project type, git state) are sent",
+	Long:  "Set how many tokens of project context are sent with questions. A project's .docsgpt/project.yaml can override it with context_budget.",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		n := 0
+		if !strings.EqualFold(args[0], "default") {
+			var err error
+			n, err = strconv.Atoi(args[0])
+			if err != nil || n < minContextBudget {
+				return fmt.Errorf("invalid value: %s (use a number of tokens, at least %d, or default)", args[0], minContextBudget)
+			}
+		}
+		cfg, err := config.Load()
+		if err != nil {
+			return err
+		}
+		cfg.Settings.ContextBudget = n
+		if err := cfg.Save(); err != nil {
+			return err
+		}
+		if n == 0 {
+			fmt.Println(display.Success("Context budget reset to the default:"), ctxenrich.DefaultContextBudget, "tokens")
+		} else {
+			fmt.Println(display.Success("Context budget set to:"), n, "tokens")
+		}
+		return nil
+	},
+}
+
+var configSetModelCmd = &cobra.Command{
+	Use:   "set-model [id|default]",
+	Short: "Set the model used by ask and chat (default lets the server choose)",
+	Long:  "Set the model used by ask and chat. See 'docsgpt-cli models' for the ids the server offers; 'default' clears the setting.",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		model := strings.TrimSpace(args[0])
+		if strings.EqualFold(model, "default") {
+			model = ""
+		}
+		cfg, err := config.Load()
+		if err != nil {
+			return err
+		}
+		cfg.Settings.Model = model
+		if err := cfg.Save(); err != nil {
+			return err
+		}
+		if model == "" {
+			fmt.Println(display.Success("Model cleared; the server's default will be used."))
+		} else {
+			fmt.Println(display.Success("Model set to:"), model)
+		}
+		return nil
+	},
+}
+
+var configSetSandboxCmd = &cobra.Command{
+	Use:   "set-sandbox [on|network|off]",
+	Short: "Confine tool and host commands on Linux (network keeps network access)",
+	Long: "Run run_command and host commands in a sandbox: the project directory and the\n" +
+		"paths from set-sandbox-paths stay writable, the rest of the filesystem is\n" +
+		"read-only, and the network is off unless the mode is network. Linux only.",
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		mode := strings.ToLower(args[0])
+		enabled, _, err := sandbox.ParseMode(mode)
+		if err != nil {
+			return err
+		}
+		if enabled {
+			if _, err := sandbox.Backend(); err != nil {
+				return err
+			}
+		}
+		cfg, err := config.Load()
+		if err != nil {
+			return err
+		}
+		cfg.Settings.Sandbox = mode
+		if err := cfg.Save(); err != nil {
+			return err
+		}
+		fmt.Println(display.Success("Sandbox set to:"), mode)
+		return nil
+	},
+}
+
+var configSetSandboxPathsCmd = &cobra.Command{
+	Use:   "set-sandbox-paths [path...]",
+	Short: "Set extra directories the sandbox leaves writable, such as build caches (none clears)",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := config.Load()
+		if err != nil {
+			return err
+		}
+		cfg.Settings.SandboxPaths = args
+		if err := cfg.Save(); err != nil {
+			return err
+		}
+		if len(args) == 0 {
+			fmt.Println(display.Success("Sandbox paths cleared; only the project directory is writable."))
+		} else {
+			fmt.Println(display.Success("Sandbox paths set to:"), strings.Join(args, ", "))
+		}
+		return nil
+	},
+}
+
+var configSetRedactCmd = &cobra.Command{
+	Use:   "set-redact [on|formats|off]",
+	Short: "Mask secrets in context, tool results and host output (formats skips the entropy check)",
+	Long: "Mask secrets before they are sent to the server: known token formats, values\n" +
+		"assigned to names like PASSWORD or api_key, high-entropy strings (not with\n" +
+		"formats), and the patterns from set-redact-patterns.",
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		mode, err := redact.ParseMode(args[0])
+		if err != nil {
+			return err
+		}
+		cfg, err := config.Load()
+		if err != nil {
+			return err
+		}
+		cfg.Settings.Redact = mode
+		if err := cfg.Save(); err != nil {
+			return err
+		}
+		fmt.Println(display.Success("Redaction set to:"), mode)
+		return nil
+	},
+}
+
+var configSetRedactPatternsCmd = &cobra.Command{
+	Use:   "set-redact-patterns [regex...]",
+	Short: "Set regular expressions of more secrets to mask (none clears)",
+	Long:  "Set regular expressions (Go syntax) of more secrets to mask. When a pattern has a group, only the first group is masked.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if _, err := redact.New(redact.ModeOn, args); err != nil {
+			return err
+		}
+		cfg, err := config.Load()
+		if err != nil {
+			return err
+		}
+		cfg.Settings.RedactPatterns = args
+		if err := cfg.Save(); err != nil {
+			return err
+		}
+		if len(args) == 0 {
+			fmt.Println(display.Success("Redact patterns cleared."))
+		} else {
+			fmt.Println(display.Success("Redact patterns set to:"), strings.Join(args, ", "))
+		}
+		return nil
+	},
+}
+
+var configSetGitDiffCmd = &cobra.Command{
+	Use:   "set-git-diff [on|off]",
+	Short: "Send unstaged changes as context with every question, as --with-diff",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		on, err := parseOnOff(args[0])
+		if err != nil {
+			return err
+		}
+		cfg, err := config.Load()
+		if err != nil {
+			return err
+		}
+		cfg.Settings.SendGitDiff = on
+		if err := cfg.Save(); err != nil {
+			return err
+		}
+		fmt.Println(display.Success("Send git diff set to:"), strings.ToLower(args[0]))
+		return nil
+	},
+}
+
+var configSetGitStagedCmd = &cobra.Command{
+	Use:   "set-git-staged [on|off]",
+	Short: "Send staged changes as context with every question, as --with-staged",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		on, err := parseOnOff(args[0])
+		if err != nil {
+			return err
+		}
+		cfg, err := config.Load()
+		if err != nil {
+			return err
+		}
+		cfg.Settings.SendGitStaged = on
+		if err := cfg.Save(); err != nil {
+			return err
+		}
+		fmt.Println(display.Success("Send staged changes set to:"), strings.ToLower(args[0]))
+		return nil
+	},
+}
+
+var configSetGitLogCmd = &cobra.Command{
+	Use:   "set-git-log [n]",
+	Short: "Send the last N commits as context with every question, as --with-log (0 disables)",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		n, err := strconv.Atoi(args[0])
+		if err != nil || n < 0 || n > ctxenrich.MaxLogCommits {
+			return fmt.Errorf("invalid value: %s (use 0 to %d)", args[0], ctxenrich.MaxLogCommits)
+		}
+		cfg, err := config.Load()
+		if err != nil {
+			return err
+		}
+		cfg.Settings.GitLogCommits = n
+		if err := cfg.Save(); err != nil {
+			return err
+		}
+		fmt.Println(display.Success("Git log commits set to:"), n)
+		return nil
+	},
+}
+
+var configSetHTTPAllowPrivateCmd = &cobra.Command{
+	Use:   "set-http-allow-private [on|off]",
+	Short: "Let http_get reach localhost and private networks",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		on, err := parseOnOff(args[0])
+		if err != nil {
+			return err
+		}
+		cfg, err := config.Load()
+		if err != nil {
+			return err
+		}
+		cfg.Settings.HTTPAllowPrivate = on
+		if err := cfg.Save(); err != nil {
+			return err
+		}
+		fmt.Println(display.Success("http_get private addresses set to:"), strings.ToLower(args[0]))
+		return nil
+	},
+}
+
+var configSetHostSandboxRootCmd = &cobra.Command{
+	Use:   "set-host-sandbox-root [dir|default]",
+	Short: "Set the directory host mode's sandbox leaves writable (default: each command's working directory)",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		root := ""
+		if !strings.EqualFold(args[0], "default") {
+			abs, err := filepath.Abs(args[0])
+			if err != nil {
+				return err
+			}
+			if info, err := os.Stat(abs); err != nil || !info.IsDir() {
+				return fmt.Errorf("invalid value: %s (use an existing directory or default)", args[0])
+			}
+			if err := sandbox.CheckRoot(abs); err != nil {
+				return err
+			}
+			root = abs
+		}
+		cfg, err := config.Load()
+		if err != nil {
+			return err
+		}
+		cfg.Settings.HostSandboxRoot = root
+		if err := cfg.Save(); err != nil {
+			return err
+		}
+		if root == "" {
+			fmt.Println(display.Success("Host sandbox root cleared; each command's working directory is writable."))
+		} else {
+			fmt.Println(display.Success("Host sandbox root set to:"), root)
+		}
+		return nil
+	},
+}
+
+// parseOnOff reads the value of an on/off setting.
+func parseOnOff(s string) (bool, error) {
+	switch strings.ToLower(s) {
+	case "on":
+		return true, nil
+	case "off":
+		return false, nil
+	}
+	return false, fmt.Errorf("invalid value: %s (use on or off)", s)
+}
+
 func init() {
 	configCmd.AddCommand(configShowCmd)
 	configCmd.AddCommand(configSetURLCmd)
 	configCmd.AddCommand(configSetThemeCmd)
 	configCmd.AddCommand(configSetBannerCmd)
 	configCmd.AddCommand(configSetAutoUpdateCmd)
+	configCmd.AddCommand(configSetMaxRetriesCmd)
+	configCmd.AddCommand(configSetMaxToolRoundsCmd)
+	configCmd.AddCommand(configSetOutputBudgetCmd)
+	configCmd.AddCommand(configSetContextBudgetCmd)
+	configCmd.AddCommand(configSetModelCmd)
+	configCmd.AddCommand(configSetSandboxCmd)
+	configCmd.AddCommand(configSetSandboxPathsCmd)
+	configCmd.AddCommand(configSetRedactCmd)
+	configCmd.AddCommand(configSetRedactPatternsCmd)
+	configCmd.AddCommand(configSetGitDiffCmd)
+	configCmd.AddCommand(configSetGitStagedCmd)
+	configCmd.AddCommand(configSetGitLogCmd)
+	configCmd.AddCommand(configSetHTTPAllowPrivateCmd)
+	configCmd.AddCommand(configSetHostSandboxRootCmd)
 }
@@ -0,0 +1,80 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"docsgpt-cli/internal/display"
+	"docsgpt-cli/internal/export"
+	"docsgpt-cli/internal/session"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	exportOutput string
+	exportFormat string
+)
+
+var exportCmd = &cobra.Command{
+	Use:   "export [session]",
+	Short: "Export a saved chat session as Markdown, JSON, or HTML",
+	Long: `Export the full history of a saved chat session, including tool calls,
+tool results and reasoning, as Markdown, JSON, or a standalone HTML page.
+
+The session defaults to the most recent one ("last"); any unambiguous id
+prefix works. Without --output the transcript is written to stdout.
+
+Examples:
+    docsgpt-cli export                         # last session as Markdown
+    docsgpt-cli export 20261018 -o incident.html
+    docsgpt-cli export last --format json > session.json`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ref := "last"
+		if len(args) > 0 {
+			ref = args[0]
+		}
+		sess, err := session.Load(ref)
+		if err != nil {
+			return err
+		}
+
+		format := export.FormatMarkdown
+		if exportOutput != "" {
+			format = export.FormatForPath(exportOutput)
+		}
+		if exportFormat != "" {
+			if format, err = export.ParseFormat(exportFormat); err != nil {
+				return err
+			}
+		}
+
+		if exportOutput == "" {
+			return export.Write(os.Stdout, format, sess)
+		}
+		if err := writeExportFile(exportOutput, format, sess); err != nil {
+			return err
+		}
+		fmt.Println(display.Success("Exported session " + sess.ID + " to " + exportOutput))
+		return nil
+	},
+}
+
+func init() {
+	exportCmd.Flags().StringVarP(&exportOutput, "output", "o", "", "Write to this file (format inferred from the extension)")
+	exportCmd.Flags().StringVar(&exportFormat, "format", "", "Output format: md, json, or html")
+}
+
+// writeExportFile renders sess to path in format.
+func writeExportFile(path, format string, sess *session.Session) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("create %s: %w", path, err)
+	}
+	if err := export.Write(f, format, sess); err != nil {
+		f.Close()
+		return fmt.Errorf("write %s: %w", path, err)
+	}
+	return f.Close()
+}
@@ -18,6 +18,7 @@ import (
 	"docsgpt-cli/internal/config"
 	"docsgpt-cli/internal/display"
 	"docsgpt-cli/internal/host"
+	"docsgpt-cli/internal/sandbox"
 	"docsgpt-cli/internal/update"
 
 	"github.com/mattn/go-isatty"
@@ -74,7 +75,35 @@ func runHostDaemon(cmd *cobra.Command) error {
 	if err != nil {
 		return err
 	}
+	cliCfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+	// The daemon's own working directory is no project (a service starts
+	// in / or the home directory), so each invocation is confined to its
+	// working directory, or to the configured host_sandbox_root.
+	if host.Sandbox, err = sandboxSettings(cliCfg); err != nil {
+		return err
+	}
+	host.SandboxRoot = cliCfg.Settings.HostSandboxRoot
+	if host.Sandbox != nil && host.SandboxRoot != "" {
+		if err := sandbox.CheckRoot(host.SandboxRoot); err != nil {
+			return err
+		}
+	}
+	if host.Redactor, err = newRedactor(cliCfg); err != nil {
+		return err
+	}
 	host.ShowStartupBanner(cfg)
+	if host.Sandbox != nil {
+		opts := *host.Sandbox
+		root := host.SandboxRoot
+		if root == "" {
+			root = "each command's working directory"
+		}
+		opts.Writable = append([]string{root}, opts.Writable...)
+		fmt.Println(display.Muted(describeSandbox(&opts)))
+	}
 
 	t := host.NewTransport(cfg, key, rootCmd.Version)
 	ctx, cancel := context.WithCancel(context.Background())
@@ -0,0 +1,209 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"docsgpt-cli/internal/api"
+	"docsgpt-cli/internal/display"
+	"docsgpt-cli/internal/mcp"
+	"docsgpt-cli/internal/tools"
+
+	"github.com/spf13/cobra"
+)
+
+// mcpStartTimeout bounds launching a server and listing its tools.
+const mcpStartTimeout = 20 * time.Second
+
+var mcpCmd = &cobra.Command{
+	Use:   "mcp",
+	Short: "Show the MCP servers whose tools ask and chat offer the model",
+	Long: `ask and chat start the stdio MCP (Model Context Protocol) servers listed
+in ~/.docsgpt/mcp.json and in the project's .docsgpt/mcp.json, and offer
+their tools to the model as mcp__<server>__<tool>. Calls to them go through
+the approval card, approval rules and policy like any tool call.
+
+  {
+    "mcpServers": {
+      "jira": {"command": "jira-mcp", "args": ["--stdio"], "env": {"JIRA_TOKEN": "$JIRA_TOKEN"}}
+    }
+  }
+
+A project's servers start only once you agree to them, and again after the
+file changes.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		cmd.Help()
+	},
+}
+
+var mcpListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "Start the configured MCP servers and list their tools",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cwd, _ := os.Getwd()
+		cfg, err := mcp.Load(cwd)
+		if err != nil {
+			return err
+		}
+		if len(cfg.Servers) == 0 {
+			fmt.Println("No MCP servers configured.")
+			return nil
+		}
+		for _, s := range trustedServers(cfg) {
+			fmt.Printf("%s  %s\n", display.Accent(s.Name), display.Muted("$ "+strings.Join(append([]string{s.Command}, s.Args...), " ")+"  ("+s.Source+")"))
+			ctx, cancel := context.WithTimeout(context.Background(), mcpStartTimeout)
+			client, err := mcp.Start(ctx, s, cwd, rootCmd.Version)
+			cancel()
+			if err != nil {
+				fmt.Println("  " + display.Warn("failed: "+err.Error()))
+				continue
+			}
+			for _, t := range client.Tools {
+				fmt.Printf("  %s  %s\n", mcp.ToolName(s.Name, t.Name), display.Muted(firstLine(t.Description)))
+			}
+			client.Close()
+		}
+		return nil
+	},
+}
+
+// startMCP starts the configured MCP servers and registers their tools for
+// this run. It reports what started on tools.Prompts; a server that fails
+// is skipped with a warning. The returned func stops the servers.
+func startMCP() (stop func()) {
+	cwd, _ := os.Getwd()
+	cfg, err := mcp.Load(cwd)
+	if err != nil {
+		fmt.Fprintln(tools.Prompts, display.Warn("MCP servers not started: "+err.Error()))
+		return func() {}
+	}
+	servers := trustedServers(cfg)
+	if len(servers) == 0 {
+		return func() {}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), mcpStartTimeout)
+	defer cancel()
+	clients := make([]*mcp.Client, len(servers))
+	errs := make([]error, len(servers))
+	var wg sync.WaitGroup
+	for i, s := range servers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			clients[i], errs[i] = mcp.Start(ctx, s, cwd, rootCmd.Version)
+		}()
+	}
+	wg.Wait()
+
+	var started []string
+	for i, client := range clients {
+		if errs[i] != nil {
+			fmt.Fprintln(tools.Prompts, display.Warn(fmt.Sprintf("MCP server %s not started: %v", servers[i].Name, errs[i])))
+			continue
+		}
+		n := 0
+		for _, t := range client.Tools {
+			if err := tools.RegisterExternal(mcpTool(client, t)); err != nil {
+				fmt.Fprintln(tools.Prompts, display.Warn("MCP: "+err.Error()))
+				continue
+			}
+			n++
+		}
+		started = append(started, fmt.Sprintf("%s (%d tools)", client.Server.Name, n))
+	}
+	if len(started) > 0 {
+		fmt.Fprintln(tools.Prompts, display.Muted("MCP: "+strings.Join(started, ", ")))
+	}
+	return func() {
+		for _, c := range clients {
+			if c != nil {
+				c.Close()
+			}
+		}
+	}
+}
+
+// trustedServers returns the servers to start: the user's own, and the
+// project's when the user agrees to them. Without a terminal to ask, a
+// project's servers that were not agreed to before are skipped.
+func trustedServers(cfg *mcp.Config) []mcp.Server {
+	var user, project []mcp.Server
+	for _, s := range cfg.Servers {
+		if cfg.Project != "" && s.Source == cfg.Project {
+			project = append(project, s)
+		} else {
+			user = append(user, s)
+		}
+	}
+	if len(project) == 0 || mcp.Trusted(cfg.Project, cfg.ProjectData) {
+		return append(user, project...)
+	}
+	if !stdinIsTTY() {
+		fmt.Fprintln(tools.Prompts, display.Muted("Skipping the MCP servers in "+cfg.Project+": run chat in a terminal once to approve them."))
+		return user
+	}
+	fmt.Fprintf(tools.Prompts, "\n%s %s defines MCP servers, which run as programs on this machine:\n", display.Warn("!"), cfg.Project)
+	for _, s := range project {
+		fmt.Fprintf(tools.Prompts, "    %s: $ %s\n", s.Name, strings.Join(append([]string{s.Command}, s.Args...), " "))
+	}
+	ok, err := tools.Confirm("Start them (now and until the file changes)?")
+	if err != nil || !ok {
+		fmt.Fprintln(tools.Prompts, display.Muted("  Not starting the project's MCP servers."))
+		return user
+	}
+	if err := mcp.Trust(cfg.Project, cfg.ProjectData); err != nil {
+		fmt.Fprintln(tools.Prompts, display.Warn("  Could not remember the answer: "+err.Error()))
+	}
+	return append(user, project...)
+}
+
+// mcpTool wraps a server's tool as an external tool.
+func mcpTool(client *mcp.Client, t mcp.Tool) tools.External {
+	schema := t.InputSchema
+	if len(schema) == 0 {
+		schema = json.RawMessage(`{"type": "object", "properties": {}}`)
+	}
+	return tools.External{
+		Def: api.Tool{
+			Type: "function",
+			Function: api.ToolFunction{
+				Name:        mcp.ToolName(client.Server.Name, t.Name),
+				Description: fmt.Sprintf("[MCP server %s] %s", client.Server.Name, t.Description),
+				Parameters:  schema,
+			},
+		},
+		// A server's hint is its own claim; it counts only for servers the
+		// user trusts with it.
+		ReadOnly: t.Annotations.ReadOnlyHint && client.Server.TrustReadOnly,
+		Call: func(rawArgs string, timeout time.Duration) tools.ToolResult {
+			ctx, cancel := context.WithTimeout(context.Background(), timeout)
+			defer cancel()
+			res, err := client.CallTool(ctx, t.Name, json.RawMessage(rawArgs))
+			if err != nil {
+				return tools.ToolResult{Error: err.Error()}
+			}
+			out := tools.TruncateOutput(res.Text, 0)
+			if res.IsError {
+				return tools.ToolResult{Output: out, Error: "the tool reported an error"}
+			}
+			return tools.ToolResult{Output: out}
+		},
+	}
+}
+
+// firstLine returns the first line of s.
+func firstLine(s string) string {
+	line, _, _ := strings.Cut(strings.TrimSpace(s), "\n")
+	return line
+}
+
+func init() {
+	mcpCmd.AddCommand(mcpListCmd)
+}
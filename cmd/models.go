@@ -0,0 +1,95 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"docsgpt-cli/internal/api"
+	"docsgpt-cli/internal/bench/pricing"
+	"docsgpt-cli/internal/config"
+	"docsgpt-cli/internal/display"
+
+	"github.com/spf13/cobra"
+)
+
+var modelsCmd = &cobra.Command{
+	Use:   "models",
+	Short: "List the models the server offers",
+	Long: `List the models from the server's catalog (GET /api/models) with their
+context size and, when the server reports it, price per million tokens.
+
+Pick one per command with --model <id>, or make it the default with
+'docsgpt-cli config set-model <id>'. The model in use is marked with ●.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := config.Load()
+		if err != nil {
+			return err
+		}
+		// The catalog is usually public; only insist on a key the user
+		// asked for explicitly.
+		_, apiKey, err := cfg.ResolveKey(globalKey)
+		if err != nil && globalKey != "" {
+			return err
+		}
+		client := newAPIClient(cfg, cfg.ResolveURL(globalURL), apiKey)
+
+		list, err := client.ListModels(context.Background())
+		if err != nil {
+			return fmt.Errorf("could not fetch the model list: %s", friendlyError(err))
+		}
+		if len(list.Models) == 0 {
+			fmt.Println("The server did not report any models.")
+			return nil
+		}
+		printModelList(list, client.Model)
+		return nil
+	},
+}
+
+// printModelList renders one aligned line per model. current is the model
+// ask and chat would use ("" marks the server default instead).
+func printModelList(list *api.ModelList, current string) {
+	if current == "" {
+		current = list.DefaultID
+	}
+	width := 0
+	for _, m := range list.Models {
+		width = max(width, len(m.ID))
+	}
+	for _, m := range list.Models {
+		marker := "  "
+		if m.ID == current {
+			marker = display.Accent("● ")
+		}
+		cols := []string{fmt.Sprintf("%-*s", width, m.ID)}
+		if m.ContextWindow > 0 {
+			cols = append(cols, fmt.Sprintf("%6s ctx", formatTokenCount(m.ContextWindow)))
+		} else {
+			cols = append(cols, fmt.Sprintf("%10s", ""))
+		}
+		if p, ok := pricing.ForModel(m.Raw); ok {
+			cols = append(cols, fmt.Sprintf("$%.2f in / $%.2f out per 1M", p.InputPerMillion, p.OutputPerMillion))
+		}
+		line := marker + strings.Join(cols, "  ")
+		if m.Name != "" {
+			line += display.Muted("  " + m.Name)
+		}
+		if m.ID == list.DefaultID {
+			line += display.Muted("  (default)")
+		}
+		fmt.Println(line)
+	}
+}
+
+// formatTokenCount abbreviates a token count: 8192 → 8k, 1048576 → 1M.
+func formatTokenCount(n int) string {
+	switch {
+	case n >= 1_000_000:
+		return strings.TrimSuffix(fmt.Sprintf("%.1f", float64(n)/1e6), ".0") + "M"
+	case n >= 1000:
+		return fmt.Sprintf("%dk", (n+500)/1000)
+	}
+	return fmt.Sprint(n)
+}
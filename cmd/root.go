@@ -5,6 +5,7 @@ import (
 	"os"
 	"path/filepath"
 
+	"docsgpt-cli/internal/api"
 	"docsgpt-cli/internal/config"
 	"docsgpt-cli/internal/display"
 	"docsgpt-cli/internal/update"
@@ -16,14 +17,20 @@ import (
 var Version = "dev"
 
 var (
-	globalURL         string
-	globalKey         string
-	globalNoStream    bool
-	globalNoContext   bool
-	globalAutoApprove bool
-	globalTimeout     int
-	globalTheme       string
-	globalNoMotion    bool
+	globalURL           string
+	globalKey           string
+	globalNoStream      bool
+	globalNoContext     bool
+	globalAutoApprove   bool
+	globalTimeout       int
+	globalReqTimeout    int
+	globalMaxRetries    int
+	globalMaxToolRounds int
+	globalModel         string
+	globalTheme         string
+	globalNoMotion      bool
+	globalSandbox       string
+	globalShowRedacted  bool
 )
 
 var rootCmd = &cobra.Command{
@@ -148,6 +155,13 @@ func init() {
 	rootCmd.PersistentFlags().BoolVar(&globalNoContext, "no-context", false, "Disable context enrichment")
 	rootCmd.PersistentFlags().BoolVar(&globalAutoApprove, "auto-approve", false, "Auto-approve tool calls")
 	rootCmd.PersistentFlags().IntVar(&globalTimeout, "timeout", 30, "Command execution timeout in seconds")
+	rootCmd.PersistentFlags().IntVar(&globalReqTimeout, "request-timeout", 0, "Timeout in seconds for each API request, including the streamed answer (0 = none)")
+	rootCmd.PersistentFlags().StringVar(&globalModel, "model", "", "Model id for ask and chat (overrides config; default: the server's)")
+	rootCmd.PersistentFlags().IntVar(&globalMaxRetries, "max-retries", 3, "Retries for rate-limited or failed API requests (overrides config)")
+	rootCmd.PersistentFlags().IntVar(&globalMaxToolRounds, "max-tool-rounds", api.DefaultMaxToolRounds, "Tool-calling rounds per answer before the model is asked to wrap up (overrides config; -1 = no limit)")
+	rootCmd.PersistentFlags().StringVar(&globalSandbox, "sandbox", "", "Confine tool commands (Linux): on, network (on, with network access) or off (overrides config)")
+	rootCmd.PersistentFlags().Lookup("sandbox").NoOptDefVal = "on"
+	rootCmd.PersistentFlags().BoolVar(&globalShowRedacted, "show-redactions", false, "Report the secrets masked in context, tool results and host output")
 	rootCmd.PersistentFlags().StringVar(&globalTheme, "theme", "", "Color theme: auto, dark, light")
 	rootCmd.PersistentFlags().BoolVar(&globalNoMotion, "no-motion", false, "Disable banner animation")
 
@@ -156,6 +170,13 @@ func init() {
 	rootCmd.AddCommand(installCmd)
 	rootCmd.AddCommand(configCmd)
 	rootCmd.AddCommand(chatCmd)
+	rootCmd.AddCommand(sessionsCmd)
+	rootCmd.AddCommand(undoCmd)
+	rootCmd.AddCommand(approvalsCmd)
+	rootCmd.AddCommand(mcpCmd)
+	rootCmd.AddCommand(commitMsgCmd)
+	rootCmd.AddCommand(exportCmd)
+	rootCmd.AddCommand(modelsCmd)
 	rootCmd.AddCommand(updateCmd)
 	rootCmd.AddCommand(benchCmd)
 }
@@ -0,0 +1,141 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"docsgpt-cli/internal/api"
+	"docsgpt-cli/internal/display"
+	"docsgpt-cli/internal/session"
+
+	"github.com/spf13/cobra"
+)
+
+var sessionsCmd = &cobra.Command{
+	Use:   "sessions",
+	Short: "List, show, and remove saved chat sessions",
+	Long: `Every chat session is saved under ~/.docsgpt/sessions. Resume one with
+'docsgpt-cli chat --resume <id>' (or --resume alone for the most recent).
+
+A session id may be abbreviated to any unambiguous prefix, and "last"
+always refers to the most recently updated session.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		cmd.Help()
+	},
+}
+
+var sessionsListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List saved chat sessions, most recent first",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		all, err := session.List()
+		if err != nil {
+			return err
+		}
+		if len(all) == 0 {
+			fmt.Println("No saved sessions.")
+			return nil
+		}
+		printSessionList(all, "")
+		return nil
+	},
+}
+
+var sessionsShowCmd = &cobra.Command{
+	Use:   "show [id|last]",
+	Short: "Print the transcript of a saved session",
+	Args:  cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ref := "last"
+		if len(args) > 0 {
+			ref = args[0]
+		}
+		sess, err := session.Load(ref)
+		if err != nil {
+			return err
+		}
+		fmt.Println(display.KeyValue("session:", sess.ID))
+		fmt.Println(display.KeyValue("server: ", sess.BaseURL+" (key: "+sess.KeyName+")"))
+		fmt.Println(display.KeyValue("updated:", sess.UpdatedAt.Local().Format("2006-01-02 15:04")))
+		fmt.Println()
+		printTranscript(sess.Messages)
+		return nil
+	},
+}
+
+var sessionsRmCmd = &cobra.Command{
+	Use:   "rm [id...]",
+	Short: "Delete saved sessions",
+	Args:  cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		for _, ref := range args {
+			id, err := session.Delete(ref)
+			if err != nil {
+				return err
+			}
+			fmt.Println(display.Success("Deleted session " + id))
+		}
+		return nil
+	},
+}
+
+func init() {
+	sessionsCmd.AddCommand(sessionsListCmd)
+	sessionsCmd.AddCommand(sessionsShowCmd)
+	sessionsCmd.AddCommand(sessionsRmCmd)
+}
+
+// printSessionList renders one aligned line per session. currentID, when
+// set, is marked so /sessions in chat shows which one is active.
+func printSessionList(all []*session.Session, currentID string) {
+	for _, s := range all {
+		marker := "  "
+		if s.ID == currentID {
+			marker = display.Accent("● ")
+		}
+		age := humanDuration(time.Since(s.UpdatedAt)) + " ago"
+		fmt.Printf("%s%s  %8s  %3d turns  %s\n",
+			marker, display.Accent(s.ID), age, s.UserTurns(), s.Title)
+	}
+}
+
+// transcriptToolResultLines caps how much of each tool result is echoed.
+const transcriptToolResultLines = 8
+
+// printTranscript renders a saved history the way chat showed it: user turns
+// behind the prompt symbol, assistant answers as markdown, tool calls and
+// their (abbreviated) results in muted text. The system context is skipped.
+func printTranscript(msgs []api.Message) {
+	for _, m := range msgs {
+		switch m.Role {
+		case "user":
+			fmt.Println(display.Prompt("❯ ") + m.Content)
+			if len(m.Attachments) > 0 {
+				fmt.Println(display.Muted("  📎 " + attachmentNames(m.Attachments)))
+			}
+			fmt.Println()
+		case "assistant":
+			if m.Content != "" {
+				fmt.Print(display.RenderMarkdown(m.Content))
+			}
+			for _, tc := range m.ToolCalls {
+				fmt.Println(display.Muted("🔧 " + tc.Function.Name + " " + tc.Function.Arguments))
+			}
+			if footer := display.RenderSources(m.Sources, false); footer != "" {
+				fmt.Print(footer)
+			}
+		case "tool":
+			lines := strings.Split(strings.TrimRight(m.Content, "\n"), "\n")
+			if len(lines) > transcriptToolResultLines {
+				more := len(lines) - transcriptToolResultLines
+				lines = append(lines[:transcriptToolResultLines], fmt.Sprintf("... (%d more lines)", more))
+			}
+			for _, line := range lines {
+				fmt.Println(display.Muted("  │ " + line))
+			}
+			fmt.Println()
+		}
+	}
+}
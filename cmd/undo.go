@@ -0,0 +1,127 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"docsgpt-cli/internal/display"
+	"docsgpt-cli/internal/tools"
+	"docsgpt-cli/internal/undo"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	undoSession string
+	undoAll     bool
+	undoList    bool
+	undoForce   bool
+)
+
+var undoCmd = &cobra.Command{
+	Use:   "undo",
+	Short: "Revert file changes made by tools in a session",
+	Long: `Every file that write_file or edit_file changes is backed up first under
+~/.docsgpt/undo/<session>. 'undo' reverts the most recent change of a
+session (the one that changed files most recently, unless --session is
+given); --all reverts all of them, newest first, and --list shows what the
+session changed as a diff without touching anything.
+
+A change is not reverted if the file was modified again afterwards; pass
+--force to restore the earlier version anyway.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		id, err := undo.Resolve(undoSession)
+		if err != nil {
+			return err
+		}
+		j := undo.Open(id)
+		if undoList {
+			fmt.Println(display.KeyValue("session:", id))
+			return printChanges(j)
+		}
+		for {
+			if err := undoLast(j, undoForce); err != nil {
+				if errors.Is(err, undo.ErrNothingToUndo) {
+					fmt.Println(display.Muted("No tool changes left to undo in session " + id + "."))
+					return nil
+				}
+				return err
+			}
+			if !undoAll {
+				return nil
+			}
+		}
+	},
+}
+
+func init() {
+	undoCmd.Flags().StringVar(&undoSession, "session", "last", "Session id (or unambiguous prefix); \"last\" is the session that changed files most recently")
+	undoCmd.Flags().BoolVar(&undoAll, "all", false, "Revert every change of the session, newest first")
+	undoCmd.Flags().BoolVar(&undoList, "list", false, "Show the files the session changed, with a diff, and revert nothing")
+	undoCmd.Flags().BoolVar(&undoForce, "force", false, "Revert even if a file was modified after the tool changed it")
+}
+
+// undoLast reverts the journal's most recent change and reports it.
+func undoLast(j *undo.Journal, force bool) error {
+	e, err := j.Undo(force)
+	if err != nil {
+		return err
+	}
+	if e.Existed {
+		fmt.Println(display.Success("Restored "+displayPath(e.Path)) + display.Muted(" (undid "+e.Tool+" from "+e.Time.Local().Format("15:04:05")+")"))
+	} else {
+		fmt.Println(display.Success("Removed "+displayPath(e.Path)) + display.Muted(" (it was created by "+e.Tool+")"))
+	}
+	return nil
+}
+
+// printChanges lists every file the journal's session changed, each with a
+// colored diff from its content before the session to now.
+func printChanges(j *undo.Journal) error {
+	changes, err := j.Changes()
+	if err != nil {
+		return err
+	}
+	if len(changes) == 0 {
+		fmt.Println(display.Muted("No files changed by tools in this session."))
+		return nil
+	}
+	for _, c := range changes {
+		writes := "1 write"
+		if c.Writes > 1 {
+			writes = fmt.Sprintf("%d writes", c.Writes)
+		}
+		status := ""
+		switch {
+		case c.Equal():
+			status = ", now unchanged"
+		case c.Original == nil:
+			status = ", created"
+		case c.Current == nil:
+			status = ", since deleted"
+		}
+		fmt.Println()
+		fmt.Println(display.Accent(displayPath(c.Path)) + display.Muted(" ("+writes+status+")"))
+		for _, line := range tools.UnifiedDiff(string(c.Original), string(c.Current), 3) {
+			fmt.Println("  " + display.ColorDiffLine(line))
+		}
+	}
+	return nil
+}
+
+// displayPath shows path relative to the working directory when it lies
+// inside it.
+func displayPath(path string) string {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return path
+	}
+	if rel, err := filepath.Rel(cwd, path); err == nil && !strings.HasPrefix(rel, "..") {
+		return rel
+	}
+	return path
+}
@@ -0,0 +1,121 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"docsgpt-cli/internal/api"
+	"docsgpt-cli/internal/bench/pricing"
+	"docsgpt-cli/internal/bench/spec"
+	"docsgpt-cli/internal/display"
+)
+
+// modelsFetchTimeout bounds the catalog request behind /model completion and
+// cost estimates.
+const modelsFetchTimeout = 5 * time.Second
+
+// fetchModelList fetches the server's model catalog without retries, so a
+// slow or older server never stalls the prompt. It returns nil when the
+// catalog is unavailable.
+func fetchModelList(client *api.Client) *api.ModelList {
+	c := *client
+	c.Retry.MaxRetries = 0
+	c.OnRetry = nil
+	ctx, cancel := context.WithTimeout(context.Background(), modelsFetchTimeout)
+	defer cancel()
+	list, err := c.ListModels(ctx)
+	if err != nil {
+		return nil
+	}
+	return list
+}
+
+// turnCost is the priced usage of one answer.
+type turnCost struct {
+	Usage api.Usage
+	// Model is the model the usage was priced for ("" when unknown).
+	Model  string
+	Price  spec.ModelPricing
+	Cost   float64
+	Priced bool
+}
+
+// priceUsage estimates the cost of u from the catalog's pricing. The model
+// is the first of candidates that is set: normally the model the server
+// reported, then the requested one, then the catalog default.
+func priceUsage(u api.Usage, list *api.ModelList, candidates ...string) turnCost {
+	tc := turnCost{Usage: u}
+	if list != nil {
+		candidates = append(candidates, list.DefaultID)
+	}
+	for _, m := range candidates {
+		if m != "" {
+			tc.Model = m
+			break
+		}
+	}
+	if tc.Model == "" || list == nil {
+		return tc
+	}
+	prices := pricing.New(nil)
+	for _, m := range list.Models {
+		if m.ID != tc.Model {
+			continue
+		}
+		if p, ok := pricing.ForModel(m.Raw); ok {
+			prices.Merge(map[string]spec.ModelPricing{m.ID: p})
+			tc.Price = p
+		}
+	}
+	tc.Cost, tc.Priced = prices.Cost(tc.Model, u.PromptTokens, u.CompletionTokens)
+	return tc
+}
+
+// usageStatusLine is the muted line printed after an answer: this turn's
+// tokens and cost, then the running session total when there is one.
+func usageStatusLine(turn turnCost, session *api.Usage, sessionCost float64) string {
+	line := fmt.Sprintf("%s in · %s out", formatCount(turn.Usage.PromptTokens), formatCount(turn.Usage.CompletionTokens))
+	if turn.Priced {
+		line += " · " + formatUSD(turn.Cost)
+	}
+	if session != nil && session.TotalTokens > turn.Usage.TotalTokens {
+		line += " │ session " + formatCount(session.TotalTokens) + " tokens"
+		if sessionCost > 0 {
+			line += " · " + formatUSD(sessionCost)
+		}
+	}
+	return display.Muted(line)
+}
+
+// usageSummary renders one /usage row: prompt + completion = total.
+func usageSummary(u api.Usage) string {
+	return fmt.Sprintf("%s in + %s out = %s tokens",
+		formatCount(u.PromptTokens), formatCount(u.CompletionTokens), formatCount(u.TotalTokens))
+}
+
+// formatCount renders n with thousands separators: 12345 → 12,345.
+func formatCount(n int) string {
+	s := strconv.Itoa(n)
+	if n < 0 {
+		return "-" + formatCount(-n)
+	}
+	var b strings.Builder
+	for i, r := range s {
+		if i > 0 && (len(s)-i)%3 == 0 {
+			b.WriteByte(',')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// formatUSD renders a cost with enough precision for sub-cent turns.
+func formatUSD(c float64) string {
+	if c < 0.01 {
+		return fmt.Sprintf("$%.4f", c)
+	}
+	return fmt.Sprintf("$%.2f", c)
+}
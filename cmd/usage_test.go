@@ -0,0 +1,51 @@
+package cmd
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+
+	"docsgpt-cli/internal/api"
+)
+
+func TestFormatCount(t *testing.T) {
+	for n, want := range map[int]string{0: "0", 999: "999", 1000: "1,000", 1234567: "1,234,567", -4200: "-4,200"} {
+		if got := formatCount(n); got != want {
+			t.Errorf("formatCount(%d) = %q, want %q", n, got, want)
+		}
+	}
+}
+
+func TestPriceUsage(t *testing.T) {
+	list := &api.ModelList{
+		DefaultID: "cheap",
+		Models: []api.Model{
+			{ID: "cheap", Raw: json.RawMessage(`{"id":"cheap","pricing":{"input":1,"output":2}}`)},
+			{ID: "big", Raw: json.RawMessage(`{"id":"big","input_cost_per_token":0.00001,"output_cost_per_token":0.00003}`)},
+			{ID: "free", Raw: json.RawMessage(`{"id":"free"}`)},
+		},
+	}
+	u := api.Usage{PromptTokens: 1_000_000, CompletionTokens: 500_000, TotalTokens: 1_500_000}
+	tests := []struct {
+		name       string
+		list       *api.ModelList
+		candidates []string
+		model      string
+		cost       float64
+		priced     bool
+	}{
+		{"reported model wins", list, []string{"big", "cheap"}, "big", 25, true},
+		{"falls back to requested model", list, []string{"", "cheap"}, "cheap", 2, true},
+		{"falls back to catalog default", list, []string{"", ""}, "cheap", 2, true},
+		{"unpriced model", list, []string{"free"}, "free", 0, false},
+		{"no catalog", nil, []string{"big"}, "big", 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tc := priceUsage(u, tt.list, tt.candidates...)
+			if tc.Model != tt.model || tc.Priced != tt.priced || math.Abs(tc.Cost-tt.cost) > 1e-9 {
+				t.Errorf("priceUsage = %+v, want model %s cost %v priced %v", tc, tt.model, tt.cost, tt.priced)
+			}
+		})
+	}
+}
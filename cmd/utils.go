@@ -1,13 +1,28 @@
 package cmd
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
 	"regexp"
+	"slices"
 	"strings"
+	"time"
 
+	"docsgpt-cli/internal/api"
+	"docsgpt-cli/internal/config"
+	ctxenrich "docsgpt-cli/internal/context"
 	"docsgpt-cli/internal/display"
+	"docsgpt-cli/internal/redact"
+	"docsgpt-cli/internal/sandbox"
+	"docsgpt-cli/internal/tools"
 
 	"github.com/atotto/clipboard"
+	"github.com/mattn/go-isatty"
+	"github.com/spf13/cobra"
 )
 
 func printError(message string) {
@@ -39,3 +54,249 @@ func copyToClipboard(command string) {
 		fmt.Printf("%s %s\n", display.Success("Command copied to clipboard:"), display.Success(trimmedCommand))
 	}
 }
+
+// stdoutIsTTY reports whether stdout is an interactive terminal, as opposed
+// to a pipe or file that should receive only the answer.
+func stdoutIsTTY() bool {
+	fd := os.Stdout.Fd()
+	return isatty.IsTerminal(fd) || isatty.IsCygwinTerminal(fd)
+}
+
+// newAPIClient builds the chat client used by ask and chat: the model comes
+// from resolveModel, the retry budget from --max-retries when given, else
+// from the config (as does the tool round cap, --max-tool-rounds), the
+// per-request deadline from --request-timeout, and each retry is announced
+// on stderr so a slow answer never looks like a hang.
+func newAPIClient(cfg config.Config, baseURL, apiKey string) *api.Client {
+	client := api.NewClient(baseURL, apiKey)
+	client.Model = resolveModel(cfg)
+	client.Retry.MaxRetries = cfg.Settings.MaxRetries
+	if f := rootCmd.PersistentFlags().Lookup("max-retries"); f != nil && f.Changed {
+		client.Retry.MaxRetries = globalMaxRetries
+	}
+	client.RequestTimeout = time.Duration(globalReqTimeout) * time.Second
+	client.MaxToolRounds = cfg.Settings.MaxToolRounds
+	if f := rootCmd.PersistentFlags().Lookup("max-tool-rounds"); f != nil && f.Changed {
+		client.MaxToolRounds = globalMaxToolRounds
+	}
+	client.OnToolLoop = confirmToolLoop
+	client.OnRetry = func(ev api.RetryEvent) {
+		fmt.Fprintln(os.Stderr, display.Muted(describeRetry(ev)))
+	}
+	return client
+}
+
+// configureTools applies the tool settings for ask and chat: the output
+// budget, the sandbox and secret redaction.
+func configureTools(cfg config.Config) (err error) {
+	tools.OutputBudget = tools.DefaultOutputBudget
+	if cfg.Settings.OutputBudget > 0 {
+		tools.OutputBudget = cfg.Settings.OutputBudget
+	}
+	tools.HTTPAllowPrivate = cfg.Settings.HTTPAllowPrivate
+	if tools.Sandbox, err = sandboxOptions(cfg); err != nil {
+		return err
+	}
+	r, err := newRedactor(cfg)
+	tools.Redactor, ctxenrich.Redactor = r, r
+	return err
+}
+
+// newRedactor builds the secret redactor from the settings; nil when
+// redaction is off. The configured API keys are always masked, and with
+// --show-redactions each masked secret is reported on stderr.
+func newRedactor(cfg config.Config) (*redact.Redactor, error) {
+	r, err := redact.New(cfg.Settings.Redact, cfg.Settings.RedactPatterns)
+	if err != nil || r == nil {
+		return nil, err
+	}
+	for _, key := range cfg.Keys {
+		r.AddSecret("docsgpt-api-key", key)
+	}
+	if globalShowRedacted {
+		r.Report = func(where string, found []redact.Finding) {
+			for _, f := range found {
+				fmt.Fprintln(os.Stderr, display.Warn("redacted")+display.Muted(" from "+where+": "+f.String()))
+			}
+		}
+	}
+	return r, nil
+}
+
+// loadCustomTools registers the tools declared in tools.d/*.yaml, and
+// warns about the files that could not be loaded.
+func loadCustomTools() {
+	cwd, _ := os.Getwd()
+	_, errs := tools.LoadCustom(cwd)
+	for _, err := range errs {
+		fmt.Fprintln(tools.Prompts, display.Warn("Custom tool skipped: "+err.Error()))
+	}
+}
+
+// sandboxOptions resolves the sandbox for tool commands: --sandbox, then
+// the config setting. The project directory (the working directory) and
+// the configured sandbox_paths are writable. nil means no sandbox; one that
+// was asked for but cannot be set up is an error, so commands never run
+// unconfined by surprise.
+func sandboxOptions(cfg config.Config) (*sandbox.Options, error) {
+	opts, err := sandboxSettings(cfg)
+	if err != nil || opts == nil {
+		return nil, err
+	}
+	cwd, err := os.Getwd()
+	if err != nil {
+		return nil, err
+	}
+	if err := sandbox.CheckRoot(cwd); err != nil {
+		return nil, fmt.Errorf("%w; start in a project directory", err)
+	}
+	opts.Writable = append([]string{cwd}, opts.Writable...)
+	return opts, nil
+}
+
+// sandboxSettings resolves the sandbox mode like sandboxOptions, with only
+// the configured sandbox_paths writable. Host mode adds each invocation's
+// root to it.
+func sandboxSettings(cfg config.Config) (*sandbox.Options, error) {
+	mode := cfg.Settings.Sandbox
+	if f := rootCmd.PersistentFlags().Lookup("sandbox"); f != nil && f.Changed {
+		mode = globalSandbox
+	}
+	enabled, network, err := sandbox.ParseMode(mode)
+	if err != nil || !enabled {
+		return nil, err
+	}
+	if _, err := sandbox.Backend(); err != nil {
+		return nil, err
+	}
+	return &sandbox.Options{
+		Writable: slices.Clone(cfg.Settings.SandboxPaths),
+		Network:  network,
+	}, nil
+}
+
+// describeSandbox renders a one-line summary of the sandbox in effect.
+func describeSandbox(opts *sandbox.Options) string {
+	name, _ := sandbox.Backend()
+	network := "network off"
+	if opts.Network {
+		network = "network on"
+	}
+	return fmt.Sprintf("Sandbox: %s, %s; writable: %s", name, network, strings.Join(opts.Writable, ", "))
+}
+
+// resolveModel picks the model for ask and chat: --model, then the config
+// setting. "" leaves the choice to the server.
+func resolveModel(cfg config.Config) string {
+	if globalModel != "" {
+		return globalModel
+	}
+	return cfg.Settings.Model
+}
+
+// describeRetry renders a retry notice such as "rate limited, retrying in
+// 4s (1/3)".
+func describeRetry(ev api.RetryEvent) string {
+	what := "connection failed"
+	var apiErr *api.APIError
+	if errors.As(ev.Err, &apiErr) {
+		if apiErr.StatusCode == http.StatusTooManyRequests {
+			what = "rate limited"
+		} else {
+			what = fmt.Sprintf("server error %d", apiErr.StatusCode)
+		}
+	}
+	return fmt.Sprintf("%s, retrying in %s (%d/%d)", what, formatWait(ev.Delay), ev.Attempt, ev.MaxRetries)
+}
+
+// formatWait renders a short wait as "4s", or "0.5s" below one second.
+func formatWait(d time.Duration) string {
+	if d < time.Second {
+		return fmt.Sprintf("%.1fs", d.Seconds())
+	}
+	return d.Round(time.Second).String()
+}
+
+// friendlyError turns an API failure into an actionable message; other
+// errors pass through unchanged.
+func friendlyError(err error) string {
+	if errors.Is(err, api.ErrRequestTimeout) {
+		return err.Error() + " (see --request-timeout)"
+	}
+	var apiErr *api.APIError
+	if !errors.As(err, &apiErr) {
+		return err.Error()
+	}
+	switch {
+	case apiErr.StatusCode == http.StatusUnauthorized || apiErr.StatusCode == http.StatusForbidden:
+		return fmt.Sprintf("the server rejected the API key (%d: %s). Check it with 'docsgpt-cli keys'",
+			apiErr.StatusCode, apiErr.Message)
+	case apiErr.StatusCode == http.StatusTooManyRequests:
+		msg := "rate limited by the server"
+		if apiErr.RetryAfter > 0 {
+			msg += ", try again in " + formatWait(apiErr.RetryAfter)
+		}
+		return msg
+	case apiErr.StatusCode >= 500:
+		return fmt.Sprintf("the server failed to answer (%d: %s)", apiErr.StatusCode, apiErr.Message)
+	}
+	return err.Error()
+}
+
+// interruptContext works like signal.NotifyContext for Ctrl-C, except that
+// a Ctrl-C while a run_command is in progress only stops that command: the
+// model gets the command's partial output and the turn goes on.
+func interruptContext() (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt)
+	go func() {
+		for {
+			select {
+			case <-sigs:
+				if !tools.Interrupt() {
+					cancel()
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return ctx, func() {
+		signal.Stop(sigs)
+		cancel()
+	}
+}
+
+// The opt-in git context flags of ask and chat.
+var (
+	withDiff   bool
+	withStaged bool
+	withLog    int
+)
+
+// addGitContextFlags adds --with-diff, --with-staged and --with-log to cmd.
+func addGitContextFlags(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(&withDiff, "with-diff", false, "Send the unstaged changes (git diff) as context")
+	cmd.Flags().BoolVar(&withStaged, "with-staged", false, "Send the staged changes (git diff --cached) as context")
+	cmd.Flags().IntVar(&withLog, "with-log", 0, "Send the last N commits, with their messages, as context")
+}
+
+// applyGitContextFlags overrides the git context settings with the flags
+// given on the command line.
+func applyGitContextFlags(cmd *cobra.Command, s *config.Settings) error {
+	flags := cmd.Flags()
+	if flags.Changed("with-diff") {
+		s.SendGitDiff = withDiff
+	}
+	if flags.Changed("with-staged") {
+		s.SendGitStaged = withStaged
+	}
+	if flags.Changed("with-log") {
+		if withLog < 0 || withLog > ctxenrich.MaxLogCommits {
+			return fmt.Errorf("invalid --with-log %d (use 0 to %d)", withLog, ctxenrich.MaxLogCommits)
+		}
+		s.GitLogCommits = withLog
+	}
+	return nil
+}
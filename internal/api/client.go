@@ -5,16 +5,33 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
 	"net/http"
 	"strings"
+	"time"
 )
 
 type Client struct {
 	BaseURL    string
 	APIKey     string
 	HTTPClient *http.Client
+	// Model, when set, is sent as the model for every chat request; empty
+	// leaves the choice to the server (the agent's default).
+	Model string
+	Retry RetryPolicy
+	// OnRetry, when set, is called before each retry sleep.
+	OnRetry func(RetryEvent)
+	// RequestTimeout, when positive, bounds each Send or SendStream call
+	// as a whole: connecting, retries and reading the streamed answer.
+	RequestTimeout time.Duration
+	// MaxToolRounds caps the tool-calling rounds of one RunWithTools turn:
+	// 0 uses DefaultMaxToolRounds, a negative value removes the cap.
+	MaxToolRounds int
+	// OnToolLoop, when set, is asked whether to keep running tools when the
+	// model repeats an identical call (return false to stop), and is told
+	// when the round limit is reached (the return value is then ignored).
+	OnToolLoop func(ToolLoopEvent) bool
 }
 
 func NewClient(baseURL, apiKey string) *Client {
@@ -22,6 +39,7 @@ func NewClient(baseURL, apiKey string) *Client {
 		BaseURL:    strings.TrimRight(baseURL, "/"),
 		APIKey:     apiKey,
 		HTTPClient: &http.Client{},
+		Retry:      DefaultRetryPolicy(),
 	}
 }
 
@@ -29,31 +47,59 @@ func (c *Client) endpoint() string {
 	return c.BaseURL + "/v1/chat/completions"
 }
 
+// ErrRequestTimeout reports an API call that ran past Client.RequestTimeout.
+var ErrRequestTimeout = errors.New("the API request timed out")
+
+// withTimeout derives the context for one API call from RequestTimeout.
+func (c *Client) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if c.RequestTimeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, c.RequestTimeout)
+}
+
+// timeoutErr rewrites err as ErrRequestTimeout when reqCtx hit the request
+// deadline while the caller's ctx was still live. A cut-off stream keeps
+// ErrIncompleteStream so its partial answer is still handled.
+func (c *Client) timeoutErr(ctx, reqCtx context.Context, err error) error {
+	if err == nil || ctx.Err() != nil || !errors.Is(reqCtx.Err(), context.DeadlineExceeded) {
+		return err
+	}
+	timeout := fmt.Errorf("%w after %s", ErrRequestTimeout, c.RequestTimeout)
+	if errors.Is(err, ErrIncompleteStream) {
+		return fmt.Errorf("%w: %w", ErrIncompleteStream, timeout)
+	}
+	return timeout
+}
+
 // Send performs a non-streaming chat completion request.
 func (c *Client) Send(ctx context.Context, req ChatRequest) (*ChatResponse, error) {
+	reqCtx, cancel := c.withTimeout(ctx)
+	defer cancel()
+	resp, err := c.send(reqCtx, req)
+	return resp, c.timeoutErr(ctx, reqCtx, err)
+}
+
+func (c *Client) send(ctx context.Context, req ChatRequest) (*ChatResponse, error) {
 	req.Stream = false
 	body, err := json.Marshal(req)
 	if err != nil {
 		return nil, fmt.Errorf("marshal request: %w", err)
 	}
 
-	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, c.endpoint(), bytes.NewReader(body))
-	if err != nil {
-		return nil, err
-	}
-	c.setHeaders(httpReq)
-
-	resp, err := c.HTTPClient.Do(httpReq)
+	resp, err := c.do(ctx, func() (*http.Request, error) {
+		httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, c.endpoint(), bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		c.setHeaders(httpReq)
+		return httpReq, nil
+	})
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		respBody, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("API error %d: %s", resp.StatusCode, string(respBody))
-	}
-
 	var chatResp ChatResponse
 	if err := json.NewDecoder(resp.Body).Decode(&chatResp); err != nil {
 		return nil, fmt.Errorf("decode response: %w", err)
@@ -61,39 +107,53 @@ func (c *Client) Send(ctx context.Context, req ChatRequest) (*ChatResponse, erro
 	return &chatResp, nil
 }
 
+// ErrIncompleteStream reports a streamed answer that ended before the server
+// signalled completion (the connection dropped, or the body ended without
+// [DONE] or a finish_reason).
+var ErrIncompleteStream = errors.New("the stream ended before the answer was complete")
+
 // SendStream performs a streaming chat completion request.
 // onDelta is called for each SSE chunk with the delta and finish_reason.
-// Returns the accumulated final response.
+// Returns the accumulated final response. On an error wrapping
+// ErrIncompleteStream the partial response is returned alongside it.
 func (c *Client) SendStream(ctx context.Context, req ChatRequest, onDelta func(Delta, string)) (*ChatResponse, error) {
+	reqCtx, cancel := c.withTimeout(ctx)
+	defer cancel()
+	resp, err := c.sendStream(reqCtx, req, onDelta)
+	return resp, c.timeoutErr(ctx, reqCtx, err)
+}
+
+func (c *Client) sendStream(ctx context.Context, req ChatRequest, onDelta func(Delta, string)) (*ChatResponse, error) {
 	req.Stream = true
+	req.StreamOptions = &StreamOptions{IncludeUsage: true}
 	body, err := json.Marshal(req)
 	if err != nil {
 		return nil, fmt.Errorf("marshal request: %w", err)
 	}
 
-	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, c.endpoint(), bytes.NewReader(body))
-	if err != nil {
-		return nil, err
-	}
-	c.setHeaders(httpReq)
-	httpReq.Header.Set("Accept", "text/event-stream")
-
-	resp, err := c.HTTPClient.Do(httpReq)
+	resp, err := c.do(ctx, func() (*http.Request, error) {
+		httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, c.endpoint(), bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		c.setHeaders(httpReq)
+		httpReq.Header.Set("Accept", "text/event-stream")
+		return httpReq, nil
+	})
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		respBody, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("API error %d: %s", resp.StatusCode, string(respBody))
-	}
-
 	// Accumulate the full response
 	var accumulated Delta
 	var finishReason string
 	var conversationID string
+	var sources json.RawMessage
+	var usage *Usage
+	var model string
 	var accToolCalls []ToolCall
+	done := false
 
 	scanner := bufio.NewScanner(resp.Body)
 	for scanner.Scan() {
@@ -105,6 +165,7 @@ func (c *Client) SendStream(ctx context.Context, req ChatRequest, onDelta func(D
 		data := strings.TrimPrefix(line, "data: ")
 
 		if data == "[DONE]" {
+			done = true
 			break
 		}
 
@@ -112,20 +173,33 @@ func (c *Client) SendStream(ctx context.Context, req ChatRequest, onDelta func(D
 		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
 			// Try parsing as a docsgpt metadata chunk
 			var meta struct {
-				DocsGPT struct {
-					ConversationID string `json:"conversation_id"`
-				} `json:"docsgpt"`
+				DocsGPT DocsGPTMeta `json:"docsgpt"`
 			}
-			if json.Unmarshal([]byte(data), &meta) == nil && meta.DocsGPT.ConversationID != "" {
-				conversationID = meta.DocsGPT.ConversationID
+			if json.Unmarshal([]byte(data), &meta) == nil {
+				if meta.DocsGPT.ConversationID != "" {
+					conversationID = meta.DocsGPT.ConversationID
+				}
+				if len(meta.DocsGPT.Sources) > 0 {
+					sources = meta.DocsGPT.Sources
+				}
 			}
 			continue
 		}
 
-		// Also check for conversation_id in standard response chunks
+		// Also check for conversation_id and sources in standard response chunks
 		if chunk.DocsGPT.ConversationID != "" {
 			conversationID = chunk.DocsGPT.ConversationID
 		}
+		if len(chunk.DocsGPT.Sources) > 0 {
+			sources = chunk.DocsGPT.Sources
+		}
+		// Usage arrives on the final chunk (stream_options.include_usage).
+		if chunk.Usage != nil {
+			usage = chunk.Usage
+		}
+		if chunk.Model != "" {
+			model = chunk.Model
+		}
 
 		if len(chunk.Choices) == 0 {
 			continue
@@ -169,48 +243,124 @@ func (c *Client) SendStream(ctx context.Context, req ChatRequest, onDelta func(D
 		}
 	}
 
-	if err := scanner.Err(); err != nil {
-		return nil, fmt.Errorf("reading stream: %w", err)
-	}
-
 	accumulated.ToolCalls = accToolCalls
 
-	return &ChatResponse{
+	result := &ChatResponse{
 		Choices: []Choice{
 			{
 				Message:      accumulated,
 				FinishReason: finishReason,
 			},
 		},
-		DocsGPT: DocsGPTMeta{ConversationID: conversationID},
-	}, nil
+		DocsGPT: DocsGPTMeta{ConversationID: conversationID, Sources: sources},
+		Usage:   usage,
+		Model:   model,
+	}
+
+	// A stream that breaks off, or simply ends without [DONE] or a
+	// finish_reason, was cut short. Hand back what arrived so the caller
+	// can keep the partial answer.
+	if err := scanner.Err(); err != nil {
+		return result, fmt.Errorf("%w: %w", ErrIncompleteStream, err)
+	}
+	if !done && finishReason == "" {
+		return result, ErrIncompleteStream
+	}
+	return result, nil
 }
 
 // ToolCallHandler is called when the model requests a tool call.
 // It receives the tool call and should return the result string.
 type ToolCallHandler func(tc ToolCall) string
 
+// ToolBatchHandler runs the tool calls of one round together, so that a
+// caller can approve or run independent calls at once. It must return one
+// result per call, in the order of calls.
+type ToolBatchHandler func(calls []ToolCall) []string
+
+// TurnResult is the outcome of one RunWithTools call. It is returned even on
+// error, holding whatever history had accumulated by then.
+type TurnResult struct {
+	History        []Message
+	ConversationID string
+	// Sources is the raw docsgpt.sources of the last response that had any.
+	Sources json.RawMessage
+	// Usage sums the token usage of every request in the tool loop; nil
+	// when the server reported none.
+	Usage *Usage
+	// Model is the model the server reported using ("" when not reported).
+	Model string
+}
+
 // RunWithTools sends a chat request and handles tool call loops.
 // When the model returns tool_calls, onToolCall is invoked for each one,
 // and results are sent back in a continuation request. This repeats
 // until the model returns finish_reason "stop" (or non-tool_calls).
+// conversationID continues an existing server-side conversation ("" starts
+// a new one); the id the server reports is returned in the result. When a
+// stream is cut short, the partial answer is kept in the returned history
+// (flagged Incomplete) and the error wraps ErrIncompleteStream. Attachments
+// on the last user message are sent with every request of the turn, so the
+// continuation after a tool call still sees them.
+//
+// The loop is bounded by MaxToolRounds, and identical calls repeated within
+// the turn are put to OnToolLoop. Once the loop is stopped, remaining calls
+// are not run: their results tell the model to answer with what it has. A
+// model that still asks for tools after that ends the turn with
+// ErrToolLoopStopped.
 func (c *Client) RunWithTools(
 	ctx context.Context,
+	conversationID string,
 	messages []Message,
 	tools []Tool,
 	stream bool,
 	onDelta func(Delta, string),
 	onToolCall ToolCallHandler,
-) ([]Message, error) {
+) (TurnResult, error) {
+	return c.RunWithToolBatches(ctx, conversationID, messages, tools, stream, onDelta, func(calls []ToolCall) []string {
+		results := make([]string, len(calls))
+		for i, tc := range calls {
+			results[i] = onToolCall(tc)
+		}
+		return results
+	})
+}
+
+// RunWithToolBatches is RunWithTools with the calls of each round handed to
+// onToolCalls together rather than one by one. Calls stopped by the tool
+// loop checks are left out of the batch; results are appended to history in
+// the order the model made the calls.
+func (c *Client) RunWithToolBatches(
+	ctx context.Context,
+	conversationID string,
+	messages []Message,
+	tools []Tool,
+	stream bool,
+	onDelta func(Delta, string),
+	onToolCalls ToolBatchHandler,
+) (TurnResult, error) {
 	history := make([]Message, len(messages))
 	copy(history, messages)
-	var conversationID string
+	var ext *DocsGPTRequest
+	if ids := turnAttachmentIDs(history); len(ids) > 0 {
+		ext = &DocsGPTRequest{Attachments: ids}
+	}
+
+	var sources json.RawMessage
+	var usage *Usage
+	var model string
+	result := func() TurnResult {
+		return TurnResult{History: history, ConversationID: conversationID, Sources: sources, Usage: usage, Model: model}
+	}
 
+	loop := c.newToolLoop()
 	for {
 		req := ChatRequest{
-			Messages:       history,
+			Model:          c.Model,
+			Messages:       outbound(history),
 			Tools:          tools,
 			ConversationID: conversationID,
+			DocsGPT:        ext,
 		}
 
 		var resp *ChatResponse
@@ -222,47 +372,153 @@ func (c *Client) RunWithTools(
 			resp, err = c.Send(ctx, req)
 		}
 		if err != nil {
-			return history, err
+			if errors.Is(err, ErrIncompleteStream) && resp != nil {
+				keepPartial(&history, &conversationID, resp)
+				usage = addUsage(usage, resp.Usage)
+				if len(resp.DocsGPT.Sources) > 0 {
+					sources = resp.DocsGPT.Sources
+				}
+			}
+			return result(), err
 		}
 
 		// Track conversation_id for continuation requests
 		if resp.DocsGPT.ConversationID != "" {
 			conversationID = resp.DocsGPT.ConversationID
 		}
+		if len(resp.DocsGPT.Sources) > 0 {
+			sources = resp.DocsGPT.Sources
+		}
+		usage = addUsage(usage, resp.Usage)
+		if resp.Model != "" {
+			model = resp.Model
+		}
 
 		if len(resp.Choices) == 0 {
-			return history, fmt.Errorf("empty response from API")
+			return result(), fmt.Errorf("empty response from API")
 		}
 
 		choice := resp.Choices[0]
 
 		// Append the assistant message to history
 		assistantMsg := Message{
-			Role:      "assistant",
-			Content:   choice.Message.Content,
-			ToolCalls: choice.Message.ToolCalls,
+			Role:             "assistant",
+			Content:          choice.Message.Content,
+			ToolCalls:        choice.Message.ToolCalls,
+			ReasoningContent: choice.Message.ReasoningContent,
 		}
 		history = append(history, assistantMsg)
 
-		// If no tool calls, we're done
+		// If no tool calls, we're done. The turn's sources may have come
+		// with an earlier request of the loop; cite them on the answer.
 		if choice.FinishReason != "tool_calls" || len(choice.Message.ToolCalls) == 0 {
-			return history, nil
+			history[len(history)-1].Sources = ParseSources(sources)
+			return result(), nil
 		}
 
 		// Process each tool call
-		for _, tc := range choice.Message.ToolCalls {
-			result := onToolCall(tc)
+		calls := choice.Message.ToolCalls
+		ignoredStop := !loop.startRound(calls)
+		results := make([]string, len(calls))
+		var runnable []ToolCall
+		var slots []int
+		for i, tc := range calls {
+			if results[i] = loop.check(tc); results[i] == "" {
+				runnable = append(runnable, tc)
+				slots = append(slots, i)
+			}
+		}
+		if len(runnable) > 0 {
+			for j, r := range onToolCalls(runnable) {
+				results[slots[j]] = r
+			}
+		}
+		for i, tc := range calls {
 			history = append(history, Message{
 				Role:       "tool",
-				Content:    result,
+				Content:    results[i],
 				ToolCallID: tc.ID,
 			})
 		}
+		if ignoredStop {
+			return result(), ErrToolLoopStopped
+		}
 
 		// Loop continues — sends history with tool results back to API
 	}
 }
 
+// turnAttachmentIDs returns the attachment ids of the last user message.
+func turnAttachmentIDs(history []Message) []string {
+	for i := len(history) - 1; i >= 0; i-- {
+		if history[i].Role != "user" {
+			continue
+		}
+		var ids []string
+		for _, a := range history[i].Attachments {
+			ids = append(ids, a.ID)
+		}
+		return ids
+	}
+	return nil
+}
+
+// addUsage returns the sum of total and u; nil only when both are nil. A
+// missing total_tokens is derived from the prompt and completion counts.
+func addUsage(total, u *Usage) *Usage {
+	if u == nil {
+		return total
+	}
+	if total == nil {
+		total = &Usage{}
+	}
+	total.PromptTokens += u.PromptTokens
+	total.CompletionTokens += u.CompletionTokens
+	if u.TotalTokens > 0 {
+		total.TotalTokens += u.TotalTokens
+	} else {
+		total.TotalTokens += u.PromptTokens + u.CompletionTokens
+	}
+	return total
+}
+
+// keepPartial records the text of a cut-off answer in history, flagged as
+// incomplete. Half-streamed tool calls are dropped: their arguments may be
+// truncated JSON and must never run.
+func keepPartial(history *[]Message, conversationID *string, resp *ChatResponse) {
+	if resp.DocsGPT.ConversationID != "" {
+		*conversationID = resp.DocsGPT.ConversationID
+	}
+	if len(resp.Choices) == 0 {
+		return
+	}
+	partial := resp.Choices[0].Message
+	if partial.Content == "" && partial.ReasoningContent == "" {
+		return
+	}
+	*history = append(*history, Message{
+		Role:             "assistant",
+		Content:          partial.Content,
+		ReasoningContent: partial.ReasoningContent,
+		Incomplete:       true,
+		Sources:          ParseSources(resp.DocsGPT.Sources),
+	})
+}
+
+// outbound returns history as it should be sent: local-only fields such as
+// reasoning are dropped, since servers reject them on input.
+func outbound(history []Message) []Message {
+	out := make([]Message, len(history))
+	for i, m := range history {
+		m.ReasoningContent = ""
+		m.Incomplete = false
+		m.Attachments = nil
+		m.Sources = nil
+		out[i] = m
+	}
+	return out
+}
+
 func (c *Client) setHeaders(req *http.Request) {
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Authorization", "Bearer "+c.APIKey)
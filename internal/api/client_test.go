@@ -0,0 +1,537 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fastRetry keeps retry tests quick while still exercising the backoff path.
+var fastRetry = RetryPolicy{MaxRetries: 2, BaseDelay: time.Millisecond, MaxDelay: 5 * time.Millisecond}
+
+func TestSendRetriesThrottledThenSucceeds(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			w.Write([]byte(`{"error":{"message":"slow down"}}`))
+			return
+		}
+		w.Write([]byte(`{"choices":[{"message":{"content":"ok"},"finish_reason":"stop"}]}`))
+	}))
+	defer srv.Close()
+
+	c := NewClient(srv.URL, "k")
+	c.Retry = fastRetry
+	var events []RetryEvent
+	c.OnRetry = func(ev RetryEvent) { events = append(events, ev) }
+
+	resp, err := c.Send(context.Background(), ChatRequest{})
+	if err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	if got := resp.Choices[0].Message.Content; got != "ok" {
+		t.Errorf("content = %q, want ok", got)
+	}
+	if calls.Load() != 2 || len(events) != 1 {
+		t.Fatalf("calls = %d, retry events = %d; want 2 and 1", calls.Load(), len(events))
+	}
+	var apiErr *APIError
+	if !errors.As(events[0].Err, &apiErr) || apiErr.StatusCode != 429 || apiErr.Message != "slow down" {
+		t.Errorf("retry event error = %v, want 429 APIError with the server message", events[0].Err)
+	}
+}
+
+func TestSendStreamGivesUpAfterMaxRetries(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		http.Error(w, "bad gateway", http.StatusBadGateway)
+	}))
+	defer srv.Close()
+
+	c := NewClient(srv.URL, "k")
+	c.Retry = fastRetry
+	_, err := c.SendStream(context.Background(), ChatRequest{}, nil)
+
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusBadGateway {
+		t.Fatalf("err = %v, want *APIError 502", err)
+	}
+	if apiErr.Message != "bad gateway" {
+		t.Errorf("Message = %q, want raw body", apiErr.Message)
+	}
+	if got := calls.Load(); got != 3 {
+		t.Errorf("calls = %d, want 1 + MaxRetries = 3", got)
+	}
+}
+
+func TestSendDoesNotRetryClientErrors(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"detail":"invalid key"}`))
+	}))
+	defer srv.Close()
+
+	c := NewClient(srv.URL, "k")
+	c.Retry = fastRetry
+	_, err := c.Send(context.Background(), ChatRequest{})
+
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != 401 || apiErr.Message != "invalid key" {
+		t.Fatalf("err = %v, want 401 APIError with detail message", err)
+	}
+	if calls.Load() != 1 {
+		t.Errorf("calls = %d, want 1 (401 is not retryable)", calls.Load())
+	}
+}
+
+func TestSendRetriesConnectionReset(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			// Drop the connection before any response byte.
+			conn, _, _ := w.(http.Hijacker).Hijack()
+			conn.Close()
+			return
+		}
+		w.Write([]byte(`{"choices":[{"message":{"content":"ok"}}]}`))
+	}))
+	defer srv.Close()
+
+	c := NewClient(srv.URL, "k")
+	c.Retry = fastRetry
+	if _, err := c.Send(context.Background(), ChatRequest{}); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	if calls.Load() != 2 {
+		t.Errorf("calls = %d, want 2", calls.Load())
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	tests := []struct {
+		in   string
+		want time.Duration
+	}{
+		{"", 0},
+		{"7", 7 * time.Second},
+		{"-3", 0},
+		{"soon", 0},
+		{now.Add(10 * time.Second).Format(http.TimeFormat), 10 * time.Second},
+		{now.Add(-time.Minute).Format(http.TimeFormat), 0},
+	}
+	for _, tt := range tests {
+		if got := parseRetryAfter(tt.in, now); got != tt.want {
+			t.Errorf("parseRetryAfter(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	p := RetryPolicy{MaxRetries: 5, BaseDelay: time.Second, MaxDelay: 8 * time.Second}
+	for attempt := 1; attempt <= 6; attempt++ {
+		full := min(time.Second<<(attempt-1), p.MaxDelay)
+		for range 20 {
+			d := p.delay(attempt, 0)
+			if d < full/2 || d > full {
+				t.Fatalf("delay(%d) = %v, want within [%v, %v]", attempt, d, full/2, full)
+			}
+		}
+	}
+	if d := p.delay(1, 5*time.Second); d != 5*time.Second {
+		t.Errorf("Retry-After longer than backoff should win, got %v", d)
+	}
+	if d := p.delay(1, time.Hour); d != p.MaxDelay {
+		t.Errorf("Retry-After must be capped at MaxDelay, got %v", d)
+	}
+}
+
+func sseServer(t *testing.T, body string) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestSendStreamDetectsTruncation(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		incomplete bool
+	}{
+		{"done marker", "data: {\"choices\":[{\"delta\":{\"content\":\"hi\"}}]}\n\ndata: [DONE]\n\n", false},
+		{"finish reason without done", "data: {\"choices\":[{\"delta\":{\"content\":\"hi\"},\"finish_reason\":\"stop\"}]}\n\n", false},
+		{"eof mid answer", "data: {\"choices\":[{\"delta\":{\"content\":\"hi\"}}]}\n\n", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewClient(sseServer(t, tt.body).URL, "k")
+			resp, err := c.SendStream(context.Background(), ChatRequest{}, nil)
+			if got := errors.Is(err, ErrIncompleteStream); got != tt.incomplete {
+				t.Fatalf("err = %v, incomplete = %v, want %v", err, got, tt.incomplete)
+			}
+			if resp == nil || resp.Choices[0].Message.Content != "hi" {
+				t.Errorf("partial content not returned: %+v", resp)
+			}
+		})
+	}
+}
+
+func TestRunWithToolsKeepsPartialAnswer(t *testing.T) {
+	body := "data: {\"docsgpt\":{\"conversation_id\":\"conv-7\"}}\n\n" +
+		"data: {\"choices\":[{\"delta\":{\"content\":\"The fix is\"}}]}\n\n" +
+		"data: {\"choices\":[{\"delta\":{\"tool_calls\":[{\"index\":0,\"id\":\"c1\",\"function\":{\"name\":\"read_file\",\"arguments\":\"{\\\"pa\"}}]}}]}\n\n"
+	c := NewClient(sseServer(t, body).URL, "k")
+
+	called := false
+	turn, err := c.RunWithTools(context.Background(), "", []Message{{Role: "user", Content: "q"}}, nil, true, nil,
+		func(ToolCall) string { called = true; return "" })
+	if !errors.Is(err, ErrIncompleteStream) {
+		t.Fatalf("err = %v, want ErrIncompleteStream", err)
+	}
+	if called {
+		t.Error("a half-streamed tool call must never run")
+	}
+	if turn.ConversationID != "conv-7" {
+		t.Errorf("ConversationID = %q, want conv-7", turn.ConversationID)
+	}
+	if len(turn.History) != 2 {
+		t.Fatalf("history len = %d, want user + partial assistant", len(turn.History))
+	}
+	last := turn.History[1]
+	if last.Content != "The fix is" || !last.Incomplete || len(last.ToolCalls) != 0 {
+		t.Errorf("partial message = %+v, want incomplete text without tool calls", last)
+	}
+	if out := outbound(turn.History); out[1].Incomplete || out[1].Content != "The fix is" {
+		t.Errorf("outbound must strip local-only fields, got %+v", out[1])
+	}
+}
+
+func TestParseModelList(t *testing.T) {
+	docsgpt := []byte(`{"default_model_id":"m2","models":[
+		{"id":"m1","display_name":"Model One","context_window":128000},
+		{"id":"m2","name":"m2","max_input_tokens":8192},
+		{"name":"no id"}]}`)
+	list := parseModelList(docsgpt)
+	if list.DefaultID != "m2" || len(list.Models) != 2 {
+		t.Fatalf("list = %+v, want default m2 and two models", list)
+	}
+	if m := list.Models[0]; m.Name != "Model One" || m.ContextWindow != 128000 {
+		t.Errorf("m1 = %+v", m)
+	}
+	if m := list.Models[1]; m.Name != "" || m.ContextWindow != 8192 {
+		t.Errorf("m2 = %+v, want no name (same as id) and 8192 context", m)
+	}
+	if !list.Has("m1") || list.Has("m3") {
+		t.Error("Has does not match the catalog")
+	}
+
+	openai := parseModelList([]byte(`{"object":"list","data":[{"id":"gpt-x","context_length":32768}]}`))
+	if len(openai.Models) != 1 || openai.Models[0].ContextWindow != 32768 {
+		t.Errorf("OpenAI shape = %+v", openai)
+	}
+}
+
+func TestRunWithToolsSumsUsageAcrossToolLoop(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			w.Write([]byte(`{"choices":[{"message":{"tool_calls":[{"id":"c1","function":{"name":"read_file","arguments":"{}"}}]},"finish_reason":"tool_calls"}],
+				"usage":{"prompt_tokens":10,"completion_tokens":2,"total_tokens":12}}`))
+			return
+		}
+		w.Write([]byte(`{"model":"m1","choices":[{"message":{"content":"done"},"finish_reason":"stop"}],
+			"docsgpt":{"sources":[{"title":"a"}]},
+			"usage":{"prompt_tokens":20,"completion_tokens":5}}`))
+	}))
+	defer srv.Close()
+
+	c := NewClient(srv.URL, "k")
+	turn, err := c.RunWithTools(context.Background(), "", []Message{{Role: "user", Content: "q"}}, nil, false, nil,
+		func(ToolCall) string { return "ok" })
+	if err != nil {
+		t.Fatalf("RunWithTools: %v", err)
+	}
+	if turn.Usage == nil || *turn.Usage != (Usage{PromptTokens: 30, CompletionTokens: 7, TotalTokens: 37}) {
+		t.Errorf("Usage = %+v, want the sum of both requests (missing total derived)", turn.Usage)
+	}
+	if turn.Model != "m1" {
+		t.Errorf("Model = %q, want the reported m1", turn.Model)
+	}
+	if string(turn.Sources) != `[{"title":"a"}]` {
+		t.Errorf("Sources = %s", turn.Sources)
+	}
+}
+
+func TestRunWithToolsSendsTurnAttachments(t *testing.T) {
+	var bodies []ChatRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req ChatRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		bodies = append(bodies, req)
+		if len(bodies) == 1 {
+			w.Write([]byte(`{"choices":[{"message":{"tool_calls":[{"id":"c1","function":{"name":"read_file","arguments":"{}"}}]},"finish_reason":"tool_calls"}]}`))
+			return
+		}
+		w.Write([]byte(`{"choices":[{"message":{"content":"done"},"finish_reason":"stop"}]}`))
+	}))
+	defer srv.Close()
+
+	history := []Message{
+		{Role: "user", Content: "old", Attachments: []Attachment{{ID: "a0", Name: "old.txt"}}},
+		{Role: "assistant", Content: "ok"},
+		{Role: "user", Content: "q", Attachments: []Attachment{{ID: "a1", Name: "log.txt"}, {ID: "a2", Name: "cfg.yaml"}}},
+	}
+	c := NewClient(srv.URL, "k")
+	if _, err := c.RunWithTools(context.Background(), "", history, nil, false, nil, func(ToolCall) string { return "ok" }); err != nil {
+		t.Fatalf("RunWithTools: %v", err)
+	}
+	if len(bodies) != 2 {
+		t.Fatalf("requests = %d, want 2", len(bodies))
+	}
+	for i, req := range bodies {
+		if req.DocsGPT == nil || !slices.Equal(req.DocsGPT.Attachments, []string{"a1", "a2"}) {
+			t.Errorf("request %d docsgpt = %+v, want the last user message's ids", i, req.DocsGPT)
+		}
+		for _, m := range req.Messages {
+			if len(m.Attachments) > 0 {
+				t.Errorf("request %d leaks local attachment metadata in messages", i)
+			}
+		}
+	}
+}
+
+// toolLoopServer answers every request with one read_file call whose
+// arguments come from args(n) for the n-th request, until it has answered
+// calls requests; after that it answers with text.
+func toolLoopServer(t *testing.T, calls int, args func(n int) string) *httptest.Server {
+	var n atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		i := int(n.Add(1))
+		if i > calls {
+			w.Write([]byte(`{"choices":[{"message":{"content":"done"},"finish_reason":"stop"}]}`))
+			return
+		}
+		call, _ := json.Marshal(ToolCall{ID: fmt.Sprintf("c%d", i), Type: "function",
+			Function: FunctionCall{Name: "read_file_ct0", Arguments: args(i)}})
+		fmt.Fprintf(w, `{"choices":[{"message":{"tool_calls":[%s]},"finish_reason":"tool_calls"}]}`, call)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestRunWithToolsCapsToolRounds(t *testing.T) {
+	srv := toolLoopServer(t, 3, func(n int) string { return fmt.Sprintf(`{"path":"f%d"}`, n) })
+	c := NewClient(srv.URL, "k")
+	c.MaxToolRounds = 2
+	var events []ToolLoopEvent
+	c.OnToolLoop = func(ev ToolLoopEvent) bool { events = append(events, ev); return true }
+	ran := 0
+	turn, err := c.RunWithTools(context.Background(), "", []Message{{Role: "user", Content: "q"}}, nil, false, nil,
+		func(ToolCall) string { ran++; return "ok" })
+	if err != nil {
+		t.Fatalf("RunWithTools: %v", err)
+	}
+	if ran != 2 {
+		t.Errorf("ran %d tool calls, want 2", ran)
+	}
+	if len(events) != 1 || !events[0].Limit() || events[0].Rounds != 2 {
+		t.Errorf("events = %+v, want one limit event after 2 rounds", events)
+	}
+	limited := turn.History[len(turn.History)-2]
+	if limited.ToolCallID != "c3" || !strings.Contains(limited.Content, "limit of 2 tool rounds") {
+		t.Errorf("the call past the limit should get a limit result, got %+v", limited)
+	}
+	if last := turn.History[len(turn.History)-1]; last.Content != "done" {
+		t.Errorf("last message = %+v, want the wrap-up answer", last)
+	}
+}
+
+func TestRunWithToolsDetectsRepeatedCalls(t *testing.T) {
+	// Same call each time, with key order and spacing varying.
+	args := func(n int) string {
+		if n%2 == 0 {
+			return `{ "path": "a", "offset": 1 }`
+		}
+		return `{"offset":1,"path":"a"}`
+	}
+	tests := []struct {
+		name     string
+		keepOn   bool
+		calls    int
+		wantRan  int
+		wantErr  error
+		wantLast string
+	}{
+		{"user continues", true, 4, 4, nil, "ok"},
+		{"user stops", false, 3, 2, nil, "stopped the tool loop because read_file_ct0 was called 3 times"},
+		{"model ignores the stop", false, 4, 2, ErrToolLoopStopped, "Do not call any more tools"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewClient(toolLoopServer(t, tt.calls, args).URL, "k")
+			var asked []int
+			c.OnToolLoop = func(ev ToolLoopEvent) bool { asked = append(asked, ev.Repeats); return tt.keepOn }
+			ran := 0
+			turn, err := c.RunWithTools(context.Background(), "", []Message{{Role: "user", Content: "q"}}, nil, false, nil,
+				func(ToolCall) string { ran++; return "ok" })
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if ran != tt.wantRan {
+				t.Errorf("ran %d tool calls, want %d", ran, tt.wantRan)
+			}
+			if len(asked) != 1 || asked[0] != 3 {
+				t.Errorf("asked at repeats %v, want once at 3", asked)
+			}
+			var lastTool Message
+			for _, m := range turn.History {
+				if m.Role == "tool" {
+					lastTool = m
+				}
+			}
+			if !strings.Contains(lastTool.Content, tt.wantLast) {
+				t.Errorf("last tool result = %q, want it to contain %q", lastTool.Content, tt.wantLast)
+			}
+		})
+	}
+}
+
+func TestRunWithToolBatchesPassesRoundTogether(t *testing.T) {
+	var n atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if n.Add(1) == 1 {
+			w.Write([]byte(`{"choices":[{"message":{"tool_calls":[
+				{"id":"c1","function":{"name":"read_file","arguments":"{\"path\":\"a\"}"}},
+				{"id":"c2","function":{"name":"read_file","arguments":"{\"path\":\"b\"}"}},
+				{"id":"c3","function":{"name":"read_file","arguments":"{\"path\":\"c\"}"}}]},"finish_reason":"tool_calls"}]}`))
+			return
+		}
+		w.Write([]byte(`{"choices":[{"message":{"content":"done"},"finish_reason":"stop"}]}`))
+	}))
+	defer srv.Close()
+
+	var batches [][]string
+	c := NewClient(srv.URL, "k")
+	turn, err := c.RunWithToolBatches(context.Background(), "", []Message{{Role: "user", Content: "q"}}, nil, false, nil,
+		func(calls []ToolCall) []string {
+			var ids, results []string
+			for _, tc := range calls {
+				ids = append(ids, tc.ID)
+				results = append(results, "result "+tc.ID)
+			}
+			batches = append(batches, ids)
+			return results
+		})
+	if err != nil {
+		t.Fatalf("RunWithToolBatches: %v", err)
+	}
+	if len(batches) != 1 || strings.Join(batches[0], ",") != "c1,c2,c3" {
+		t.Errorf("batches = %v, want one batch of the whole round", batches)
+	}
+	for i, id := range []string{"c1", "c2", "c3"} {
+		if m := turn.History[2+i]; m.ToolCallID != id || m.Content != "result "+id {
+			t.Errorf("history[%d] = %+v, want the result of %s", 2+i, m, id)
+		}
+	}
+}
+
+func TestParseSources(t *testing.T) {
+	raw := json.RawMessage(`[
+		{"title":"Install","source":"docs/install.md","text":"brew install docsgpt-cli"},
+		{"title":"Install","source":"docs/install.md","text":"second chunk of the same file"},
+		{"title":"notes.pdf","source":"local","text":"uploaded"},
+		{"name":"API","url":"https://docs.example.com/api","snippet":"GET /api/models"},
+		"https://example.com/faq",
+		{"unrelated":true}
+	]`)
+	want := []Source{
+		{Title: "Install", Location: "docs/install.md", Text: "brew install docsgpt-cli"},
+		{Title: "notes.pdf", Text: "uploaded"},
+		{Title: "API", Location: "https://docs.example.com/api", Text: "GET /api/models"},
+		{Location: "https://example.com/faq"},
+	}
+	if got := ParseSources(raw); !slices.Equal(got, want) {
+		t.Errorf("ParseSources =\n%+v\nwant\n%+v", got, want)
+	}
+	if got := ParseSources(json.RawMessage(`{"not":"a list"}`)); got != nil {
+		t.Errorf("non-array sources = %+v, want nil", got)
+	}
+}
+
+func TestRunWithToolsCitesSourcesOnAnswer(t *testing.T) {
+	body := "data: {\"choices\":[{\"delta\":{\"content\":\"See the guide.\"}}],\"docsgpt\":{\"type\":\"source\",\"sources\":[{\"title\":\"Guide\",\"source\":\"guide.md\"}]}}\n\n" +
+		"data: {\"choices\":[{\"delta\":{},\"finish_reason\":\"stop\"}]}\n\n" +
+		"data: [DONE]\n\n"
+	c := NewClient(sseServer(t, body).URL, "k")
+	turn, err := c.RunWithTools(context.Background(), "", []Message{{Role: "user", Content: "q"}}, nil, true, nil, nil)
+	if err != nil {
+		t.Fatalf("RunWithTools: %v", err)
+	}
+	answer := turn.History[len(turn.History)-1]
+	if len(answer.Sources) != 1 || answer.Sources[0].Title != "Guide" {
+		t.Fatalf("answer sources = %+v", answer.Sources)
+	}
+	if out := outbound(turn.History); out[len(out)-1].Sources != nil {
+		t.Error("sources must not be sent back to the server")
+	}
+}
+
+func TestRequestTimeout(t *testing.T) {
+	// stall holds the response open until the client gives up; the bound
+	// keeps a broken client from hanging the test server's Close.
+	stall := func(r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(500 * time.Millisecond):
+		}
+	}
+	streaming := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte("data: {\"choices\":[{\"delta\":{\"content\":\"partial\"}}]}\n\n"))
+		w.(http.Flusher).Flush()
+		stall(r)
+	}))
+	defer streaming.Close()
+	silent := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { stall(r) }))
+	defer silent.Close()
+
+	c := NewClient(streaming.URL, "k")
+	c.RequestTimeout = 50 * time.Millisecond
+	resp, err := c.SendStream(context.Background(), ChatRequest{}, nil)
+	if !errors.Is(err, ErrRequestTimeout) || !errors.Is(err, ErrIncompleteStream) {
+		t.Fatalf("stalled stream: err = %v, want ErrRequestTimeout and ErrIncompleteStream", err)
+	}
+	if resp == nil || resp.Choices[0].Message.Content != "partial" {
+		t.Errorf("partial content not returned: %+v", resp)
+	}
+
+	c = NewClient(silent.URL, "k")
+	c.RequestTimeout = 50 * time.Millisecond
+	if _, err := c.Send(context.Background(), ChatRequest{}); !errors.Is(err, ErrRequestTimeout) || errors.Is(err, ErrIncompleteStream) {
+		t.Errorf("silent server: err = %v, want only ErrRequestTimeout", err)
+	}
+
+	// A cancellation by the caller is not a timeout.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := c.Send(ctx, ChatRequest{}); err == nil || errors.Is(err, ErrRequestTimeout) {
+		t.Errorf("caller cancellation: err = %v, want a non-timeout error", err)
+	}
+}
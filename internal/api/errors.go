@@ -0,0 +1,103 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/tidwall/gjson"
+)
+
+// maxErrorBody bounds how much of an error response is read and kept.
+const maxErrorBody = 64 * 1024
+
+// APIError is a non-200 response from the chat completions endpoint.
+type APIError struct {
+	StatusCode int
+	// Message is the server's error message when the body carries one in a
+	// known shape, else the (trimmed) raw body.
+	Message string
+	// RetryAfter is the server-requested wait from the Retry-After header,
+	// zero when absent.
+	RetryAfter time.Duration
+}
+
+func (e *APIError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("API error %d: %s", e.StatusCode, http.StatusText(e.StatusCode))
+	}
+	return fmt.Sprintf("API error %d: %s", e.StatusCode, e.Message)
+}
+
+// Retryable reports whether the request may be repeated as-is: the server
+// either throttled it or failed before producing an answer.
+func (e *APIError) Retryable() bool {
+	switch e.StatusCode {
+	case http.StatusTooManyRequests,
+		http.StatusInternalServerError,
+		http.StatusBadGateway,
+		http.StatusServiceUnavailable,
+		http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// newAPIError consumes (and closes) a non-200 response body.
+func newAPIError(resp *http.Response) *APIError {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+	resp.Body.Close()
+	return &APIError{
+		StatusCode: resp.StatusCode,
+		Message:    errorMessage(body),
+		RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()),
+	}
+}
+
+// errorMessage extracts the human-readable message from the error body
+// shapes seen in the wild (OpenAI, Flask, FastAPI), falling back to the raw
+// text.
+func errorMessage(body []byte) string {
+	if gjson.ValidBytes(body) {
+		for _, path := range []string{"error.message", "error", "message", "detail"} {
+			if r := gjson.GetBytes(body, path); r.Exists() && r.Type == gjson.String && r.String() != "" {
+				return r.String()
+			}
+		}
+	}
+	return strings.TrimSpace(string(body))
+}
+
+// parseRetryAfter accepts both Retry-After forms: delay-seconds and an
+// HTTP date. Anything unparseable or in the past yields zero.
+func parseRetryAfter(v string, now time.Time) time.Duration {
+	v = strings.TrimSpace(v)
+	if v == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(v); err == nil {
+		if secs < 0 {
+			return 0
+		}
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil && t.After(now) {
+		return t.Sub(now)
+	}
+	return 0
+}
+
+// isRetryableNetErr reports transport failures where the request cannot have
+// been processed: the connection was refused, or dropped before any
+// response header arrived.
+func isRetryableNetErr(err error) bool {
+	return errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF)
+}
@@ -0,0 +1,98 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/tidwall/gjson"
+)
+
+// Model is one entry of the server's model catalog.
+type Model struct {
+	ID   string
+	Name string
+	// ContextWindow is the context size in tokens, zero when not reported.
+	ContextWindow int
+	// Raw is the catalog entry as sent, for fields this struct does not
+	// model (pricing shapes vary across deployments).
+	Raw json.RawMessage
+}
+
+// ModelList is the parsed GET /api/models response.
+type ModelList struct {
+	DefaultID string
+	Models    []Model
+}
+
+// ListModels fetches the model catalog. It accepts both the DocsGPT shape
+// ({"default_model_id", "models": [...]}) and the OpenAI list shape
+// ({"data": [...]}).
+func (c *Client) ListModels(ctx context.Context) (*ModelList, error) {
+	url := c.BaseURL + "/api/models"
+	resp, err := c.do(ctx, func() (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return nil, err
+		}
+		c.setHeaders(req)
+		req.Header.Set("Accept", "application/json")
+		return req, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 4<<20))
+	if err != nil {
+		return nil, fmt.Errorf("read model list: %w", err)
+	}
+	if !gjson.ValidBytes(body) {
+		return nil, fmt.Errorf("GET %s returned non-JSON", url)
+	}
+	return parseModelList(body), nil
+}
+
+func parseModelList(body []byte) *ModelList {
+	doc := gjson.ParseBytes(body)
+	list := &ModelList{DefaultID: doc.Get("default_model_id").String()}
+	models := doc.Get("models")
+	if !models.IsArray() {
+		models = doc.Get("data")
+	}
+	models.ForEach(func(_, m gjson.Result) bool {
+		id := m.Get("id").String()
+		if id == "" {
+			return true
+		}
+		model := Model{ID: id, Raw: json.RawMessage(m.Raw)}
+		for _, path := range []string{"display_name", "name"} {
+			if v := m.Get(path).String(); v != "" && v != id {
+				model.Name = v
+				break
+			}
+		}
+		for _, path := range []string{"context_window", "context_length", "max_context_tokens", "max_input_tokens", "context_size"} {
+			if v := m.Get(path); v.Type == gjson.Number && v.Int() > 0 {
+				model.ContextWindow = int(v.Int())
+				break
+			}
+		}
+		list.Models = append(list.Models, model)
+		return true
+	})
+	return list
+}
+
+// Has reports whether id is in the catalog.
+func (l *ModelList) Has(id string) bool {
+	for _, m := range l.Models {
+		if m.ID == id {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,98 @@
+package api
+
+import (
+	"context"
+	"math/rand/v2"
+	"net/http"
+	"time"
+)
+
+// RetryPolicy controls how failed requests are retried. Only failures that
+// happen before the first response byte are retried (refused or reset
+// connections, 429 and 5xx), so a half-streamed answer is never replayed.
+type RetryPolicy struct {
+	// MaxRetries is the number of retries after the first attempt; zero
+	// disables retrying.
+	MaxRetries int
+	// BaseDelay is the backoff for the first retry; it doubles per attempt.
+	BaseDelay time.Duration
+	// MaxDelay caps both the computed backoff and a server's Retry-After.
+	MaxDelay time.Duration
+}
+
+// DefaultRetryPolicy is used by NewClient.
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxRetries: 3,
+		BaseDelay:  time.Second,
+		MaxDelay:   30 * time.Second,
+	}
+}
+
+// delay returns the wait before retry number attempt (1-based): exponential
+// backoff with jitter over its upper half, so synchronized clients spread
+// out without any retry collapsing to zero. A longer Retry-After from the
+// server wins, up to MaxDelay.
+func (p RetryPolicy) delay(attempt int, retryAfter time.Duration) time.Duration {
+	d := p.BaseDelay << (attempt - 1)
+	if d <= 0 || d > p.MaxDelay {
+		d = p.MaxDelay
+	}
+	if half := d / 2; half > 0 {
+		d = half + rand.N(half)
+	}
+	if retryAfter > d {
+		d = min(retryAfter, p.MaxDelay)
+	}
+	return d
+}
+
+// RetryEvent describes a retry about to happen, for progress reporting.
+type RetryEvent struct {
+	Attempt    int // 1-based retry number
+	MaxRetries int
+	Delay      time.Duration
+	Err        error // *APIError or the transport error that triggered it
+}
+
+// do sends the request built by newReq, retrying per c.Retry. A 200
+// response is returned open for the caller to consume; any other status
+// comes back as *APIError.
+func (c *Client) do(ctx context.Context, newReq func() (*http.Request, error)) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		req, err := newReq()
+		if err != nil {
+			return nil, err
+		}
+
+		var retryAfter time.Duration
+		resp, err := c.HTTPClient.Do(req)
+		switch {
+		case err != nil:
+			if ctx.Err() != nil || !isRetryableNetErr(err) {
+				return nil, err
+			}
+		case resp.StatusCode == http.StatusOK:
+			return resp, nil
+		default:
+			apiErr := newAPIError(resp)
+			if !apiErr.Retryable() {
+				return nil, apiErr
+			}
+			err, retryAfter = apiErr, apiErr.RetryAfter
+		}
+
+		if attempt >= c.Retry.MaxRetries {
+			return nil, err
+		}
+		wait := c.Retry.delay(attempt+1, retryAfter)
+		if c.OnRetry != nil {
+			c.OnRetry(RetryEvent{Attempt: attempt + 1, MaxRetries: c.Retry.MaxRetries, Delay: wait, Err: err})
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package api
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/tidwall/gjson"
+)
+
+// Source is one retrieved document behind an answer.
+type Source struct {
+	Title string `json:"title,omitempty"`
+	// Location is the document's path or URL.
+	Location string `json:"location,omitempty"`
+	Text     string `json:"text,omitempty"`
+}
+
+// ParseSources reads docsgpt.sources leniently: deployments differ in field
+// names, so each field takes the first of several known keys, and entries
+// with nothing to show are skipped. Duplicates (same title and location,
+// e.g. several chunks of one file) are folded into the first occurrence.
+func ParseSources(raw json.RawMessage) []Source {
+	if len(raw) == 0 {
+		return nil
+	}
+	doc := gjson.ParseBytes(raw)
+	if !doc.IsArray() {
+		return nil
+	}
+	first := func(r gjson.Result, keys ...string) string {
+		for _, k := range keys {
+			if v := strings.TrimSpace(r.Get(k).String()); v != "" {
+				return v
+			}
+		}
+		return ""
+	}
+
+	var out []Source
+	seen := map[[2]string]bool{}
+	doc.ForEach(func(_, r gjson.Result) bool {
+		var s Source
+		if r.Type == gjson.String {
+			s.Location = strings.TrimSpace(r.String())
+		} else {
+			s.Title = first(r, "title", "name", "filename", "metadata.title")
+			s.Location = first(r, "source", "url", "link", "path", "metadata.source", "metadata.file_path")
+			s.Text = first(r, "text", "snippet", "content", "page_content")
+		}
+		// DocsGPT reports uploaded documents with the placeholder "local".
+		if s.Location == "local" {
+			s.Location = ""
+		}
+		if s.Title == s.Location {
+			s.Title = ""
+		}
+		if s.Title == "" && s.Location == "" && s.Text == "" {
+			return true
+		}
+		key := [2]string{s.Title, s.Location}
+		if s.Location != "" || s.Title != "" {
+			if seen[key] {
+				return true
+			}
+			seen[key] = true
+		}
+		out = append(out, s)
+		return true
+	})
+	return out
+}
@@ -0,0 +1,112 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// DefaultMaxToolRounds caps the tool-calling rounds of one turn when
+// Client.MaxToolRounds is zero. A round is one response that asks for
+// tools, however many calls it carries.
+const DefaultMaxToolRounds = 25
+
+// repeatThreshold is how often an identical call (same normalized name and
+// arguments) may be made in one turn before OnToolLoop is consulted.
+// Repeating a call once is common and legitimate, e.g. re-reading a file
+// after writing it.
+const repeatThreshold = 3
+
+// ErrToolLoopStopped reports a model that kept asking for tools after it was
+// told the tool loop was over. The returned history still pairs every call
+// with a result.
+var ErrToolLoopStopped = errors.New("the model kept calling tools after the tool loop was stopped")
+
+// ToolLoopEvent describes why RunWithTools is about to stop running tools.
+type ToolLoopEvent struct {
+	// Call is the call that triggered the event.
+	Call ToolCall
+	// Repeats is how many times Call has been made this turn, counting
+	// this one; 0 when the event is the round limit.
+	Repeats int
+	// Rounds is the number of tool rounds run so far this turn.
+	Rounds    int
+	MaxRounds int
+}
+
+// Limit reports whether the event is the round limit rather than a
+// repeated call.
+func (ev ToolLoopEvent) Limit() bool { return ev.Repeats == 0 }
+
+// toolLoop tracks one turn's tool rounds and identical calls. Once stopped,
+// every further call is answered with the stop reason instead of running,
+// so the model can wrap up on its own.
+type toolLoop struct {
+	maxRounds int
+	onEvent   func(ToolLoopEvent) bool
+	rounds    int
+	seen      map[string]int
+	asked     map[string]bool
+	stopped   string
+}
+
+func (c *Client) newToolLoop() *toolLoop {
+	n := c.MaxToolRounds
+	if n == 0 {
+		n = DefaultMaxToolRounds
+	}
+	return &toolLoop{maxRounds: n, onEvent: c.OnToolLoop, seen: map[string]int{}, asked: map[string]bool{}}
+}
+
+// startRound counts a response that asks for tools and stops the loop when
+// it goes past the limit. It reports false when the loop was already
+// stopped before this round: the model ignored the request to wrap up.
+func (l *toolLoop) startRound(calls []ToolCall) bool {
+	if l.stopped != "" {
+		return false
+	}
+	l.rounds++
+	if l.maxRounds > 0 && l.rounds > l.maxRounds {
+		l.stopped = fmt.Sprintf("the limit of %d tool rounds for this turn was reached", l.maxRounds)
+		if l.onEvent != nil {
+			l.onEvent(ToolLoopEvent{Call: calls[0], Rounds: l.rounds - 1, MaxRounds: l.maxRounds})
+		}
+	}
+	return true
+}
+
+// check records tc and returns "" when it may run, or the tool result to
+// report instead.
+func (l *toolLoop) check(tc ToolCall) string {
+	if l.stopped == "" {
+		key := callKey(tc)
+		l.seen[key]++
+		if n := l.seen[key]; n >= repeatThreshold && !l.asked[key] {
+			l.asked[key] = true
+			ev := ToolLoopEvent{Call: tc, Repeats: n, Rounds: l.rounds, MaxRounds: l.maxRounds}
+			if l.onEvent != nil && !l.onEvent(ev) {
+				l.stopped = fmt.Sprintf("the user stopped the tool loop because %s was called %d times with the same arguments", tc.Function.Name, n)
+			}
+		}
+	}
+	if l.stopped != "" {
+		return "Tool call not run: " + l.stopped + ". Do not call any more tools; answer now with the information you already have and tell the user what is left undone."
+	}
+	return ""
+}
+
+// callKey identifies a call by its name and canonical arguments, so key
+// order and whitespace don't hide a repeat. Server-appended suffixes such
+// as "_ct0" are dropped from the name.
+func callKey(tc ToolCall) string {
+	name := NormalizeToolName(strings.TrimSpace(tc.Function.Name))
+	args := strings.TrimSpace(tc.Function.Arguments)
+	var v any
+	if err := json.Unmarshal([]byte(args), &v); err == nil {
+		if b, err := json.Marshal(v); err == nil {
+			args = string(b)
+		}
+	}
+	return name + "\x00" + args
+}
@@ -1,12 +1,33 @@
 package api
 
-import "encoding/json"
+import (
+	"encoding/json"
+	"regexp"
+)
 
 type Message struct {
 	Role       string     `json:"role"`
 	Content    string     `json:"content,omitempty"`
 	ToolCalls  []ToolCall `json:"tool_calls,omitempty"`
 	ToolCallID string     `json:"tool_call_id,omitempty"`
+	// ReasoningContent is kept locally (sessions, exports) and stripped
+	// before history is sent back to the server.
+	ReasoningContent string `json:"reasoning_content,omitempty"`
+	// Incomplete marks an assistant answer whose stream was cut short. It
+	// is local-only, like ReasoningContent.
+	Incomplete bool `json:"incomplete,omitempty"`
+	// Attachments are files uploaded for a user message. They are sent as
+	// docsgpt.attachments ids, not inside the message.
+	Attachments []Attachment `json:"attachments,omitempty"`
+	// Sources are the documents retrieved for an assistant answer. They
+	// are local-only, kept for citations, /sources and transcripts.
+	Sources []Source `json:"sources,omitempty"`
+}
+
+// Attachment is a file uploaded to the server's attachment store.
+type Attachment struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
 }
 
 type ChatRequest struct {
@@ -16,6 +37,12 @@ type ChatRequest struct {
 	Tools          []Tool          `json:"tools,omitempty"`
 	ConversationID string          `json:"conversation_id,omitempty"`
 	DocsGPT        *DocsGPTRequest `json:"docsgpt,omitempty"`
+	StreamOptions  *StreamOptions  `json:"stream_options,omitempty"`
+}
+
+// StreamOptions asks a streaming server to append a final usage chunk.
+type StreamOptions struct {
+	IncludeUsage bool `json:"include_usage"`
 }
 
 // DocsGPTRequest is the request-side "docsgpt" extension object. It currently
@@ -41,6 +68,8 @@ type DocsGPTMeta struct {
 }
 
 type ChatResponse struct {
+	// Model is the model the server actually used ("" when not reported).
+	Model   string      `json:"model,omitempty"`
 	Choices []Choice    `json:"choices"`
 	DocsGPT DocsGPTMeta `json:"docsgpt,omitempty"`
 	Usage   *Usage      `json:"usage,omitempty"`
@@ -81,3 +110,13 @@ type FunctionCall struct {
 	Name      string `json:"name,omitempty"`
 	Arguments string `json:"arguments,omitempty"`
 }
+
+// toolSuffixRe matches the suffix the DocsGPT server appends to tool names
+// (e.g., "write_file" → "write_file_ct0").
+var toolSuffixRe = regexp.MustCompile(`_ct\d+$`)
+
+// NormalizeToolName removes server-appended suffixes like "_ct0" from a
+// tool name.
+func NormalizeToolName(name string) string {
+	return toolSuffixRe.ReplaceAllString(name, "")
+}
@@ -0,0 +1,313 @@
+// Package approvals stores "always allow" answers given at a tool approval
+// prompt, so matching calls later run without asking. Rules live in
+// ~/.docsgpt/approvals.json and apply to one session, one project
+// directory, or everywhere.
+package approvals
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"docsgpt-cli/internal/config"
+	"docsgpt-cli/internal/policy"
+)
+
+// Kind says what a rule matches.
+type Kind string
+
+const (
+	// Exact matches one run_command command, character for character.
+	Exact Kind = "exact"
+	// Prefix matches run_command commands that start with the rule's
+	// words, such as "go test" for "go test ./...".
+	Prefix Kind = "prefix"
+	// Tool matches every call to a tool.
+	Tool Kind = "tool"
+)
+
+// Scope says where a rule applies.
+type Scope string
+
+const (
+	Session Scope = "session"
+	Project Scope = "project"
+	Global  Scope = "global"
+)
+
+// Rule is one stored approval.
+type Rule struct {
+	ID   int    `json:"id"`
+	Kind Kind   `json:"kind"`
+	Tool string `json:"tool"`
+	// Pattern is the command (Exact) or command prefix (Prefix).
+	Pattern string `json:"pattern,omitempty"`
+	Scope   Scope  `json:"scope"`
+	// Session is the session id of a Session rule.
+	Session string `json:"session,omitempty"`
+	// Project is the directory of a Project rule; it covers the
+	// directories below too.
+	Project string    `json:"project,omitempty"`
+	Created time.Time `json:"created"`
+}
+
+// Where is the context a call is made in: the chat session and the
+// project directory.
+type Where struct {
+	Session string
+	Project string
+	// Transient marks a session that is not saved, such as an ask run. No
+	// later run can match its session rules, so they are kept in memory
+	// (AddTransient) rather than stored.
+	Transient bool
+}
+
+// Describe renders what the rule allows, e.g. `run_command "go test …"`.
+func (r Rule) Describe() string {
+	switch r.Kind {
+	case Exact:
+		return fmt.Sprintf("%s %q", r.Tool, r.Pattern)
+	case Prefix:
+		return fmt.Sprintf("%s %q", r.Tool, r.Pattern+" …")
+	default:
+		return "every " + r.Tool + " call"
+	}
+}
+
+// Place renders where the rule applies.
+func (r Rule) Place() string {
+	switch r.Scope {
+	case Session:
+		return "session " + r.Session
+	case Project:
+		return "project " + r.Project
+	default:
+		return "everywhere"
+	}
+}
+
+// storePath returns ~/.docsgpt/approvals.json. It is a var so tests can
+// redirect it.
+var storePath = func() string {
+	return filepath.Join(config.Dir(), "approvals.json")
+}
+
+// mu serializes read-modify-write cycles of the store within the process,
+// and guards transient.
+var mu sync.Mutex
+
+// transient holds the rules added with AddTransient.
+var transient []Rule
+
+// List returns every stored rule, oldest first.
+func List() ([]Rule, error) {
+	mu.Lock()
+	defer mu.Unlock()
+	return load()
+}
+
+// Add stores r with the next free id and returns it.
+func Add(r Rule) (Rule, error) {
+	if err := r.validate(); err != nil {
+		return r, err
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	rules, err := load()
+	if err != nil {
+		return r, err
+	}
+	for _, old := range rules {
+		if old.same(r) {
+			return old, nil
+		}
+		r.ID = max(r.ID, old.ID)
+	}
+	r.ID++
+	if r.Created.IsZero() {
+		r.Created = time.Now()
+	}
+	return r, save(append(rules, r))
+}
+
+// AddTransient keeps r for the rest of the process without storing it.
+// Transient rules have no id.
+func AddTransient(r Rule) (Rule, error) {
+	if err := r.validate(); err != nil {
+		return r, err
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	for _, old := range transient {
+		if old.same(r) {
+			return old, nil
+		}
+	}
+	if r.Created.IsZero() {
+		r.Created = time.Now()
+	}
+	transient = append(transient, r)
+	return r, nil
+}
+
+// Revoke removes the rules with the given ids and returns them. An unknown
+// id is an error and nothing is removed.
+func Revoke(ids ...int) ([]Rule, error) {
+	mu.Lock()
+	defer mu.Unlock()
+	rules, err := load()
+	if err != nil {
+		return nil, err
+	}
+	drop := map[int]bool{}
+	for _, id := range ids {
+		drop[id] = true
+	}
+	var kept, removed []Rule
+	for _, r := range rules {
+		if drop[r.ID] {
+			removed = append(removed, r)
+			delete(drop, r.ID)
+		} else {
+			kept = append(kept, r)
+		}
+	}
+	if len(drop) > 0 {
+		var missing []int
+		for id := range drop {
+			missing = append(missing, id)
+		}
+		sort.Ints(missing)
+		return nil, fmt.Errorf("no approval rule with id %v", strings.Trim(fmt.Sprint(missing), "[]"))
+	}
+	return removed, save(kept)
+}
+
+// Match returns the first rule that allows a call of tool in w, or nil.
+// command is the run_command command line ("" for other tools). A
+// prefix rule only matches a single simple command — no pipes, lists,
+// substitutions or redirections — so "go test" never covers
+// "go test ./... && rm -rf x".
+func Match(tool, command string, w Where) (*Rule, error) {
+	rules, err := List()
+	if err != nil {
+		return nil, err
+	}
+	mu.Lock()
+	rules = append(rules, transient...)
+	mu.Unlock()
+	for i := range rules {
+		if r := &rules[i]; r.applies(w) && r.matches(tool, command) {
+			return r, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *Rule) applies(w Where) bool {
+	switch r.Scope {
+	case Session:
+		return w.Session != "" && r.Session == w.Session
+	case Project:
+		return w.Project != "" && (w.Project == r.Project || strings.HasPrefix(w.Project, r.Project+string(filepath.Separator)))
+	default:
+		return true
+	}
+}
+
+func (r *Rule) matches(tool, command string) bool {
+	if r.Tool != tool {
+		return false
+	}
+	command = strings.TrimSpace(command)
+	switch r.Kind {
+	case Exact:
+		return command == r.Pattern
+	case Prefix:
+		rest, ok := strings.CutPrefix(command, r.Pattern)
+		if !ok || (rest != "" && rest[0] != ' ' && rest[0] != '\t') {
+			return false
+		}
+		cmds, err := policy.Parse(command)
+		if err != nil || len(cmds) != 1 || len(cmds[0].Writes) > 0 || cmds[0].Sudo {
+			return false
+		}
+		return cmds[0].Name == strings.Fields(r.Pattern)[0]
+	default:
+		return true
+	}
+}
+
+func (r Rule) same(o Rule) bool {
+	return r.Kind == o.Kind && r.Tool == o.Tool && r.Pattern == o.Pattern &&
+		r.Scope == o.Scope && r.Session == o.Session && r.Project == o.Project
+}
+
+func (r Rule) validate() error {
+	if r.Tool == "" {
+		return errors.New("approval rule needs a tool")
+	}
+	switch r.Kind {
+	case Exact, Prefix:
+		if strings.TrimSpace(r.Pattern) == "" {
+			return fmt.Errorf("%s approval rule needs a command", r.Kind)
+		}
+	case Tool:
+	default:
+		return fmt.Errorf("unknown approval kind %q", r.Kind)
+	}
+	switch r.Scope {
+	case Session:
+		if r.Session == "" {
+			return errors.New("session approval rule needs a session id")
+		}
+	case Project:
+		if !filepath.IsAbs(r.Project) {
+			return errors.New("project approval rule needs an absolute directory")
+		}
+	case Global:
+	default:
+		return fmt.Errorf("unknown approval scope %q", r.Scope)
+	}
+	return nil
+}
+
+func load() ([]Rule, error) {
+	data, err := os.ReadFile(storePath())
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var rules []Rule
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", storePath(), err)
+	}
+	return rules, nil
+}
+
+func save(rules []Rule) error {
+	path := storePath()
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	if rules == nil {
+		rules = []Rule{}
+	}
+	data, err := json.MarshalIndent(rules, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path+".tmp", data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(path+".tmp", path)
+}
@@ -0,0 +1,110 @@
+package approvals
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func useTempStore(t *testing.T) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "approvals.json")
+	orig := storePath
+	storePath = func() string { return path }
+	t.Cleanup(func() { storePath = orig })
+}
+
+func TestMatch(t *testing.T) {
+	useTempStore(t)
+	for _, r := range []Rule{
+		{Kind: Exact, Tool: "run_command", Pattern: "make lint", Scope: Global},
+		{Kind: Prefix, Tool: "run_command", Pattern: "go test", Scope: Project, Project: "/work/app"},
+		{Kind: Tool, Tool: "write_file", Scope: Session, Session: "s1"},
+	} {
+		if _, err := Add(r); err != nil {
+			t.Fatal(err)
+		}
+	}
+	inApp := Where{Session: "s1", Project: "/work/app/cmd"}
+	tests := []struct {
+		tool, command string
+		w             Where
+		want          int
+	}{
+		{"run_command", "make lint", Where{}, 1},
+		{"run_command", "make lint --fix", Where{}, 0},
+		{"run_command", "go test ./...", inApp, 2},
+		{"run_command", "go test", inApp, 2},
+		{"run_command", "go testify", inApp, 0},
+		{"run_command", "go test ./... && rm -rf x", inApp, 0},
+		{"run_command", "go test $(rm -rf x)", inApp, 0},
+		{"run_command", "go test > /etc/passwd", inApp, 0},
+		{"run_command", "go test ./...", Where{Project: "/work/application"}, 0},
+		{"write_file", "", inApp, 3},
+		{"write_file", "", Where{Session: "s2"}, 0},
+		{"edit_file", "", inApp, 0},
+	}
+	for _, tt := range tests {
+		r, err := Match(tt.tool, tt.command, tt.w)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := 0
+		if r != nil {
+			got = r.ID
+		}
+		if got != tt.want {
+			t.Errorf("Match(%s, %q, %+v) = rule %d, want %d", tt.tool, tt.command, tt.w, got, tt.want)
+		}
+	}
+}
+
+func TestAddTransient(t *testing.T) {
+	useTempStore(t)
+	t.Cleanup(func() { transient = nil })
+	run := Where{Session: "ask-1", Project: "/work/app", Transient: true}
+	r, err := AddTransient(Rule{Kind: Exact, Tool: "run_command", Pattern: "go vet", Scope: Session, Session: run.Session})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := Match("run_command", "go vet", run); got == nil || got.Pattern != r.Pattern {
+		t.Errorf("Match in the same run = %+v, want the transient rule", got)
+	}
+	if got, _ := Match("run_command", "go vet", Where{Session: "ask-2"}); got != nil {
+		t.Errorf("Match in another run = %+v, want nil", got)
+	}
+	if rules, _ := List(); len(rules) != 0 {
+		t.Errorf("a transient rule was stored: %+v", rules)
+	}
+}
+
+func TestAddAndRevoke(t *testing.T) {
+	useTempStore(t)
+	a, _ := Add(Rule{Kind: Tool, Tool: "write_file", Scope: Global})
+	b, _ := Add(Rule{Kind: Exact, Tool: "run_command", Pattern: "ls", Scope: Global})
+	if again, _ := Add(Rule{Kind: Tool, Tool: "write_file", Scope: Global}); again.ID != a.ID {
+		t.Errorf("duplicate rule got id %d, want %d", again.ID, a.ID)
+	}
+	if _, err := Add(Rule{Kind: Prefix, Tool: "run_command", Scope: Global}); err == nil {
+		t.Error("prefix rule without a pattern should be rejected")
+	}
+	if _, err := Add(Rule{Kind: Tool, Tool: "x", Scope: Project, Project: "rel"}); err == nil {
+		t.Error("project rule with a relative directory should be rejected")
+	}
+
+	if _, err := Revoke(a.ID, 99); err == nil || !strings.Contains(err.Error(), "99") {
+		t.Errorf("revoke with unknown id: err = %v", err)
+	}
+	if rules, _ := List(); len(rules) != 2 {
+		t.Fatalf("a failed revoke removed rules: %+v", rules)
+	}
+	removed, err := Revoke(a.ID)
+	if err != nil || len(removed) != 1 || removed[0].ID != a.ID {
+		t.Fatalf("Revoke = %+v, %v", removed, err)
+	}
+	// Ids are never reused while later rules exist.
+	c, _ := Add(Rule{Kind: Tool, Tool: "edit_file", Scope: Global})
+	if c.ID != b.ID+1 {
+		t.Errorf("new rule id = %d, want %d", c.ID, b.ID+1)
+	}
+}
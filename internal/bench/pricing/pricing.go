@@ -137,6 +137,12 @@ func (t *Table) Fetch(ctx context.Context, baseURL string) error {
 	return nil
 }
 
+// ForModel reads the price from one raw /api/models catalog entry, accepting
+// the same shapes as Fetch.
+func ForModel(raw []byte) (spec.ModelPricing, bool) {
+	return readPricing(gjson.ParseBytes(raw))
+}
+
 // readPricing accepts, in order of preference:
 //   - input_cost_per_token / output_cost_per_token (USD per token)
 //   - input_cost_per_million / output_cost_per_million (USD per 1M tokens)
@@ -17,14 +17,29 @@ type Config struct {
 }
 
 type Settings struct {
-	SendCurrentDirectory  bool   `json:"send_current_directory"`
-	SendDirectoryContents bool   `json:"send_directory_contents"`
-	SendLastCommands      bool   `json:"send_last_commands"`
-	NumberOfLastCommands  int    `json:"number_of_last_commands"`
-	Theme                 string `json:"theme,omitempty"`                // "auto", "dark", "light"
-	Banner                string `json:"banner,omitempty"`               // "always", "once", "never"
-	AutoUpdate            string `json:"auto_update,omitempty"`          // "on", "notify", "off"
-	DisableUpdateCheck    bool   `json:"disable_update_check,omitempty"` // legacy, superseded by auto_update
+	SendCurrentDirectory  bool     `json:"send_current_directory"`
+	SendDirectoryContents bool     `json:"send_directory_contents"`
+	SendLastCommands      bool     `json:"send_last_commands"`
+	NumberOfLastCommands  int      `json:"number_of_last_commands"`
+	SendProjectContext    bool     `json:"send_project_context"`           // project instructions, type and git state
+	ContextBudget         int      `json:"context_budget,omitempty"`       // tokens of project context; 0 uses the default (2000)
+	SendGitDiff           bool     `json:"send_git_diff,omitempty"`        // unstaged changes, as --with-diff
+	SendGitStaged         bool     `json:"send_git_staged,omitempty"`      // staged changes, as --with-staged
+	GitLogCommits         int      `json:"git_log_commits,omitempty"`      // recent commits with messages, as --with-log N
+	MaxRetries            int      `json:"max_retries"`                    // API retries on 429/5xx/connection resets; 0 disables
+	MaxToolRounds         int      `json:"max_tool_rounds,omitempty"`      // tool-calling rounds per answer; 0 uses the default, negative removes the cap
+	OutputBudget          int      `json:"output_budget,omitempty"`        // bytes per tool result; 0 uses the default (10KB)
+	Model                 string   `json:"model,omitempty"`                // model id for ask/chat; empty uses the server default
+	Theme                 string   `json:"theme,omitempty"`                // "auto", "dark", "light"
+	Banner                string   `json:"banner,omitempty"`               // "always", "once", "never"
+	AutoUpdate            string   `json:"auto_update,omitempty"`          // "on", "notify", "off"
+	Sandbox               string   `json:"sandbox,omitempty"`              // "on", "network", "off": confine tool and host commands (Linux)
+	SandboxPaths          []string `json:"sandbox_paths,omitempty"`        // writable in the sandbox besides the project directory
+	HostSandboxRoot       string   `json:"host_sandbox_root,omitempty"`    // host mode's writable root (default: each command's working directory)
+	Redact                string   `json:"redact,omitempty"`               // "on" (default), "formats" (no entropy check), "off": mask secrets sent to the server
+	RedactPatterns        []string `json:"redact_patterns,omitempty"`      // regexes of more secrets to mask
+	HTTPAllowPrivate      bool     `json:"http_allow_private,omitempty"`   // let http_get reach localhost and private networks
+	DisableUpdateCheck    bool     `json:"disable_update_check,omitempty"` // legacy, superseded by auto_update
 }
 
 // AutoUpdateMode resolves the effective auto-update mode: "on" (stage and
@@ -51,6 +66,8 @@ func DefaultConfig() Config {
 			SendDirectoryContents: true,
 			SendLastCommands:      true,
 			NumberOfLastCommands:  3,
+			SendProjectContext:    true,
+			MaxRetries:            3,
 		},
 	}
 }
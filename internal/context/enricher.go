@@ -7,8 +7,14 @@ import (
 	"strings"
 
 	"docsgpt-cli/internal/config"
+	"docsgpt-cli/internal/display"
+	"docsgpt-cli/internal/redact"
 )
 
+// Redactor, when set, masks the secrets in the shell history, project
+// context and git output BuildContext sends.
+var Redactor *redact.Redactor
+
 // BuildContext creates a context string based on the user's settings.
 func BuildContext(settings config.Settings) string {
 	var context string
@@ -32,14 +38,41 @@ func BuildContext(settings config.Settings) string {
 
 	if settings.SendLastCommands {
 		lastCommands := GetLastCommands(settings.NumberOfLastCommands)
+		lastCommands = Redactor.Redact("shell history", lastCommands)
 		context += fmt.Sprintf("LAST_COMMANDS:\n%s\n", lastCommands)
 	}
 
+	if settings.SendProjectContext {
+		currentPath, _ := os.Getwd()
+		project, err := LoadProject(currentPath, settings.ContextBudget)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, display.Warn("warning:"), err)
+		}
+		context += Redactor.Redact("project context", project.Render())
+	}
+
+	git := GitOptions{
+		Diff:   settings.SendGitDiff,
+		Staged: settings.SendGitStaged,
+		Log:    settings.GitLogCommits,
+		Branch: !settings.SendProjectContext,
+	}
+	if git.Any() {
+		currentPath, _ := os.Getwd()
+		gitContext, err := GitContext(currentPath, git)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, display.Warn("warning:"), "git context:", err)
+		}
+		context += Redactor.Redact("git context", gitContext)
+	}
+
 	return context
 }
 
-// BuildQuestion formats a question with optional context.
+// BuildQuestion formats a question with optional context. Secrets in the
+// question, which includes piped stdin, are masked like the context's.
 func BuildQuestion(question string, settings config.Settings, includeContext bool) string {
+	question = Redactor.Redact("question", question)
 	if !includeContext {
 		return question
 	}
@@ -0,0 +1,173 @@
+package context
+
+import (
+	stdcontext "context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// MaxDiffBytes caps each diff attached to the context. The --stat summary
+// before it is always complete.
+const MaxDiffBytes = 16 * 1024
+
+// MaxLogCommits caps --with-log.
+const MaxLogCommits = 50
+
+// gitDiffTimeout bounds the git commands of GitContext together; diffs of
+// large trees take longer than the status and log of LoadProject.
+const gitDiffTimeout = 10 * time.Second
+
+// GitOptions selects the git output GitContext attaches.
+type GitOptions struct {
+	Diff   bool // unstaged changes: git diff
+	Staged bool // staged changes: git diff --cached
+	Log    int  // the last Log commits with their messages: git log
+	// Branch adds the current branch to the output of the others. The
+	// project context has it already.
+	Branch bool
+}
+
+// Any reports whether o attaches anything.
+func (o GitOptions) Any() bool {
+	return o.Diff || o.Staged || o.Log > 0
+}
+
+// ErrNotGitRepository is returned for git context outside a git work tree.
+var ErrNotGitRepository = errors.New("not a git repository")
+
+// runGit runs git in dir and returns its output without the trailing
+// newline. A failure carries git's own message.
+func runGit(ctx stdcontext.Context, dir string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", append([]string{"-C", dir}, args...)...)
+	out, err := cmd.Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && len(exitErr.Stderr) > 0 {
+			return "", fmt.Errorf("git %s: %s", args[0], strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return "", fmt.Errorf("git %s: %w", args[0], err)
+	}
+	return strings.TrimRight(string(out), "\n"), nil
+}
+
+// inWorkTree reports whether dir is inside a git work tree.
+func inWorkTree(ctx stdcontext.Context, dir string) bool {
+	_, err := runGit(ctx, dir, "rev-parse", "--is-inside-work-tree")
+	return err == nil
+}
+
+// GitContext returns the git output opts asks for, for the repository
+// containing dir, each diff capped at MaxDiffBytes.
+func GitContext(dir string, opts GitOptions) (string, error) {
+	if !opts.Any() {
+		return "", nil
+	}
+	ctx, cancel := stdcontext.WithTimeout(stdcontext.Background(), gitDiffTimeout)
+	defer cancel()
+	if !inWorkTree(ctx, dir) {
+		return "", ErrNotGitRepository
+	}
+	var b strings.Builder
+	if branch, _ := runGit(ctx, dir, "branch", "--show-current"); opts.Branch && branch != "" {
+		fmt.Fprintf(&b, "GIT_BRANCH: %s\n", branch)
+	}
+	if opts.Staged {
+		diff, err := diff(ctx, dir, true, MaxDiffBytes)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(&b, "GIT_STAGED_DIFF:\n%s\n", orNone(diff))
+	}
+	if opts.Diff {
+		diff, err := diff(ctx, dir, false, MaxDiffBytes)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(&b, "GIT_UNSTAGED_DIFF:\n%s\n", orNone(diff))
+	}
+	if opts.Log > 0 {
+		log, err := recentLog(ctx, dir, opts.Log)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(&b, "GIT_LOG:\n%s\n", orNone(log))
+	}
+	return b.String(), nil
+}
+
+func orNone(s string) string {
+	if s == "" {
+		return "(none)"
+	}
+	return s
+}
+
+// StagedDiff returns the summary and diff of the staged changes in the
+// repository containing dir, capped at maxBytes, or "" when nothing is
+// staged.
+func StagedDiff(dir string, maxBytes int) (string, error) {
+	ctx, cancel := stdcontext.WithTimeout(stdcontext.Background(), gitDiffTimeout)
+	defer cancel()
+	if !inWorkTree(ctx, dir) {
+		return "", ErrNotGitRepository
+	}
+	return diff(ctx, dir, true, maxBytes)
+}
+
+// RecentLog returns the last n commits of the repository containing dir,
+// subjects and bodies.
+func RecentLog(dir string, n int) (string, error) {
+	ctx, cancel := stdcontext.WithTimeout(stdcontext.Background(), gitDiffTimeout)
+	defer cancel()
+	if !inWorkTree(ctx, dir) {
+		return "", ErrNotGitRepository
+	}
+	return recentLog(ctx, dir, n)
+}
+
+// diff returns git diff --stat followed by the diff itself, staged or not,
+// with the diff capped at maxBytes; "" when there are no changes.
+func diff(ctx stdcontext.Context, dir string, staged bool, maxBytes int) (string, error) {
+	args := []string{"diff", "--no-color", "--no-ext-diff"}
+	if staged {
+		args = append(args, "--cached")
+	}
+	stat, err := runGit(ctx, dir, append(args, "--stat")...)
+	if err != nil || stat == "" {
+		return "", err
+	}
+	patch, err := runGit(ctx, dir, args...)
+	if err != nil {
+		return "", err
+	}
+	return stat + "\n\n" + capText(patch, maxBytes), nil
+}
+
+// recentLog returns the last n commits, newest first. The log of a
+// repository without commits is empty.
+func recentLog(ctx stdcontext.Context, dir string, n int) (string, error) {
+	if n > MaxLogCommits {
+		n = MaxLogCommits
+	}
+	if _, err := runGit(ctx, dir, "rev-parse", "--verify", "-q", "HEAD"); err != nil {
+		return "", nil
+	}
+	log, err := runGit(ctx, dir, "log", "--no-color", "--no-decorate", fmt.Sprintf("-%d", n), "--format=%h %an, %ar: %s%n%b")
+	return capText(log, MaxDiffBytes), err
+}
+
+// capText cuts text to at most maxBytes at a line boundary, noting how
+// much was left out.
+func capText(text string, maxBytes int) string {
+	if len(text) <= maxBytes {
+		return text
+	}
+	cut := text[:maxBytes]
+	if i := strings.LastIndexByte(cut, '\n'); i > 0 {
+		cut = cut[:i]
+	}
+	return fmt.Sprintf("%s\n... [cut: %d of %d bytes shown]", cut, len(cut), len(text))
+}
@@ -0,0 +1,85 @@
+package context
+
+import (
+	"errors"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCapText(t *testing.T) {
+	text := "one\ntwo\nthree\n"
+	if got := capText(text, 100); got != text {
+		t.Errorf("capText under the cap = %q", got)
+	}
+	if got := capText(text, 9); got != "one\ntwo\n... [cut: 7 of 14 bytes shown]" {
+		t.Errorf("capText(9) = %q", got)
+	}
+}
+
+func TestGitContext(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	root := t.TempDir()
+	git := func(args ...string) {
+		cmd := exec.Command("git", append([]string{"-C", root, "-c", "user.name=t", "-c", "user.email=t@example.com"}, args...)...)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	git("init", "-q", "-b", "main")
+
+	// A repository without commits has an empty log, not an error.
+	if out, err := GitContext(root, GitOptions{Log: 3}); err != nil || out != "GIT_LOG:\n(none)\n" {
+		t.Errorf("GitContext before the first commit = %q, %v", out, err)
+	}
+
+	writeFile(t, filepath.Join(root, "a.txt"), "one\n")
+	writeFile(t, filepath.Join(root, "b.txt"), "one\n")
+	git("add", ".")
+	git("commit", "-q", "-m", "Add a and b", "-m", "Both start at one.")
+	writeFile(t, filepath.Join(root, "a.txt"), "two\n")
+	git("add", "a.txt")
+	writeFile(t, filepath.Join(root, "b.txt"), "three\n")
+
+	out, err := GitContext(root, GitOptions{Diff: true, Staged: true, Log: 5, Branch: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"GIT_BRANCH: main\n",
+		"GIT_STAGED_DIFF:\n a.txt | 2 +-",
+		"+two\n",
+		"GIT_UNSTAGED_DIFF:\n b.txt | 2 +-",
+		"+three\n",
+		"GIT_LOG:\n",
+		": Add a and b\nBoth start at one.",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("GitContext lacks %q:\n%s", want, out)
+		}
+	}
+	if strings.Index(out, "+two") > strings.Index(out, "GIT_UNSTAGED_DIFF") {
+		t.Errorf("the staged diff should come first:\n%s", out)
+	}
+
+	if out, _ := GitContext(root, GitOptions{Staged: true}); strings.Contains(out, "GIT_BRANCH") || strings.Contains(out, "three") {
+		t.Errorf("GitContext(Staged) = %q", out)
+	}
+	if staged, err := StagedDiff(root, 1<<20); err != nil || !strings.Contains(staged, "+two") || strings.Contains(staged, "three") {
+		t.Errorf("StagedDiff = %q, %v", staged, err)
+	}
+	git("commit", "-q", "-m", "Change a")
+	if staged, err := StagedDiff(root, 1<<20); err != nil || staged != "" {
+		t.Errorf("StagedDiff with nothing staged = %q, %v", staged, err)
+	}
+
+	if _, err := GitContext(t.TempDir(), GitOptions{Diff: true}); !errors.Is(err, ErrNotGitRepository) {
+		t.Errorf("GitContext outside a repository: %v", err)
+	}
+	if out, err := GitContext(t.TempDir(), GitOptions{Branch: true}); out != "" || err != nil {
+		t.Errorf("GitContext with nothing asked = %q, %v", out, err)
+	}
+}
@@ -0,0 +1,320 @@
+package context
+
+import (
+	stdcontext "context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"docsgpt-cli/internal/config"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultContextBudget is the default size, in tokens, of the project
+// context.
+const DefaultContextBudget = 2000
+
+// InstructionsFile is the project instructions file at a project's root.
+const InstructionsFile = "DOCSGPT.md"
+
+// ProjectFile holds per-project context settings, in .docsgpt.
+const ProjectFile = "project.yaml"
+
+// maxDirtyFiles and maxCommits bound the git status and log sent along.
+const (
+	maxDirtyFiles = 20
+	maxCommits    = 5
+)
+
+// gitTimeout bounds all git commands together.
+const gitTimeout = 3 * time.Second
+
+// Project is what the CLI knows about the project the user is in.
+type Project struct {
+	// Root is the directory holding .docsgpt or DOCSGPT.md, or "" outside
+	// a project.
+	Root string
+	// Instructions are the project's instructions for the model, by file.
+	Instructions []Instructions
+	// Type describes the kind of project, such as "Go module example.com/x".
+	Type string
+	Git  *GitInfo
+	// Budget is the size, in tokens, the project context must fit in.
+	Budget int
+}
+
+// Instructions is the content of one instructions file.
+type Instructions struct {
+	Path string
+	Text string
+}
+
+// GitInfo is the state of the git repository the user is in.
+type GitInfo struct {
+	Branch  string
+	Dirty   []string // git status --short lines
+	Commits []string // git log --oneline, newest first
+}
+
+// projectSettings is the layout of .docsgpt/project.yaml.
+type projectSettings struct {
+	// ContextBudget overrides the context_budget setting for the project.
+	ContextBudget int `yaml:"context_budget"`
+	// Git set to false leaves out the git information.
+	Git *bool `yaml:"git"`
+}
+
+// FindProjectRoot returns the nearest directory, dir or one of its
+// parents, that holds a .docsgpt directory or a DOCSGPT.md file, or "".
+// The home directory's .docsgpt holds the CLI's own state and does not
+// make it a project.
+func FindProjectRoot(dir string) string {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return ""
+	}
+	for {
+		if info, err := os.Stat(filepath.Join(dir, InstructionsFile)); err == nil && !info.IsDir() {
+			return dir
+		}
+		if path := filepath.Join(dir, ".docsgpt"); path != config.Dir() {
+			if info, err := os.Stat(path); err == nil && info.IsDir() {
+				return dir
+			}
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// LoadProject gathers the project context for dir. budget is the
+// configured context budget in tokens (0 for the default); a project's
+// .docsgpt/project.yaml can override it. A project.yaml that cannot be
+// read is reported in the error and ignored: the project is still
+// returned.
+func LoadProject(dir string, budget int) (*Project, error) {
+	p := &Project{Root: FindProjectRoot(dir), Budget: budget}
+	if p.Budget <= 0 {
+		p.Budget = DefaultContextBudget
+	}
+	var err error
+	withGit := true
+	if p.Root != "" {
+		var s projectSettings
+		path := filepath.Join(p.Root, ".docsgpt", ProjectFile)
+		data, rerr := os.ReadFile(path)
+		switch {
+		case rerr == nil:
+			if yerr := yaml.Unmarshal(data, &s); yerr != nil {
+				s, err = projectSettings{}, fmt.Errorf("parse %s: %w", path, yerr)
+			}
+		case !errors.Is(rerr, fs.ErrNotExist):
+			err = rerr
+		}
+		if s.ContextBudget > 0 {
+			p.Budget = s.ContextBudget
+		}
+		if s.Git != nil {
+			withGit = *s.Git
+		}
+		for _, path := range []string{
+			filepath.Join(p.Root, InstructionsFile),
+			filepath.Join(p.Root, ".docsgpt", "instructions.md"),
+		} {
+			if data, err := os.ReadFile(path); err == nil && strings.TrimSpace(string(data)) != "" {
+				p.Instructions = append(p.Instructions, Instructions{Path: path, Text: strings.TrimSpace(string(data))})
+			}
+		}
+	}
+
+	typeDir := p.Root
+	if typeDir == "" {
+		typeDir = dir
+	}
+	p.Type = detectType(typeDir)
+	if withGit {
+		p.Git = loadGit(dir)
+	}
+	return p, err
+}
+
+// detectType describes the project in dir from its manifest files.
+func detectType(dir string) string {
+	var kinds []string
+	if data, err := os.ReadFile(filepath.Join(dir, "go.mod")); err == nil {
+		kind := "Go module"
+		if m := goModuleRe.FindSubmatch(data); m != nil {
+			kind += " " + string(m[1])
+		}
+		if m := goVersionRe.FindSubmatch(data); m != nil {
+			kind += " (go " + string(m[1]) + ")"
+		}
+		kinds = append(kinds, kind)
+	}
+	if data, err := os.ReadFile(filepath.Join(dir, "package.json")); err == nil {
+		kinds = append(kinds, describePackageJSON(dir, data))
+	}
+	for _, m := range []struct{ file, kind string }{
+		{"Cargo.toml", "Rust crate"},
+		{"pyproject.toml", "Python project"},
+		{"requirements.txt", "Python project"},
+		{"pom.xml", "Maven project"},
+		{"build.gradle", "Gradle project"},
+		{"Gemfile", "Ruby project"},
+	} {
+		if _, err := os.Stat(filepath.Join(dir, m.file)); err == nil && !contains(kinds, m.kind) {
+			kinds = append(kinds, m.kind)
+		}
+	}
+	return strings.Join(kinds, "; ")
+}
+
+var (
+	goModuleRe  = regexp.MustCompile(`(?m)^module\s+(\S+)`)
+	goVersionRe = regexp.MustCompile(`(?m)^go\s+(\S+)`)
+)
+
+// describePackageJSON names a Node package, its package manager and its
+// scripts.
+func describePackageJSON(dir string, data []byte) string {
+	var pkg struct {
+		Name    string            `json:"name"`
+		Scripts map[string]string `json:"scripts"`
+	}
+	json.Unmarshal(data, &pkg)
+	kind := "Node package"
+	if pkg.Name != "" {
+		kind += " " + pkg.Name
+	}
+	manager := "npm"
+	for _, lock := range []struct{ file, manager string }{
+		{"pnpm-lock.yaml", "pnpm"},
+		{"yarn.lock", "yarn"},
+		{"bun.lockb", "bun"},
+	} {
+		if _, err := os.Stat(filepath.Join(dir, lock.file)); err == nil {
+			manager = lock.manager
+			break
+		}
+	}
+	kind += " (" + manager
+	if len(pkg.Scripts) > 0 {
+		names := make([]string, 0, len(pkg.Scripts))
+		for name := range pkg.Scripts {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		kind += "; scripts: " + strings.Join(names, ", ")
+	}
+	return kind + ")"
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// loadGit reads the branch, uncommitted changes and recent commits of the
+// repository containing dir, or returns nil outside one or without git.
+func loadGit(dir string) *GitInfo {
+	ctx, cancel := stdcontext.WithTimeout(stdcontext.Background(), gitTimeout)
+	defer cancel()
+	git := func(args ...string) (string, error) {
+		return runGit(ctx, dir, args...)
+	}
+	if !inWorkTree(ctx, dir) {
+		return nil
+	}
+	info := &GitInfo{}
+	info.Branch, _ = git("branch", "--show-current")
+	if info.Branch == "" {
+		if head, err := git("rev-parse", "--short", "HEAD"); err == nil {
+			info.Branch = "detached at " + head
+		}
+	}
+	if status, err := git("status", "--short"); err == nil && status != "" {
+		info.Dirty = strings.Split(status, "\n")
+	}
+	if log, err := git("log", "--oneline", "--no-decorate", fmt.Sprintf("-%d", maxCommits)); err == nil && log != "" {
+		info.Commits = strings.Split(log, "\n")
+	}
+	return info
+}
+
+// Render formats the project context, most important first —
+// instructions, project type, git — cut to fit the budget.
+func (p *Project) Render() string {
+	var sections []string
+	if p.Root != "" {
+		sections = append(sections, fmt.Sprintf("PROJECT_ROOT: %s\n", p.Root))
+	}
+	for _, in := range p.Instructions {
+		sections = append(sections, fmt.Sprintf("PROJECT_INSTRUCTIONS (%s):\n%s\n", in.Path, in.Text))
+	}
+	if p.Type != "" {
+		sections = append(sections, fmt.Sprintf("PROJECT_TYPE: %s\n", p.Type))
+	}
+	if g := p.Git; g != nil {
+		var b strings.Builder
+		if g.Branch != "" {
+			fmt.Fprintf(&b, "GIT_BRANCH: %s\n", g.Branch)
+		}
+		if len(g.Dirty) > 0 {
+			dirty := g.Dirty
+			if len(dirty) > maxDirtyFiles {
+				dirty = append(dirty[:maxDirtyFiles:maxDirtyFiles], fmt.Sprintf("... and %d more", len(g.Dirty)-maxDirtyFiles))
+			}
+			fmt.Fprintf(&b, "GIT_UNCOMMITTED_CHANGES:\n%s\n", strings.Join(dirty, "\n"))
+		}
+		if len(g.Commits) > 0 {
+			fmt.Fprintf(&b, "GIT_RECENT_COMMITS:\n%s\n", strings.Join(g.Commits, "\n"))
+		}
+		sections = append(sections, b.String())
+	}
+	return fitBudget(sections, p.Budget)
+}
+
+// EstimateTokens approximates how many tokens text takes: about four bytes
+// each for English and code.
+func EstimateTokens(text string) int {
+	return (len(text) + 3) / 4
+}
+
+// fitBudget joins sections while they fit in budget tokens. The first
+// section that does not fit is cut short, and the rest are left out.
+func fitBudget(sections []string, budget int) string {
+	const cutNote = "... [cut to fit the context budget]\n"
+	room := budget * 4
+	var b strings.Builder
+	for _, s := range sections {
+		if b.Len()+len(s) <= room {
+			b.WriteString(s)
+			continue
+		}
+		if left := room - b.Len() - len(cutNote); left > 0 {
+			cut := s[:left]
+			if i := strings.LastIndexByte(cut, '\n'); i > 0 {
+				cut = cut[:i+1]
+			}
+			b.WriteString(cut)
+			b.WriteString(cutNote)
+		}
+		break
+	}
+	return b.String()
+}
@@ -0,0 +1,162 @@
+package context
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeFile(t *testing.T, path, data string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFindProjectRoot(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	os.MkdirAll(filepath.Join(home, ".docsgpt"), 0o755)
+	os.MkdirAll(filepath.Join(home, "md", "a", "b"), 0o755)
+	writeFile(t, filepath.Join(home, "md", InstructionsFile), "Use tabs.")
+	os.MkdirAll(filepath.Join(home, "dot", ".docsgpt"), 0o755)
+	os.MkdirAll(filepath.Join(home, "dot", "x"), 0o755)
+	os.MkdirAll(filepath.Join(home, "none"), 0o755)
+
+	cases := map[string]string{
+		filepath.Join(home, "md", "a", "b"): filepath.Join(home, "md"),
+		filepath.Join(home, "md"):           filepath.Join(home, "md"),
+		filepath.Join(home, "dot", "x"):     filepath.Join(home, "dot"),
+		// ~/.docsgpt is the CLI's own directory, not a project.
+		filepath.Join(home, "none"): "",
+	}
+	for dir, want := range cases {
+		if got := FindProjectRoot(dir); got != want {
+			t.Errorf("FindProjectRoot(%s) = %q, want %q", dir, got, want)
+		}
+	}
+}
+
+func TestDetectType(t *testing.T) {
+	cases := []struct {
+		files map[string]string
+		want  string
+	}{
+		{map[string]string{"go.mod": "module example.com/app\n\ngo 1.22\n"}, "Go module example.com/app (go 1.22)"},
+		{map[string]string{
+			"package.json": `{"name":"web","scripts":{"test":"vitest","build":"vite build"}}`,
+			"yarn.lock":    "",
+		}, "Node package web (yarn; scripts: build, test)"},
+		{map[string]string{"package.json": `{}`}, "Node package (npm)"},
+		{map[string]string{"pyproject.toml": "", "requirements.txt": ""}, "Python project"},
+		{map[string]string{"go.mod": "module m\n", "Cargo.toml": ""}, "Go module m; Rust crate"},
+		{map[string]string{"README.md": ""}, ""},
+	}
+	for _, c := range cases {
+		dir := t.TempDir()
+		for name, data := range c.files {
+			writeFile(t, filepath.Join(dir, name), data)
+		}
+		if got := detectType(dir); got != c.want {
+			t.Errorf("detectType(%v) = %q, want %q", c.files, got, c.want)
+		}
+	}
+}
+
+func TestFitBudget(t *testing.T) {
+	sections := []string{"A: 1\n", "B:\n" + strings.Repeat("line\n", 20), "C: 3\n"}
+	if got := fitBudget(sections, 100); got != strings.Join(sections, "") {
+		t.Errorf("everything fits in 100 tokens, got %q", got)
+	}
+	got := fitBudget(sections, 15)
+	if !strings.HasPrefix(got, "A: 1\nB:\nline\n") || !strings.HasSuffix(got, "[cut to fit the context budget]\n") || strings.Contains(got, "C: 3") {
+		t.Errorf("fitBudget(15) = %q", got)
+	}
+	if len(got) > 15*4 {
+		t.Errorf("fitBudget(15) is %d bytes, over the budget", len(got))
+	}
+	if got := fitBudget(sections, 2); got != "A: 1\n" {
+		t.Errorf("fitBudget(2) = %q", got)
+	}
+}
+
+func TestLoadProject(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, InstructionsFile), "Run make test before committing.\n")
+	writeFile(t, filepath.Join(root, ".docsgpt", "instructions.md"), "Never touch vendor/.")
+	writeFile(t, filepath.Join(root, ".docsgpt", ProjectFile), "context_budget: 500\ngit: false\n")
+	writeFile(t, filepath.Join(root, "go.mod"), "module example.com/app\n")
+	os.MkdirAll(filepath.Join(root, "cmd"), 0o755)
+
+	p, err := LoadProject(filepath.Join(root, "cmd"), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Root != root || p.Budget != 500 || p.Git != nil || p.Type != "Go module example.com/app" || len(p.Instructions) != 2 {
+		t.Fatalf("LoadProject = %+v", p)
+	}
+	out := p.Render()
+	for _, want := range []string{
+		"PROJECT_ROOT: " + root,
+		"PROJECT_INSTRUCTIONS (" + filepath.Join(root, InstructionsFile) + "):\nRun make test before committing.\n",
+		"Never touch vendor/.",
+		"PROJECT_TYPE: Go module example.com/app\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Render() lacks %q:\n%s", want, out)
+		}
+	}
+
+	// A broken project.yaml is reported, and the rest still loads.
+	writeFile(t, filepath.Join(root, ".docsgpt", ProjectFile), "context_budget: [\n")
+	p, err = LoadProject(root, 300)
+	if err == nil || p == nil || p.Budget != 300 || len(p.Instructions) != 2 {
+		t.Errorf("LoadProject with a broken project.yaml = %+v, %v", p, err)
+	}
+}
+
+func TestLoadProjectGit(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	t.Setenv("HOME", t.TempDir())
+	root := t.TempDir()
+	git := func(args ...string) {
+		cmd := exec.Command("git", append([]string{"-C", root, "-c", "user.name=t", "-c", "user.email=t@example.com"}, args...)...)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	git("init", "-q", "-b", "main")
+	writeFile(t, filepath.Join(root, "a.txt"), "a")
+	git("add", "a.txt")
+	git("commit", "-q", "-m", "Add a")
+	writeFile(t, filepath.Join(root, "a.txt"), "changed")
+	writeFile(t, filepath.Join(root, "b.txt"), "new")
+
+	p, err := LoadProject(root, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Root != "" || p.Git == nil {
+		t.Fatalf("LoadProject = %+v", p)
+	}
+	g := p.Git
+	if g.Branch != "main" || strings.Join(g.Dirty, "|") != " M a.txt|?? b.txt" || len(g.Commits) != 1 || !strings.HasSuffix(g.Commits[0], " Add a") {
+		t.Errorf("git info = %+v", g)
+	}
+	out := p.Render()
+	if !strings.Contains(out, "GIT_BRANCH: main\n") || !strings.Contains(out, "GIT_UNCOMMITTED_CHANGES:\n M a.txt\n?? b.txt\n") {
+		t.Errorf("Render() = %q", out)
+	}
+
+	if g := loadGit(t.TempDir()); g != nil {
+		t.Errorf("loadGit outside a repository = %+v", g)
+	}
+}
@@ -31,23 +31,8 @@ func cardWidth() int {
 
 // RenderApprovalCard builds a bordered approval card for a tool call.
 func RenderApprovalCard(toolName, detail string, preview []string, risk string) string {
-	w := cardWidth()
-
-	// Risk badge
-	var badge string
-	switch risk {
-	case "safe":
-		badge = T.Success.Render(" SAFE ")
-	case "caution":
-		badge = T.Warn.Render(" CAUTION ")
-	case "danger":
-		badge = T.Danger.Render(" DANGER ")
-	default:
-		badge = T.Muted.Render(" " + risk + " ")
-	}
-
 	// Header line
-	header := fmt.Sprintf("🔧 %s  %s", T.Accent.Bold(true).Render(toolName), badge)
+	header := fmt.Sprintf("🔧 %s  %s", T.Accent.Bold(true).Render(toolName), riskBadge(risk))
 
 	// Detail line
 	detailLine := T.Info.Render(detail)
@@ -68,19 +53,67 @@ func RenderApprovalCard(toolName, detail string, preview []string, risk string)
 	}
 
 	// Separator + choices
-	choices := fmt.Sprintf("  %s  %s  %s",
+	choices := fmt.Sprintf("  %s  %s  %s  %s",
 		T.Selection.Render("[1] Approve"),
 		T.Muted.Render("[2] Deny"),
 		T.Muted.Render("[3] Edit"),
+		T.Muted.Render("[4] Always…"),
+	)
+	parts = append(parts, "", choices)
+
+	return renderCard(strings.Join(parts, "\n"))
+}
+
+// RenderBatchApprovalCard builds one approval card for several tool calls
+// that are approved together; details holds one line per call.
+func RenderBatchApprovalCard(details []string, risk string) string {
+	header := fmt.Sprintf("🔧 %s  %s", T.Accent.Bold(true).Render(fmt.Sprintf("%d tool calls", len(details))), riskBadge(risk))
+	parts := []string{header}
+	for i, d := range details {
+		parts = append(parts, T.Muted.Render(fmt.Sprintf("%d.", i+1))+" "+T.Info.Render(d))
+	}
+	choices := fmt.Sprintf("  %s  %s  %s",
+		T.Selection.Render("[1] Approve all"),
+		T.Muted.Render("[2] Deny all"),
+		T.Muted.Render("[3] Review each"),
 	)
 	parts = append(parts, "", choices)
 
-	body := strings.Join(parts, "\n")
+	return renderCard(strings.Join(parts, "\n"))
+}
+
+// ColorDiffLine colors one line of a unified diff for a card preview:
+// additions green, removals red, hunk headers in the accent color.
+func ColorDiffLine(line string) string {
+	switch {
+	case strings.HasPrefix(line, "@@"):
+		return T.Accent.Render(line)
+	case strings.HasPrefix(line, "+"):
+		return T.Success.Render(line)
+	case strings.HasPrefix(line, "-"):
+		return T.Danger.Render(line)
+	}
+	return line
+}
+
+// riskBadge renders a tool risk level as a colored badge.
+func riskBadge(risk string) string {
+	switch risk {
+	case "safe":
+		return T.Success.Render(" SAFE ")
+	case "caution":
+		return T.Warn.Render(" CAUTION ")
+	case "danger":
+		return T.Danger.Render(" DANGER ")
+	default:
+		return T.Muted.Render(" " + risk + " ")
+	}
+}
 
-	// Wrap in bordered box
-	useRounded := termWidth() >= 40
+// renderCard wraps an approval card's body in its bordered box.
+func renderCard(body string) string {
 	border := lipgloss.NormalBorder()
-	if useRounded {
+	if termWidth() >= 40 {
 		border = lipgloss.RoundedBorder()
 	}
 
@@ -88,7 +121,7 @@ func RenderApprovalCard(toolName, detail string, preview []string, risk string)
 		Border(border).
 		BorderForeground(T.Border.GetForeground()).
 		Padding(0, 1).
-		Width(w)
+		Width(cardWidth())
 
 	return cardStyle.Render(body)
 }
@@ -96,11 +129,11 @@ func RenderApprovalCard(toolName, detail string, preview []string, risk string)
 // ToolRisk returns the risk level for a given tool name.
 func ToolRisk(toolName string) string {
 	switch toolName {
-	case "read_file":
+	case "read_file", "list_dir", "glob", "grep", "file_info":
 		return "safe"
 	case "run_command":
 		return "caution"
-	case "write_file":
+	case "write_file", "edit_file":
 		return "caution"
 	default:
 		return "caution"
@@ -0,0 +1,63 @@
+package display
+
+import (
+	"fmt"
+	"strings"
+
+	"docsgpt-cli/internal/api"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// snippetRunes bounds the one-line snippet in the compact citation footer.
+const snippetRunes = 100
+
+// RenderSources renders a numbered citation list. The compact form (used
+// under every answer) shows each source's title, location and a one-line
+// snippet; expanded shows the full retrieved text, wrapped to the terminal.
+// It returns "" when there are no sources.
+func RenderSources(sources []api.Source, expanded bool) string {
+	if len(sources) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString(T.Muted.Render("Sources") + "\n")
+	indent := "      "
+	wrap := lipgloss.NewStyle().Width(max(termWidth()-len(indent)-2, 20))
+	for i, s := range sources {
+		fmt.Fprintf(&b, "  %s %s\n", T.Accent.Render(fmt.Sprintf("[%d]", i+1)), sourceLabel(s))
+		if s.Text == "" {
+			continue
+		}
+		if !expanded {
+			b.WriteString(indent + T.Muted.Render(oneLine(s.Text, snippetRunes)) + "\n")
+			continue
+		}
+		for _, line := range strings.Split(wrap.Render(strings.TrimSpace(s.Text)), "\n") {
+			b.WriteString(indent + T.Muted.Render(strings.TrimRight(line, " ")) + "\n")
+		}
+	}
+	return b.String()
+}
+
+// sourceLabel joins a source's title and location, whichever are known.
+func sourceLabel(s api.Source) string {
+	switch {
+	case s.Title != "" && s.Location != "":
+		return s.Title + T.Muted.Render(" · "+s.Location)
+	case s.Title != "":
+		return s.Title
+	case s.Location != "":
+		return s.Location
+	}
+	return T.Muted.Render("(untitled)")
+}
+
+// oneLine collapses whitespace and truncates s to n runes.
+func oneLine(s string, n int) string {
+	s = strings.Join(strings.Fields(s), " ")
+	if r := []rune(s); len(r) > n {
+		return string(r[:n-1]) + "…"
+	}
+	return s
+}
@@ -0,0 +1,201 @@
+// Package export renders a saved chat session as a standalone transcript:
+// Markdown for pasting into issues and reviews, JSON for tooling, or a
+// self-contained HTML page. Every format carries the full history, including
+// tool calls, tool results and reasoning.
+package export
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"docsgpt-cli/internal/api"
+	"docsgpt-cli/internal/session"
+)
+
+// Supported formats.
+const (
+	FormatMarkdown = "md"
+	FormatJSON     = "json"
+	FormatHTML     = "html"
+)
+
+// ParseFormat normalizes a user-supplied format name.
+func ParseFormat(name string) (string, error) {
+	switch strings.ToLower(strings.TrimPrefix(name, ".")) {
+	case "md", "markdown":
+		return FormatMarkdown, nil
+	case "json":
+		return FormatJSON, nil
+	case "html", "htm":
+		return FormatHTML, nil
+	}
+	return "", fmt.Errorf("unknown export format %q (use md, json, or html)", name)
+}
+
+// FormatForPath infers the format from a file extension, defaulting to
+// Markdown when the extension is missing or unrecognized.
+func FormatForPath(path string) string {
+	if f, err := ParseFormat(filepath.Ext(path)); err == nil {
+		return f
+	}
+	return FormatMarkdown
+}
+
+// Write renders sess in format to w.
+func Write(w io.Writer, format string, sess *session.Session) error {
+	switch format {
+	case FormatMarkdown:
+		_, err := io.WriteString(w, Markdown(sess))
+		return err
+	case FormatJSON:
+		data, err := json.MarshalIndent(sess, "", "  ")
+		if err != nil {
+			return fmt.Errorf("marshal session: %w", err)
+		}
+		_, err = w.Write(append(data, '\n'))
+		return err
+	case FormatHTML:
+		return writeHTML(w, sess)
+	}
+	return fmt.Errorf("unknown export format %q", format)
+}
+
+// Markdown renders sess as a Markdown transcript. The system context message
+// is included (collapsed) since it is part of what the model saw.
+func Markdown(sess *session.Session) string {
+	var b strings.Builder
+	title := sess.Title
+	if title == "" {
+		title = "DocsGPT session " + sess.ID
+	}
+	fmt.Fprintf(&b, "# %s\n\n", title)
+	for _, kv := range metadata(sess) {
+		fmt.Fprintf(&b, "- **%s:** %s\n", kv[0], kv[1])
+	}
+	b.WriteString("\n---\n")
+
+	for _, m := range sess.Messages {
+		switch m.Role {
+		case "system":
+			b.WriteString("\n<details><summary>Context</summary>\n\n")
+			b.WriteString(codeBlock(m.Content, ""))
+			b.WriteString("\n</details>\n")
+		case "user":
+			b.WriteString("\n## User\n\n")
+			b.WriteString(strings.TrimSpace(m.Content) + "\n")
+			if len(m.Attachments) > 0 {
+				names := make([]string, len(m.Attachments))
+				for i, a := range m.Attachments {
+					names[i] = "`" + a.Name + "`"
+				}
+				b.WriteString("\n*Attachments:* " + strings.Join(names, ", ") + "\n")
+			}
+		case "assistant":
+			b.WriteString("\n## Assistant\n\n")
+			if m.ReasoningContent != "" {
+				b.WriteString("<details><summary>Reasoning</summary>\n\n")
+				b.WriteString(strings.TrimSpace(m.ReasoningContent) + "\n")
+				b.WriteString("\n</details>\n\n")
+			}
+			if m.Content != "" {
+				b.WriteString(strings.TrimSpace(m.Content) + "\n")
+			}
+			if m.Incomplete {
+				b.WriteString("\n*(answer incomplete: the connection dropped)*\n")
+			}
+			if len(m.Sources) > 0 {
+				b.WriteString("\n**Sources**\n\n")
+				for i, src := range m.Sources {
+					fmt.Fprintf(&b, "%d. %s\n", i+1, sourceLine(src))
+				}
+			}
+			for _, tc := range m.ToolCalls {
+				fmt.Fprintf(&b, "\n**Tool call** `%s` (%s)\n\n", tc.Function.Name, tc.ID)
+				b.WriteString(codeBlock(prettyJSON(tc.Function.Arguments), "json"))
+			}
+		case "tool":
+			fmt.Fprintf(&b, "\n**Tool result** (%s)\n\n", m.ToolCallID)
+			b.WriteString(codeBlock(m.Content, ""))
+		}
+	}
+	return b.String()
+}
+
+// metadata lists the header fields shared by the Markdown and HTML outputs.
+func metadata(sess *session.Session) [][2]string {
+	out := [][2]string{
+		{"Session", sess.ID},
+		{"Server", sess.BaseURL},
+		{"Key", sess.KeyName},
+	}
+	if sess.Model != "" {
+		out = append(out, [2]string{"Model", sess.Model})
+	}
+	if sess.ConversationID != "" {
+		out = append(out, [2]string{"Conversation", sess.ConversationID})
+	}
+	if !sess.CreatedAt.IsZero() {
+		out = append(out, [2]string{"Started", sess.CreatedAt.Local().Format(time.RFC1123)})
+	}
+	out = append(out, [2]string{"Exported", time.Now().Format(time.RFC1123)})
+	return out
+}
+
+// sourceLine renders one citation as a Markdown list item body.
+func sourceLine(src api.Source) string {
+	var parts []string
+	if src.Title != "" {
+		parts = append(parts, "**"+src.Title+"**")
+	}
+	if src.Location != "" {
+		parts = append(parts, "`"+src.Location+"`")
+	}
+	line := strings.Join(parts, " — ")
+	if src.Text != "" {
+		snippet := strings.Join(strings.Fields(src.Text), " ")
+		if r := []rune(snippet); len(r) > snippetRunes {
+			snippet = string(r[:snippetRunes-1]) + "…"
+		}
+		if line != "" {
+			line += ": "
+		}
+		line += snippet
+	}
+	return line
+}
+
+// snippetRunes bounds the source text quoted in a transcript.
+const snippetRunes = 200
+
+// codeBlock fences s with a run of backticks longer than any inside it, so
+// tool output containing ``` cannot break out of the block.
+func codeBlock(s, lang string) string {
+	longest, run := 0, 0
+	for _, r := range s {
+		if r == '`' {
+			run++
+			longest = max(longest, run)
+		} else {
+			run = 0
+		}
+	}
+	fence := strings.Repeat("`", max(3, longest+1))
+	return fence + lang + "\n" + strings.TrimRight(s, "\n") + "\n" + fence + "\n"
+}
+
+// prettyJSON indents raw tool arguments, leaving invalid JSON untouched.
+func prettyJSON(raw string) string {
+	var v any
+	if json.Unmarshal([]byte(raw), &v) != nil {
+		return raw
+	}
+	out, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return raw
+	}
+	return string(out)
+}
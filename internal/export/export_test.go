@@ -0,0 +1,106 @@
+package export
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"docsgpt-cli/internal/api"
+	"docsgpt-cli/internal/session"
+)
+
+func sampleSession() *session.Session {
+	return &session.Session{
+		ID:             "20261018-101500-abcd",
+		Title:          "why does the build fail?",
+		KeyName:        "work",
+		BaseURL:        "https://example.com",
+		ConversationID: "conv-9",
+		Messages: []api.Message{
+			{Role: "system", Content: "CURRENT_DIRECTORY: /src"},
+			{Role: "user", Content: "why does the build fail?", Attachments: []api.Attachment{{ID: "a1", Name: "build.log"}}},
+			{Role: "assistant", ReasoningContent: "check go.mod first", ToolCalls: []api.ToolCall{
+				{ID: "call_1", Function: api.FunctionCall{Name: "read_file", Arguments: `{"path":"go.mod"}`}},
+			}},
+			{Role: "tool", ToolCallID: "call_1", Content: "```\n<script>alert(1)</script>"},
+			{Role: "assistant", Content: "Run `go mod tidy`.", Sources: []api.Source{
+				{Title: "Build guide", Location: "docs/build.md", Text: "Run go mod tidy\nbefore building."},
+			}},
+		},
+	}
+}
+
+func TestMarkdown(t *testing.T) {
+	out := Markdown(sampleSession())
+	for _, want := range []string{
+		"# why does the build fail?",
+		"- **Conversation:** conv-9",
+		"## User\n\nwhy does the build fail?",
+		"<summary>Reasoning</summary>\n\ncheck go.mod first",
+		"**Tool call** `read_file` (call_1)",
+		"\"path\": \"go.mod\"",
+		"**Tool result** (call_1)",
+		"Run `go mod tidy`.",
+		"*Attachments:* `build.log`",
+		"**Sources**\n\n1. **Build guide** — `docs/build.md`: Run go mod tidy before building.",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Markdown output missing %q\n%s", want, out)
+		}
+	}
+	// Tool output holding ``` must be wrapped in a longer fence.
+	if !strings.Contains(out, "````\n```\n<script>") {
+		t.Errorf("tool result containing ``` was not fenced with ````:\n%s", out)
+	}
+}
+
+func TestHTMLEscapesContent(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Write(&buf, FormatHTML, sampleSession()); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	if strings.Contains(out, "<script>alert(1)</script>") {
+		t.Error("HTML export must escape tool output")
+	}
+	for _, want := range []string{"<!DOCTYPE html>", "<code>read_file</code>", "<code>go mod tidy</code>", "check go.mod first"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("HTML output missing %q", want)
+		}
+	}
+}
+
+func TestJSONRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Write(&buf, FormatJSON, sampleSession()); err != nil {
+		t.Fatal(err)
+	}
+	var got session.Session
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Messages) != 5 || got.Messages[2].ReasoningContent != "check go.mod first" {
+		t.Errorf("JSON export lost history: %+v", got.Messages)
+	}
+}
+
+func TestFormatForPath(t *testing.T) {
+	tests := map[string]string{
+		"out.md":       FormatMarkdown,
+		"out.markdown": FormatMarkdown,
+		"out.JSON":     FormatJSON,
+		"report.html":  FormatHTML,
+		"report.htm":   FormatHTML,
+		"notes":        FormatMarkdown,
+		"notes.txt":    FormatMarkdown,
+	}
+	for path, want := range tests {
+		if got := FormatForPath(path); got != want {
+			t.Errorf("FormatForPath(%q) = %q, want %q", path, got, want)
+		}
+	}
+	if _, err := ParseFormat("pdf"); err == nil {
+		t.Error("ParseFormat(pdf) should fail")
+	}
+}
@@ -0,0 +1,146 @@
+package export
+
+import (
+	"bytes"
+	"html/template"
+	"io"
+	"strings"
+
+	"docsgpt-cli/internal/api"
+	"docsgpt-cli/internal/session"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+)
+
+// md converts answer Markdown to HTML. Raw HTML in answers is escaped
+// (goldmark's default), so a transcript can never inject markup.
+var md = goldmark.New(goldmark.WithExtensions(extension.GFM))
+
+type htmlMessage struct {
+	Role        string
+	Body        template.HTML
+	Reasoning   template.HTML
+	Raw         string
+	Incomplete  bool
+	ToolCallID  string
+	ToolCalls   []htmlToolCall
+	Attachments []string
+	Sources     []api.Source
+}
+
+type htmlToolCall struct {
+	ID        string
+	Name      string
+	Arguments string
+}
+
+type htmlPage struct {
+	Title    string
+	Meta     [][2]string
+	Messages []htmlMessage
+}
+
+func writeHTML(w io.Writer, sess *session.Session) error {
+	page := htmlPage{Title: sess.Title, Meta: metadata(sess)}
+	if page.Title == "" {
+		page.Title = "DocsGPT session " + sess.ID
+	}
+	for _, m := range sess.Messages {
+		hm := htmlMessage{Role: m.Role, ToolCallID: m.ToolCallID, Incomplete: m.Incomplete, Sources: m.Sources}
+		for _, a := range m.Attachments {
+			hm.Attachments = append(hm.Attachments, a.Name)
+		}
+		switch m.Role {
+		case "user", "assistant":
+			hm.Body = renderMarkdown(m.Content)
+			hm.Reasoning = renderMarkdown(m.ReasoningContent)
+		default:
+			hm.Raw = m.Content
+		}
+		for _, tc := range m.ToolCalls {
+			hm.ToolCalls = append(hm.ToolCalls, htmlToolCall{
+				ID:        tc.ID,
+				Name:      tc.Function.Name,
+				Arguments: prettyJSON(tc.Function.Arguments),
+			})
+		}
+		page.Messages = append(page.Messages, hm)
+	}
+	return pageTmpl.Execute(w, page)
+}
+
+func renderMarkdown(s string) template.HTML {
+	if strings.TrimSpace(s) == "" {
+		return ""
+	}
+	var buf bytes.Buffer
+	if err := md.Convert([]byte(s), &buf); err != nil {
+		return template.HTML("<pre>" + template.HTMLEscapeString(s) + "</pre>")
+	}
+	return template.HTML(buf.String())
+}
+
+var pageTmpl = template.Must(template.New("page").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Title}}</title>
+<style>
+body { font: 15px/1.55 -apple-system, BlinkMacSystemFont, "Segoe UI", Helvetica, Arial, sans-serif; color: #1f2328; max-width: 860px; margin: 2rem auto; padding: 0 1rem; }
+h1 { font-size: 1.5rem; }
+dl.meta { display: grid; grid-template-columns: max-content 1fr; gap: .2rem 1rem; color: #59636e; font-size: .9rem; }
+dl.meta dt { font-weight: 600; }
+dl.meta dd { margin: 0; }
+.msg { border-left: 3px solid #d1d9e0; padding: .2rem 1rem; margin: 1.2rem 0; }
+.msg.user { border-color: #8250df; }
+.msg.assistant { border-color: #1a7f37; }
+.msg.tool, .msg.system { border-color: #9a6700; }
+.role { font-size: .75rem; font-weight: 700; letter-spacing: .05em; text-transform: uppercase; color: #59636e; }
+pre { background: #f6f8fa; padding: .8rem; overflow-x: auto; border-radius: 6px; font-size: .85rem; }
+code { font-family: ui-monospace, SFMono-Regular, Menlo, Consolas, monospace; }
+details { margin: .5rem 0; color: #59636e; }
+.call { margin: .6rem 0; }
+.attachments { color: #59636e; font-size: .9rem; }
+.sources blockquote { margin: .3rem 0 .6rem; padding-left: .8rem; border-left: 2px solid #d1d9e0; white-space: pre-wrap; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<dl class="meta">{{range .Meta}}<dt>{{index . 0}}</dt><dd>{{index . 1}}</dd>{{end}}</dl>
+{{range .Messages}}
+<div class="msg {{.Role}}">
+{{- if eq .Role "system"}}
+<details><summary class="role">Context</summary><pre><code>{{.Raw}}</code></pre></details>
+{{- else if eq .Role "tool"}}
+<div class="role">Tool result · {{.ToolCallID}}</div>
+<pre><code>{{.Raw}}</code></pre>
+{{- else}}
+<div class="role">{{.Role}}</div>
+{{- if .Reasoning}}
+<details><summary>Reasoning</summary>{{.Reasoning}}</details>
+{{- end}}
+{{.Body}}
+{{- if .Attachments}}
+<p class="attachments">Attachments:{{range .Attachments}} <code>{{.}}</code>{{end}}</p>
+{{- end}}
+{{- if .Incomplete}}
+<p><em>(answer incomplete: the connection dropped)</em></p>
+{{- end}}
+{{- if .Sources}}
+<details class="sources"><summary>Sources ({{len .Sources}})</summary><ol>
+{{- range .Sources}}
+<li>{{if .Title}}<strong>{{.Title}}</strong>{{end}}{{if .Location}} <code>{{.Location}}</code>{{end}}{{if .Text}}<blockquote>{{.Text}}</blockquote>{{end}}</li>
+{{- end}}
+</ol></details>
+{{- end}}
+{{- range .ToolCalls}}
+<div class="call"><div class="role">Tool call · <code>{{.Name}}</code> · {{.ID}}</div><pre><code>{{.Arguments}}</code></pre></div>
+{{- end}}
+{{- end}}
+</div>
+{{end}}
+</body>
+</html>
+`))
@@ -4,31 +4,49 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"os/exec"
+	"path/filepath"
+	"regexp"
 	"runtime"
 	"time"
 
-	"docsgpt-cli/internal/tools"
+	"docsgpt-cli/internal/policy"
+	"docsgpt-cli/internal/redact"
+	"docsgpt-cli/internal/sandbox"
 )
 
+// Sandbox, when set, confines every invocation's command to it. The
+// invocation's root is writable besides its Writable paths: SandboxRoot,
+// or else the invocation's working directory.
+var Sandbox *sandbox.Options
+
+// SandboxRoot is the writable root of sandboxed invocations, whatever
+// working directory they name.
+var SandboxRoot string
+
+// Redactor, when set, masks the secrets in the output streamed to the
+// server.
+var Redactor *redact.Redactor
+
 // ExecuteAndStream runs the invocation locally and streams stdout/stderr to
 // the server via chunked POST.
 func ExecuteAndStream(ctx context.Context, t *Transport, sessionID string, inv Invocation) {
-	// CLI-side safety floor.
 	command, _ := inv.Params["command"].(string)
 	workingDir, _ := inv.Params["working_directory"].(string)
 	timeoutMs := 30000
 	if v, ok := inv.Params["timeout_ms"].(float64); ok {
 		timeoutMs = int(v)
 	}
-	if safe, reason := tools.IsSafe(command); !safe {
+	// CLI-side safety floor: the local command policy's deny verdicts. Its
+	// ask verdicts do not apply here — the effective approval mode is
+	// already resolved server-side, and the CLI honors it without
+	// re-prompting (no human at the device).
+	if d := policy.Check(command, workingDir); d.Verdict == policy.Deny {
 		_ = t.PostAck(ctx, sessionID, inv.InvocationID, "denied", "denied_by_safety")
-		_ = postControl(ctx, t, sessionID, inv.InvocationID, 0, "command_blocked_by_denylist", reason)
+		_ = postControl(ctx, t, sessionID, inv.InvocationID, 0, "command_blocked_by_denylist", d.Reason)
 		return
 	}
-	// Effective approval mode is already resolved server-side. The CLI
-	// honors it without re-prompting (no human at the device).
-	_ = t.PostAck(ctx, sessionID, inv.InvocationID, "accepted", "writes_only_passthrough")
 
 	timeout := time.Duration(timeoutMs) * time.Millisecond
 	cmdCtx, cancel := context.WithTimeout(ctx, timeout)
@@ -43,6 +61,20 @@ func ExecuteAndStream(ctx context.Context, t *Transport, sessionID string, inv I
 	if workingDir != "" {
 		cmd.Dir = workingDir
 	}
+	// With a sandbox configured, a command that cannot be confined is
+	// refused rather than run unconfined.
+	if Sandbox != nil {
+		opts, err := invocationSandbox(workingDir)
+		if err == nil {
+			err = sandbox.Wrap(cmd, opts)
+		}
+		if err != nil {
+			_ = t.PostAck(ctx, sessionID, inv.InvocationID, "denied", "denied_by_safety")
+			_ = postControl(ctx, t, sessionID, inv.InvocationID, 0, "sandbox_unavailable", err.Error())
+			return
+		}
+	}
+	_ = t.PostAck(ctx, sessionID, inv.InvocationID, "accepted", "writes_only_passthrough")
 
 	stdout := &lineStreamer{
 		t:            t,
@@ -84,6 +116,36 @@ func ExecuteAndStream(ctx context.Context, t *Transport, sessionID string, inv I
 	_ = postControlFull(ctx, t, sessionID, inv.InvocationID, exitCode, int(duration), errMsg, stderr.nextSeq())
 }
 
+// invocationSandbox returns the sandbox of an invocation that runs in
+// workingDir. The root must be absolute, and neither / nor the home
+// directory, or the sandbox would leave nearly everything writable.
+func invocationSandbox(workingDir string) (sandbox.Options, error) {
+	root := SandboxRoot
+	if root == "" {
+		root = workingDir
+	}
+	if root == "" || !filepath.IsAbs(root) {
+		return sandbox.Options{}, errors.New("the command has no absolute working directory to confine it to; set host_sandbox_root")
+	}
+	if err := sandbox.CheckRoot(root); err != nil {
+		return sandbox.Options{}, err
+	}
+	opts := *Sandbox
+	opts.Writable = append([]string{root}, Sandbox.Writable...)
+	return opts, nil
+}
+
+// pemBegin and pemEnd mark a PEM private key. Output is redacted line by
+// line, so the lines of a key are held back and sent as one chunk for
+// Redactor to mask whole.
+var (
+	pemBegin = regexp.MustCompile(`-----BEGIN [A-Z ]*PRIVATE KEY-----`)
+	pemEnd   = regexp.MustCompile(`-----END [A-Z ]*PRIVATE KEY-----`)
+)
+
+// maxPEMBytes bounds a held-back key block; keys are a few KB.
+const maxPEMBytes = 64 * 1024
+
 type lineStreamer struct {
 	t            *Transport
 	sessionID    string
@@ -92,6 +154,10 @@ type lineStreamer struct {
 	seq          int
 	parent       *lineStreamer
 	buf          bytes.Buffer
+	// pem holds the lines of an open private key block, from its BEGIN
+	// line; pemHeader is that line.
+	pem       bytes.Buffer
+	pemHeader string
 }
 
 func (l *lineStreamer) Write(p []byte) (int, error) {
@@ -101,12 +167,12 @@ func (l *lineStreamer) Write(p []byte) (int, error) {
 		if !found {
 			break
 		}
-		l.send(string(line) + "\n")
+		l.emit(string(line) + "\n")
 		l.buf.Reset()
 		l.buf.Write(rest)
 	}
 	if l.buf.Len() > 16*1024 {
-		l.send(l.buf.String())
+		l.emit(l.buf.String())
 		l.buf.Reset()
 	}
 	return len(p), nil
@@ -114,15 +180,47 @@ func (l *lineStreamer) Write(p []byte) (int, error) {
 
 func (l *lineStreamer) flush() {
 	if l.buf.Len() > 0 {
-		l.send(l.buf.String())
+		l.emit(l.buf.String())
 		l.buf.Reset()
 	}
+	if l.pem.Len() > 0 {
+		l.send(l.pem.String())
+		l.pem.Reset()
+	}
+}
+
+// emit sends a line, or holds it back while a private key block is open.
+// A block still open after maxPEMBytes is sent in parts, each starting
+// with the BEGIN line so Redactor masks it all.
+func (l *lineStreamer) emit(line string) {
+	if Redactor == nil {
+		l.send(line)
+		return
+	}
+	if l.pem.Len() == 0 {
+		if !pemBegin.MatchString(line) || pemEnd.MatchString(line) {
+			l.send(line)
+			return
+		}
+		l.pemHeader = line
+	}
+	l.pem.WriteString(line)
+	switch {
+	case pemEnd.MatchString(line):
+		l.send(l.pem.String())
+		l.pem.Reset()
+	case l.pem.Len() > maxPEMBytes:
+		l.send(l.pem.String())
+		l.pem.Reset()
+		l.pem.WriteString(l.pemHeader)
+	}
 }
 
 func (l *lineStreamer) send(chunk string) {
 	if chunk == "" {
 		return
 	}
+	chunk = Redactor.Redact("host command "+l.stream, chunk)
 	payload := map[string]interface{}{
 		"stream": l.stream,
 		"chunk":  chunk,
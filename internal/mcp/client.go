@@ -0,0 +1,346 @@
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ProtocolVersion is the MCP revision the client speaks.
+const ProtocolVersion = "2025-06-18"
+
+// maxMessage bounds one JSON-RPC message from a server.
+const maxMessage = 16 << 20
+
+// stderrTail is how much of a server's stderr is kept for error messages.
+const stderrTail = 2048
+
+// Tool is a tool a server offers.
+type Tool struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description"`
+	InputSchema json.RawMessage `json:"inputSchema"`
+	Annotations struct {
+		ReadOnlyHint bool `json:"readOnlyHint"`
+	} `json:"annotations"`
+}
+
+// Result is the outcome of a tool call: its text content, and whether the
+// server reported the call as failed.
+type Result struct {
+	Text    string
+	IsError bool
+}
+
+// Client is a connection to one running stdio server.
+type Client struct {
+	Server Server
+	// Tools lists the server's tools, as of Start.
+	Tools []Tool
+
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	stderr *tailBuffer
+
+	writeMu sync.Mutex
+	mu      sync.Mutex
+	nextID  int64
+	pending map[int64]chan response
+	done    chan struct{}
+	err     error // why the connection ended, once done is closed
+}
+
+type request struct {
+	JSONRPC string `json:"jsonrpc"`
+	ID      *int64 `json:"id,omitempty"`
+	Method  string `json:"method"`
+	Params  any    `json:"params,omitempty"`
+}
+
+type response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method,omitempty"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *rpcError) Error() string {
+	return fmt.Sprintf("%s (code %d)", e.Message, e.Code)
+}
+
+// Start launches the server in dir, performs the initialize handshake and
+// lists its tools. ctx bounds the start-up only; version is reported to
+// the server as the client's.
+func Start(ctx context.Context, s Server, dir, version string) (*Client, error) {
+	cmd := exec.Command(s.Command, s.Args...)
+	cmd.Dir = dir
+	cmd.Env = os.Environ()
+	for k, v := range s.Env {
+		cmd.Env = append(cmd.Env, k+"="+os.ExpandEnv(v))
+	}
+	c := &Client{Server: s, cmd: cmd, stderr: &tailBuffer{}, pending: map[int64]chan response{}, done: make(chan struct{})}
+	cmd.Stderr = c.stderr
+	var err error
+	if c.stdin, err = cmd.StdinPipe(); err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("start %s: %w", s.Name, err)
+	}
+	go c.readLoop(stdout)
+
+	init := map[string]any{
+		"protocolVersion": ProtocolVersion,
+		"capabilities":    map[string]any{},
+		"clientInfo":      map[string]string{"name": "docsgpt-cli", "version": version},
+	}
+	if _, err := c.call(ctx, "initialize", init); err != nil {
+		c.Close()
+		return nil, c.explain(err)
+	}
+	if err := c.notify("notifications/initialized"); err != nil {
+		c.Close()
+		return nil, c.explain(err)
+	}
+	if err := c.listTools(ctx); err != nil {
+		c.Close()
+		return nil, c.explain(err)
+	}
+	return c, nil
+}
+
+// listTools fetches every page of tools/list.
+func (c *Client) listTools(ctx context.Context) error {
+	cursor := ""
+	for {
+		params := map[string]any{}
+		if cursor != "" {
+			params["cursor"] = cursor
+		}
+		raw, err := c.call(ctx, "tools/list", params)
+		if err != nil {
+			return err
+		}
+		var page struct {
+			Tools      []Tool `json:"tools"`
+			NextCursor string `json:"nextCursor"`
+		}
+		if err := json.Unmarshal(raw, &page); err != nil {
+			return fmt.Errorf("tools/list: %w", err)
+		}
+		c.Tools = append(c.Tools, page.Tools...)
+		if page.NextCursor == "" {
+			return nil
+		}
+		cursor = page.NextCursor
+	}
+}
+
+// CallTool calls a tool of the server with arguments, a JSON object.
+func (c *Client) CallTool(ctx context.Context, name string, arguments json.RawMessage) (Result, error) {
+	if len(arguments) == 0 {
+		arguments = json.RawMessage("{}")
+	}
+	raw, err := c.call(ctx, "tools/call", map[string]any{"name": name, "arguments": arguments})
+	if err != nil {
+		return Result{}, c.explain(err)
+	}
+	var out struct {
+		Content []struct {
+			Type     string `json:"type"`
+			Text     string `json:"text"`
+			MimeType string `json:"mimeType"`
+			URI      string `json:"uri"`
+			Resource struct {
+				URI  string `json:"uri"`
+				Text string `json:"text"`
+			} `json:"resource"`
+		} `json:"content"`
+		StructuredContent json.RawMessage `json:"structuredContent"`
+		IsError           bool            `json:"isError"`
+	}
+	if err := json.Unmarshal(raw, &out); err != nil {
+		return Result{}, fmt.Errorf("tools/call: %w", err)
+	}
+	var parts []string
+	for _, item := range out.Content {
+		switch item.Type {
+		case "text":
+			parts = append(parts, item.Text)
+		case "resource":
+			if item.Resource.Text != "" {
+				parts = append(parts, item.Resource.Text)
+			} else {
+				parts = append(parts, "[resource "+item.Resource.URI+"]")
+			}
+		case "resource_link":
+			parts = append(parts, "[resource "+item.URI+"]")
+		default:
+			// Images and audio cannot be passed on as text.
+			parts = append(parts, fmt.Sprintf("[%s content (%s) omitted]", item.Type, item.MimeType))
+		}
+	}
+	if len(parts) == 0 && len(out.StructuredContent) > 0 {
+		parts = append(parts, string(out.StructuredContent))
+	}
+	return Result{Text: strings.Join(parts, "\n"), IsError: out.IsError}, nil
+}
+
+// Close ends the server: its stdin is closed, which asks it to exit, and it
+// is killed if it is still running a moment later.
+func (c *Client) Close() error {
+	c.stdin.Close()
+	exited := make(chan struct{})
+	go func() {
+		c.cmd.Wait()
+		close(exited)
+	}()
+	select {
+	case <-exited:
+	case <-time.After(2 * time.Second):
+		c.cmd.Process.Kill()
+		<-exited
+	}
+	return nil
+}
+
+// call sends a request and waits for its response.
+func (c *Client) call(ctx context.Context, method string, params any) (json.RawMessage, error) {
+	c.mu.Lock()
+	c.nextID++
+	id := c.nextID
+	ch := make(chan response, 1)
+	c.pending[id] = ch
+	c.mu.Unlock()
+	defer func() {
+		c.mu.Lock()
+		delete(c.pending, id)
+		c.mu.Unlock()
+	}()
+
+	if err := c.send(request{JSONRPC: "2.0", ID: &id, Method: method, Params: params}); err != nil {
+		return nil, err
+	}
+	select {
+	case resp := <-ch:
+		if resp.Error != nil {
+			return nil, fmt.Errorf("%s: %w", method, resp.Error)
+		}
+		return resp.Result, nil
+	case <-c.done:
+		return nil, c.err
+	case <-ctx.Done():
+		// Tell the server to stop working on it.
+		c.send(request{JSONRPC: "2.0", Method: "notifications/cancelled", Params: map[string]any{"requestId": id, "reason": ctx.Err().Error()}})
+		return nil, fmt.Errorf("%s: %w", method, ctx.Err())
+	}
+}
+
+func (c *Client) notify(method string) error {
+	return c.send(request{JSONRPC: "2.0", Method: method})
+}
+
+func (c *Client) send(msg any) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	_, err = c.stdin.Write(append(data, '\n'))
+	return err
+}
+
+// readLoop dispatches the server's messages until its stdout closes:
+// responses to their calls, and answers to the server's own requests.
+func (c *Client) readLoop(stdout io.Reader) {
+	sc := bufio.NewScanner(stdout)
+	sc.Buffer(make([]byte, 64*1024), maxMessage)
+	for sc.Scan() {
+		var msg response
+		if err := json.Unmarshal(sc.Bytes(), &msg); err != nil {
+			// Servers sometimes log to stdout; skip what is not JSON-RPC.
+			continue
+		}
+		var id int64
+		hasID := len(msg.ID) > 0 && json.Unmarshal(msg.ID, &id) == nil
+		switch {
+		case msg.Method != "" && len(msg.ID) > 0:
+			c.answer(msg)
+		case msg.Method == "" && hasID:
+			c.mu.Lock()
+			ch := c.pending[id]
+			c.mu.Unlock()
+			if ch != nil {
+				ch <- msg
+			}
+		}
+		// Notifications (a method without an id) need no reply.
+	}
+	c.err = errors.New("the server exited")
+	if err := sc.Err(); err != nil {
+		c.err = fmt.Errorf("reading from the server: %w", err)
+	}
+	close(c.done)
+}
+
+// answer replies to a request from the server. The client offers no
+// capabilities, so it only answers ping.
+func (c *Client) answer(req response) {
+	reply := map[string]any{"jsonrpc": "2.0", "id": req.ID}
+	if req.Method == "ping" {
+		reply["result"] = map[string]any{}
+	} else {
+		reply["error"] = rpcError{Code: -32601, Message: "method not found: " + req.Method}
+	}
+	c.send(reply)
+}
+
+// explain adds the end of the server's stderr to an error, which is
+// usually where it says what went wrong.
+func (c *Client) explain(err error) error {
+	if tail := strings.TrimSpace(c.stderr.String()); tail != "" {
+		return fmt.Errorf("%w; server stderr: %s", err, tail)
+	}
+	return err
+}
+
+// tailBuffer keeps the last stderrTail bytes written to it.
+type tailBuffer struct {
+	mu  sync.Mutex
+	buf []byte
+}
+
+func (t *tailBuffer) Write(p []byte) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.buf = append(t.buf, p...)
+	if over := len(t.buf) - stderrTail; over > 0 {
+		t.buf = t.buf[over:]
+	}
+	return len(p), nil
+}
+
+func (t *tailBuffer) String() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return string(t.buf)
+}
@@ -0,0 +1,127 @@
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// The test binary doubles as a fake MCP server when started with
+// fakeServerEnv set.
+const fakeServerEnv = "DOCSGPT_FAKE_MCP_SERVER"
+
+func TestMain(m *testing.M) {
+	if os.Getenv(fakeServerEnv) == "1" {
+		runFakeServer()
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+func runFakeServer() {
+	out := json.NewEncoder(os.Stdout)
+	// Some servers log to stdout; the client must skip it.
+	fmt.Println("fake server starting")
+	fmt.Fprintln(os.Stderr, "fake server log line")
+	in := bufio.NewScanner(os.Stdin)
+	for in.Scan() {
+		var msg struct {
+			ID     json.RawMessage `json:"id"`
+			Method string          `json:"method"`
+			Params struct {
+				Cursor    string         `json:"cursor"`
+				Name      string         `json:"name"`
+				Arguments map[string]any `json:"arguments"`
+			} `json:"params"`
+		}
+		json.Unmarshal(in.Bytes(), &msg)
+		reply := func(result any) {
+			out.Encode(map[string]any{"jsonrpc": "2.0", "id": msg.ID, "result": result})
+		}
+		switch msg.Method {
+		case "initialize":
+			reply(map[string]any{"protocolVersion": ProtocolVersion, "capabilities": map[string]any{"tools": map[string]any{}}, "serverInfo": map[string]string{"name": "fake"}})
+		case "tools/list":
+			if msg.Params.Cursor == "" {
+				reply(map[string]any{"tools": []map[string]any{{"name": "echo", "description": "Echo text", "inputSchema": map[string]any{"type": "object"}, "annotations": map[string]any{"readOnlyHint": true}}}, "nextCursor": "2"})
+			} else {
+				reply(map[string]any{"tools": []map[string]any{{"name": "fail"}, {"name": "slow"}}})
+			}
+		case "tools/call":
+			switch msg.Params.Name {
+			case "echo":
+				// A server request in the middle of a call.
+				out.Encode(map[string]any{"jsonrpc": "2.0", "id": "srv-1", "method": "ping"})
+				out.Encode(map[string]any{"jsonrpc": "2.0", "method": "notifications/message", "params": map[string]any{"level": "info"}})
+				reply(map[string]any{"content": []map[string]any{
+					{"type": "text", "text": fmt.Sprint(msg.Params.Arguments["text"])},
+					{"type": "image", "mimeType": "image/png", "data": "AAAA"},
+				}})
+			case "fail":
+				reply(map[string]any{"content": []map[string]any{{"type": "text", "text": "no such issue"}}, "isError": true})
+			case "slow":
+				time.Sleep(10 * time.Second)
+			}
+		}
+	}
+}
+
+func startFake(t *testing.T) *Client {
+	t.Helper()
+	exe, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := Server{Name: "fake", Command: exe, Env: map[string]string{fakeServerEnv: "1"}}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	c, err := Start(ctx, s, t.TempDir(), "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { c.Close() })
+	return c
+}
+
+func TestClient(t *testing.T) {
+	c := startFake(t)
+	var names []string
+	for _, tool := range c.Tools {
+		names = append(names, tool.Name)
+	}
+	if strings.Join(names, ",") != "echo,fail,slow" || !c.Tools[0].Annotations.ReadOnlyHint {
+		t.Fatalf("tools = %+v", c.Tools)
+	}
+
+	ctx := context.Background()
+	res, err := c.CallTool(ctx, "echo", json.RawMessage(`{"text":"hello"}`))
+	if err != nil || res.IsError || res.Text != "hello\n[image content (image/png) omitted]" {
+		t.Errorf("echo = %+v, %v", res, err)
+	}
+	res, err = c.CallTool(ctx, "fail", nil)
+	if err != nil || !res.IsError || res.Text != "no such issue" {
+		t.Errorf("fail = %+v, %v", res, err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 200*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if _, err := c.CallTool(ctx, "slow", nil); err == nil || time.Since(start) > 5*time.Second {
+		t.Errorf("slow call: %v after %v", err, time.Since(start))
+	}
+}
+
+func TestStartFailure(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	s := Server{Name: "broken", Command: "sh", Args: []string{"-c", "echo missing token >&2; exit 1"}}
+	_, err := Start(ctx, s, t.TempDir(), "test")
+	if err == nil || !strings.Contains(err.Error(), "missing token") {
+		t.Errorf("err = %v, want the server's stderr in it", err)
+	}
+}
@@ -0,0 +1,226 @@
+// Package mcp is a Model Context Protocol client for stdio servers: it
+// reads the servers to launch from ~/.docsgpt/mcp.json and the project's
+// .docsgpt/mcp.json, starts them, lists their tools and calls them.
+package mcp
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"docsgpt-cli/internal/config"
+)
+
+// FileName is the server list's name, in ~/.docsgpt and in a project's
+// .docsgpt directory.
+const FileName = "mcp.json"
+
+// Server is how to launch one stdio MCP server. The layout follows the
+// mcpServers files other MCP clients use, so entries can be copied over.
+type Server struct {
+	Command  string            `json:"command"`
+	Args     []string          `json:"args,omitempty"`
+	Env      map[string]string `json:"env,omitempty"`
+	Disabled bool              `json:"disabled,omitempty"`
+	// TrustReadOnly lets the server's own readOnlyHint put its tools in
+	// batches of read-only calls, which share one approval card and run
+	// concurrently. Only the user's own mcp.json can set it.
+	TrustReadOnly bool `json:"trustReadOnly,omitempty"`
+
+	// Name is the server's key in the file.
+	Name string `json:"-"`
+	// Source is the file the server came from.
+	Source string `json:"-"`
+}
+
+// file is the layout of an mcp.json.
+type file struct {
+	Servers map[string]Server `json:"mcpServers"`
+}
+
+// Config is the merged server list for one working directory.
+type Config struct {
+	Servers []Server
+	// Project is the project's mcp.json, or "" if there is none.
+	Project string
+	// ProjectData is its content, which Trusted and Trust key on.
+	ProjectData []byte
+}
+
+// globalPath returns ~/.docsgpt/mcp.json. It is a var so tests can
+// redirect it.
+var globalPath = func() string {
+	return filepath.Join(config.Dir(), FileName)
+}
+
+// Load reads the user's server list and the one of the project containing
+// dir. A project server does not replace a user server of the same name.
+func Load(dir string) (*Config, error) {
+	cfg := &Config{}
+	global, _, err := readFile(globalPath())
+	if err != nil {
+		return nil, err
+	}
+	cfg.Servers = global
+
+	project := FindProject(dir)
+	if project == "" {
+		return cfg, nil
+	}
+	servers, data, err := readFile(project)
+	if err != nil {
+		return nil, err
+	}
+	cfg.Project, cfg.ProjectData = project, data
+	taken := map[string]bool{}
+	for _, s := range global {
+		taken[s.Name] = true
+	}
+	for _, s := range servers {
+		if !taken[s.Name] {
+			s.TrustReadOnly = false
+			cfg.Servers = append(cfg.Servers, s)
+		}
+	}
+	return cfg, nil
+}
+
+// FindProject returns the nearest .docsgpt/mcp.json in dir or one of its
+// parents, or "" if there is none. The user's own ~/.docsgpt is not a
+// project.
+func FindProject(dir string) string {
+	if dir == "" {
+		dir, _ = os.Getwd()
+	}
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return ""
+	}
+	global := globalPath()
+	for {
+		path := filepath.Join(dir, ".docsgpt", FileName)
+		if path != global {
+			if info, err := os.Stat(path); err == nil && !info.IsDir() {
+				return path
+			}
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// readFile returns the enabled servers of an mcp.json by name, and the
+// file's content. A missing file has no servers.
+func readFile(path string) ([]Server, []byte, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	var f file
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	var servers []Server
+	for name, s := range f.Servers {
+		if s.Command == "" {
+			return nil, nil, fmt.Errorf("%s: server %q has no command", path, name)
+		}
+		if s.Disabled {
+			continue
+		}
+		s.Name, s.Source = name, path
+		servers = append(servers, s)
+	}
+	sort.Slice(servers, func(i, j int) bool { return servers[i].Name < servers[j].Name })
+	return servers, data, nil
+}
+
+// trustPath returns ~/.docsgpt/mcp-trust.json, which records the project
+// server lists the user agreed to start. It is a var so tests can redirect
+// it.
+var trustPath = func() string {
+	return filepath.Join(config.Dir(), "mcp-trust.json")
+}
+
+// Trusted reports whether the user agreed to start the servers of the
+// project file at path, as it reads now. Any change to the file needs
+// agreeing to again: a cloned repository must not get to launch programs
+// on its own.
+func Trusted(path string, data []byte) bool {
+	trusted, err := readTrust()
+	return err == nil && trusted[path] == digest(data)
+}
+
+// Trust records that the user agreed to start the servers of the project
+// file at path, as it reads now.
+func Trust(path string, data []byte) error {
+	trusted, err := readTrust()
+	if err != nil {
+		return err
+	}
+	trusted[path] = digest(data)
+	out, err := json.MarshalIndent(trusted, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(trustPath()), 0o700); err != nil {
+		return err
+	}
+	return os.WriteFile(trustPath(), out, 0o600)
+}
+
+func readTrust() (map[string]string, error) {
+	trusted := map[string]string{}
+	data, err := os.ReadFile(trustPath())
+	if errors.Is(err, fs.ErrNotExist) {
+		return trusted, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &trusted); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", trustPath(), err)
+	}
+	return trusted, nil
+}
+
+func digest(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// maxToolName is the longest tool name chat APIs accept.
+const maxToolName = 64
+
+// ToolName is the name a server's tool is offered to the model under:
+// mcp__<server>__<tool>, with characters tool names may not hold replaced
+// by "_".
+func ToolName(server, tool string) string {
+	name := "mcp__" + sanitize(server) + "__" + sanitize(tool)
+	if len(name) > maxToolName {
+		name = name[:maxToolName]
+	}
+	return name
+}
+
+func sanitize(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '_' || r == '-' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' {
+			return r
+		}
+		return '_'
+	}, s)
+}
@@ -0,0 +1,115 @@
+package mcp
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// useConfigs points the user's files into a temporary home, writes global
+// as the user's server list (skipped when empty) and returns a project
+// directory holding project as its list (likewise).
+func useConfigs(t *testing.T, global, project string) string {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	origGlobal, origTrust := globalPath, trustPath
+	globalPath = func() string { return filepath.Join(home, ".docsgpt", FileName) }
+	trustPath = func() string { return filepath.Join(home, ".docsgpt", "mcp-trust.json") }
+	t.Cleanup(func() { globalPath, trustPath = origGlobal, origTrust })
+	if global != "" {
+		writeFile(t, globalPath(), global)
+	}
+	dir := filepath.Join(t.TempDir(), "repo")
+	os.MkdirAll(filepath.Join(dir, "src"), 0o755)
+	if project != "" {
+		writeFile(t, filepath.Join(dir, ".docsgpt", FileName), project)
+	}
+	return dir
+}
+
+func writeFile(t *testing.T, path, data string) {
+	t.Helper()
+	os.MkdirAll(filepath.Dir(path), 0o755)
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	dir := useConfigs(t,
+		`{"mcpServers": {"jira": {"command": "jira-mcp"}, "old": {"command": "x", "disabled": true}}}`,
+		`{"mcpServers": {"jira": {"command": "evil"}, "db": {"command": "db-mcp", "args": ["--ro"]}}}`)
+	cfg, err := Load(filepath.Join(dir, "src"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cfg.Servers) != 2 {
+		t.Fatalf("servers = %+v", cfg.Servers)
+	}
+	if s := cfg.Servers[0]; s.Name != "jira" || s.Command != "jira-mcp" {
+		t.Errorf("a project server replaced the user's: %+v", s)
+	}
+	if s := cfg.Servers[1]; s.Name != "db" || s.Source != cfg.Project || s.Args[0] != "--ro" {
+		t.Errorf("project server = %+v", s)
+	}
+	if cfg.Project != filepath.Join(dir, ".docsgpt", FileName) {
+		t.Errorf("project = %q", cfg.Project)
+	}
+
+	for _, bad := range []string{`{"mcpServers": {"x": {}}}`, `{"mcpServers": [`} {
+		dir := useConfigs(t, bad, "")
+		if _, err := Load(dir); err == nil {
+			t.Errorf("Load should reject %s", bad)
+		}
+	}
+}
+
+func TestLoadTrustReadOnly(t *testing.T) {
+	dir := useConfigs(t,
+		`{"mcpServers": {"docs": {"command": "docs-mcp", "trustReadOnly": true}}}`,
+		`{"mcpServers": {"db": {"command": "db-mcp", "trustReadOnly": true}}}`)
+	cfg, err := Load(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cfg.Servers) != 2 || !cfg.Servers[0].TrustReadOnly || cfg.Servers[1].TrustReadOnly {
+		t.Errorf("only the user's server may be trusted with read-only hints: %+v", cfg.Servers)
+	}
+}
+
+func TestTrust(t *testing.T) {
+	useConfigs(t, "", "")
+	path, data := "/repo/.docsgpt/mcp.json", []byte(`{"mcpServers": {}}`)
+	if Trusted(path, data) {
+		t.Fatal("trusted before Trust")
+	}
+	if err := Trust(path, data); err != nil {
+		t.Fatal(err)
+	}
+	if !Trusted(path, data) {
+		t.Error("not trusted after Trust")
+	}
+	if Trusted(path, []byte(`{"mcpServers": {"x": {"command": "rm"}}}`)) {
+		t.Error("a changed file is still trusted")
+	}
+	if Trusted("/other/.docsgpt/mcp.json", data) {
+		t.Error("trust carried over to another project")
+	}
+}
+
+func TestToolName(t *testing.T) {
+	cases := map[[2]string]string{
+		{"jira", "create_issue"}: "mcp__jira__create_issue",
+		{"my db", "run.query"}:   "mcp__my_db__run_query",
+		{"git-hub", "pr/list"}:   "mcp__git-hub__pr_list",
+	}
+	for in, want := range cases {
+		if got := ToolName(in[0], in[1]); got != want {
+			t.Errorf("ToolName(%q, %q) = %q, want %q", in[0], in[1], got, want)
+		}
+	}
+	if got := ToolName("server", "a_very_long_tool_name_that_keeps_going_and_going_well_past_the_limit"); len(got) != maxToolName {
+		t.Errorf("long name is %d bytes: %q", len(got), got)
+	}
+}
@@ -0,0 +1,52 @@
+package policy
+
+// builtinPolicy is always in force, under the user's and the project's
+// rules. It denies what the old substring blocklist tried to catch, by
+// command rather than by text, so `rm -fr /` is denied and `echo reboot` is
+// not. Its one allow rule lets the tools that only read files run without
+// an approval card; a user's ask or deny rule for them still wins. It is
+// written in the policy.yaml format and doubles as an example.
+const builtinPolicy = `
+rules:
+  - command: rm
+    flags: ["r|R|recursive"]
+    paths: ["/", "/*", "~"]
+    verdict: deny
+    reason: recursive delete of /, a top-level directory or the home directory
+
+  - command: [chmod, chown, chgrp]
+    flags: ["R|recursive"]
+    paths: ["/", "/*"]
+    verdict: deny
+    reason: recursive permission change on / or a top-level directory
+
+  - command: ["mkfs", "mkfs.*", mke2fs, mkswap, wipefs, fdisk, sfdisk, parted]
+    verdict: deny
+    reason: formats or repartitions a disk
+
+  - command: dd
+    args: ["of=/dev/*"]
+    verdict: deny
+    reason: writes directly to a device
+
+  - writes: ["/dev/sd*", "/dev/hd*", "/dev/vd*", "/dev/nvme*", "/dev/mmcblk*", "/dev/disk*"]
+    verdict: deny
+    reason: redirects output onto a disk device
+
+  - command: [shutdown, reboot, halt, poweroff]
+    verdict: deny
+    reason: shuts down or restarts the machine
+
+  - command: [init, telinit]
+    args: ["[06]"]
+    verdict: deny
+    reason: shuts down or restarts the machine
+
+  - line: '(\w+|:)\s*\(\)\s*\{[^}]*\|[^}]*&'
+    verdict: deny
+    reason: fork bomb
+
+  - tool: [read_file, list_dir, glob, grep, file_info]
+    verdict: allow
+    reason: only reads files
+`
@@ -0,0 +1,545 @@
+// Package policy decides whether a shell command may run: allowed outright,
+// run only after the user approves it, or denied. Rules come from built-in
+// defaults, ~/.docsgpt/policy.yaml and the nearest project
+// .docsgpt/policy.yaml, and are matched against every simple command of the
+// line as Parse splits it, so `ls && sudo rm -rf /` is judged by its rm.
+package policy
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"docsgpt-cli/internal/config"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Verdict is what a policy says about a command.
+type Verdict string
+
+const (
+	Allow Verdict = "allow"
+	Ask   Verdict = "ask"
+	Deny  Verdict = "deny"
+)
+
+// rank orders verdicts from least to most restrictive.
+func (v Verdict) rank() int {
+	switch v {
+	case Allow:
+		return 0
+	case Ask:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// FileName is the policy file name, in ~/.docsgpt and in a project's
+// .docsgpt directory.
+const FileName = "policy.yaml"
+
+// Rule matches commands and gives them a verdict. Every criterion that is
+// set must hold for a simple command to match. A rule with Tool set judges
+// calls to other tools instead.
+type Rule struct {
+	// Tool holds globs for the names of tools other than run_command, such
+	// as read_file or mcp__jira__*. A tool rule matches calls to those
+	// tools and never a command.
+	Tool patterns `yaml:"tool"`
+	// Command holds globs for the command name, matched against its base
+	// name (/bin/rm is rm). Empty matches any command.
+	Command patterns `yaml:"command"`
+	// Args are globs that each must match at least one argument.
+	Args []string `yaml:"args"`
+	// Flags are options that each must be present; alternatives are
+	// separated by "|". A single letter is a short option, also inside a
+	// cluster like -rf; a longer name is a --long option.
+	Flags []string `yaml:"flags"`
+	// Paths scopes the rule to commands with an argument inside one of
+	// these paths. "~" is the home directory, a trailing /** takes in
+	// everything below, and relative paths are relative to the command's
+	// working directory.
+	Paths []string `yaml:"paths"`
+	// Writes is like Paths for the targets of output redirections.
+	Writes []string `yaml:"writes"`
+	// Sudo limits the rule to commands run through sudo or doas.
+	Sudo bool `yaml:"sudo"`
+	// Line is a regular expression matched against the whole command line.
+	// A rule with nothing but Line judges the line as a whole.
+	Line    string  `yaml:"line"`
+	Verdict Verdict `yaml:"verdict"`
+	Reason  string  `yaml:"reason"`
+
+	// Source names the file the rule came from.
+	Source string `yaml:"-"`
+	lineRe *regexp.Regexp
+}
+
+// patterns accepts either a single string or a list in YAML.
+type patterns []string
+
+func (p *patterns) UnmarshalYAML(n *yaml.Node) error {
+	if n.Kind == yaml.ScalarNode {
+		*p = patterns{n.Value}
+		return nil
+	}
+	var list []string
+	if err := n.Decode(&list); err != nil {
+		return err
+	}
+	*p = list
+	return nil
+}
+
+// file is the layout of a policy.yaml.
+type file struct {
+	// Default is the verdict for commands no rule matches.
+	Default Verdict `yaml:"default"`
+	Rules   []Rule  `yaml:"rules"`
+}
+
+// Policy is the merged set of rules for one working directory.
+type Policy struct {
+	Default Verdict
+	Rules   []Rule
+	// Files lists the policy files that were read.
+	Files []string
+	// IgnoredAllows counts project rules dropped because a project policy
+	// may only make things stricter.
+	IgnoredAllows int
+}
+
+// Decision is the outcome of Check.
+type Decision struct {
+	Verdict Verdict
+	Reason  string
+	// Rule is the rule that decided, or nil for the default verdict.
+	Rule *Rule
+}
+
+// globalPath returns ~/.docsgpt/policy.yaml. It is a var so tests can
+// redirect it.
+var globalPath = func() string {
+	return filepath.Join(config.Dir(), FileName)
+}
+
+// Load reads the built-in rules, the user's policy and the policy of the
+// project containing dir. A project policy can add deny and ask rules and
+// make the default stricter, but its allow rules are ignored: a cloned
+// repository must not be able to wave its own commands through.
+func Load(dir string) (*Policy, error) {
+	p := &Policy{Default: Ask}
+	builtin, err := parse([]byte(builtinPolicy), "built-in")
+	if err != nil {
+		return nil, err
+	}
+	p.Rules = builtin.Rules
+
+	global := globalPath()
+	if f, err := readFile(global); err != nil {
+		return nil, err
+	} else if f != nil {
+		p.Files = append(p.Files, global)
+		if f.Default != "" {
+			p.Default = f.Default
+		}
+		p.Rules = append(p.Rules, f.Rules...)
+	}
+
+	if project := FindProject(dir); project != "" {
+		f, err := readFile(project)
+		if err != nil {
+			return nil, err
+		}
+		p.Files = append(p.Files, project)
+		if f.Default != "" && f.Default.rank() > p.Default.rank() {
+			p.Default = f.Default
+		}
+		for _, r := range f.Rules {
+			if r.Verdict == Allow {
+				p.IgnoredAllows++
+				continue
+			}
+			p.Rules = append(p.Rules, r)
+		}
+	}
+	return p, nil
+}
+
+// FindProject returns the nearest .docsgpt/policy.yaml in dir or one of its
+// parents, or "" if there is none. The user's own ~/.docsgpt is not a
+// project.
+func FindProject(dir string) string {
+	if dir == "" {
+		dir, _ = os.Getwd()
+	}
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return ""
+	}
+	global := globalPath()
+	for {
+		path := filepath.Join(dir, ".docsgpt", FileName)
+		if path != global {
+			if info, err := os.Stat(path); err == nil && !info.IsDir() {
+				return path
+			}
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+func readFile(path string) (*file, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return parse(data, path)
+}
+
+func parse(data []byte, source string) (*file, error) {
+	var f file
+	if err := yaml.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", source, err)
+	}
+	if f.Default != "" && !f.Default.valid() {
+		return nil, fmt.Errorf("%s: default must be allow, ask or deny, not %q", source, f.Default)
+	}
+	for i := range f.Rules {
+		r := &f.Rules[i]
+		r.Source = source
+		if !r.Verdict.valid() {
+			return nil, fmt.Errorf("%s: rule %d: verdict must be allow, ask or deny, not %q", source, i+1, r.Verdict)
+		}
+		if r.Line != "" {
+			re, err := regexp.Compile(r.Line)
+			if err != nil {
+				return nil, fmt.Errorf("%s: rule %d: line: %w", source, i+1, err)
+			}
+			r.lineRe = re
+		}
+		if r.lineOnly() && r.lineRe == nil {
+			return nil, fmt.Errorf("%s: rule %d matches every command; give it a command, args, flags, paths, writes, sudo or line", source, i+1)
+		}
+		if len(r.Tool) > 0 && !r.toolOnly() {
+			return nil, fmt.Errorf("%s: rule %d: a tool rule cannot have command criteria", source, i+1)
+		}
+	}
+	return &f, nil
+}
+
+func (v Verdict) valid() bool {
+	return v == Allow || v == Ask || v == Deny
+}
+
+// lineOnly reports a rule with no per-command criteria.
+func (r *Rule) lineOnly() bool {
+	return len(r.Tool) == 0 && len(r.Command) == 0 && len(r.Args) == 0 && len(r.Flags) == 0 &&
+		len(r.Paths) == 0 && len(r.Writes) == 0 && !r.Sudo
+}
+
+// Check loads the policy for dir and judges line with it. A policy file
+// that cannot be read or parsed leaves every command to the user: the
+// verdict is Ask, or Deny for a command the built-in rules deny.
+func Check(line, dir string) Decision {
+	p, err := Load(dir)
+	if err != nil {
+		d := builtinOnly().Check(line, dir)
+		if d.Verdict == Deny {
+			return d
+		}
+		return Decision{Verdict: Ask, Reason: "policy not loaded: " + err.Error()}
+	}
+	return p.Check(line, dir)
+}
+
+func builtinOnly() *Policy {
+	f, _ := parse([]byte(builtinPolicy), "built-in")
+	return &Policy{Default: Ask, Rules: f.Rules}
+}
+
+// Check judges a command line run in dir. Each simple command gets the
+// most restrictive verdict among the rules it matches, or the default when
+// none does, and the line gets the most restrictive verdict of its
+// commands. A line that cannot be parsed is at best Ask.
+func (p *Policy) Check(line, dir string) Decision {
+	var best *Decision
+	consider := func(d Decision) {
+		if best == nil || d.Verdict.rank() > best.Verdict.rank() {
+			best = &d
+		}
+	}
+
+	for i := range p.Rules {
+		r := &p.Rules[i]
+		if r.lineOnly() && r.lineRe.MatchString(line) {
+			consider(r.decision(""))
+		}
+	}
+
+	cmds, err := Parse(line)
+	if err != nil {
+		consider(Decision{Verdict: Ask, Reason: "cannot parse the command: " + err.Error()})
+	}
+	for _, c := range cmds {
+		consider(p.checkCommand(c, line, dir))
+	}
+	if best == nil {
+		return Decision{Verdict: p.Default, Reason: "no policy rule matches"}
+	}
+	return *best
+}
+
+func (p *Policy) checkCommand(c Command, line, dir string) Decision {
+	var best *Decision
+	for i := range p.Rules {
+		r := &p.Rules[i]
+		if r.lineOnly() || len(r.Tool) > 0 || !r.matches(c, line, dir) {
+			continue
+		}
+		if best == nil || r.Verdict.rank() > best.Verdict.rank() {
+			d := r.decision(c.Name)
+			best = &d
+		}
+	}
+	if best == nil {
+		name := c.Name
+		if name == "" {
+			name = "redirection"
+		}
+		return Decision{Verdict: p.Default, Reason: "no policy rule matches " + name}
+	}
+	return *best
+}
+
+// toolOnly reports a tool rule with no command criteria.
+func (r *Rule) toolOnly() bool {
+	return len(r.Command) == 0 && len(r.Args) == 0 && len(r.Flags) == 0 &&
+		len(r.Paths) == 0 && len(r.Writes) == 0 && !r.Sudo && r.Line == ""
+}
+
+// CheckTool loads the policy for dir and judges a call to the named tool,
+// as (*Policy).CheckTool does. A policy that cannot be loaded leaves the
+// call to the user.
+func CheckTool(name, dir string) Decision {
+	p, err := Load(dir)
+	if err != nil {
+		return Decision{Verdict: Ask, Reason: "policy not loaded: " + err.Error()}
+	}
+	return p.CheckTool(name)
+}
+
+// CheckTool judges a call to a tool other than run_command by the tool
+// rules: the most restrictive verdict among those that match its name. With
+// no tool rule for it the call is Ask — the default verdict is for
+// commands — so the usual approval applies.
+func (p *Policy) CheckTool(name string) Decision {
+	var best *Decision
+	for i := range p.Rules {
+		r := &p.Rules[i]
+		if !anyMatch(r.Tool, func(pat string) bool { return globMatch(pat, name) }) {
+			continue
+		}
+		if best == nil || r.Verdict.rank() > best.Verdict.rank() {
+			d := r.decision(name)
+			best = &d
+		}
+	}
+	if best == nil {
+		return Decision{Verdict: Ask, Reason: "no policy rule matches tool " + name}
+	}
+	return *best
+}
+
+func (r *Rule) decision(name string) Decision {
+	reason := r.Reason
+	if reason == "" {
+		reason = fmt.Sprintf("%s by a rule in %s", r.Verdict, r.Source)
+		if name != "" {
+			reason = fmt.Sprintf("%s: %s", filepath.Base(name), reason)
+		}
+	}
+	return Decision{Verdict: r.Verdict, Reason: reason, Rule: r}
+}
+
+func (r *Rule) matches(c Command, line, dir string) bool {
+	if r.lineRe != nil && !r.lineRe.MatchString(line) {
+		return false
+	}
+	if r.Sudo && !c.Sudo {
+		return false
+	}
+	if len(r.Command) > 0 {
+		if c.Name == "" {
+			return false
+		}
+		name := filepath.Base(c.Name)
+		ok := false
+		for _, pat := range r.Command {
+			if globMatch(pat, name) {
+				ok = true
+				break
+			}
+		}
+		if !ok {
+			return false
+		}
+	}
+	for _, pat := range r.Args {
+		if !anyMatch(c.Args, func(a string) bool { return globMatch(pat, a) }) {
+			return false
+		}
+	}
+	for _, flag := range r.Flags {
+		if !hasFlag(c.Args, strings.Split(flag, "|")) {
+			return false
+		}
+	}
+	if len(r.Paths) > 0 && !inScope(pathArgs(c.Args), r.Paths, dir) {
+		return false
+	}
+	if len(r.Writes) > 0 && !inScope(c.Writes, r.Writes, dir) {
+		return false
+	}
+	return true
+}
+
+func anyMatch(list []string, f func(string) bool) bool {
+	for _, s := range list {
+		if f(s) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasFlag reports whether args hold one of the option names: a letter as a
+// short option (alone or in a cluster such as -rf), a longer name as
+// --name or --name=value.
+func hasFlag(args []string, names []string) bool {
+	for _, a := range args {
+		if a == "--" {
+			return false
+		}
+		for _, n := range names {
+			switch {
+			case len(n) == 1 && len(a) > 1 && a[0] == '-' && a[1] != '-' && strings.Contains(a[1:], n):
+				return true
+			case len(n) > 1 && (a == "--"+n || strings.HasPrefix(a, "--"+n+"=")):
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// pathArgs returns the arguments that may name files: operands, and the
+// values of --opt=value and key=value arguments (dd's of=/dev/sda).
+func pathArgs(args []string) []string {
+	var out []string
+	for _, a := range args {
+		if strings.HasPrefix(a, "-") {
+			if _, v, ok := strings.Cut(a, "="); ok {
+				out = append(out, v)
+			}
+			continue
+		}
+		if k, v, ok := strings.Cut(a, "="); ok && assignmentRe.MatchString(k+"=") {
+			out = append(out, v)
+			continue
+		}
+		out = append(out, a)
+	}
+	return out
+}
+
+// inScope reports whether any of paths, resolved against dir, lies in one
+// of the scopes.
+func inScope(paths, scopes []string, dir string) bool {
+	for _, p := range paths {
+		if p == "" {
+			continue
+		}
+		abs := resolve(p, dir)
+		for _, s := range scopes {
+			if scopeMatch(s, abs, dir) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func scopeMatch(scope, path, dir string) bool {
+	if base, ok := strings.CutSuffix(scope, "/**"); ok {
+		base = resolve(base+"/", dir)
+		return path == base || base == "/" || strings.HasPrefix(path, base+"/")
+	}
+	ok, _ := filepath.Match(resolve(scope, dir), path)
+	return ok
+}
+
+// resolve makes p absolute and clean, expanding a leading ~, $HOME or
+// ${HOME}.
+func resolve(p, dir string) string {
+	home, _ := os.UserHomeDir()
+	for _, prefix := range []string{"~", "$HOME", "${HOME}"} {
+		if rest, ok := strings.CutPrefix(p, prefix); ok && (rest == "" || rest[0] == '/') {
+			p = home + rest
+			break
+		}
+	}
+	if !filepath.IsAbs(p) {
+		if dir == "" {
+			dir, _ = os.Getwd()
+		}
+		p = filepath.Join(dir, p)
+	}
+	return filepath.Clean(p)
+}
+
+// globMatch matches s against a shell-style glob in which * and ? also
+// match "/", so argument patterns like "origin/*" and "*.env" work on any
+// argument.
+func globMatch(pattern, s string) bool {
+	var b strings.Builder
+	b.WriteString("^")
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; c {
+		case '*':
+			b.WriteString(".*")
+		case '?':
+			b.WriteString(".")
+		case '[':
+			end := strings.IndexByte(pattern[i+1:], ']')
+			if end < 0 {
+				b.WriteString(`\[`)
+				continue
+			}
+			class := pattern[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			b.WriteString("[" + strings.ReplaceAll(class, `\`, `\\`) + "]")
+			i += end + 1
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	b.WriteString("$")
+	re, err := regexp.Compile(b.String())
+	return err == nil && re.MatchString(s)
+}
@@ -0,0 +1,215 @@
+package policy
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// usePolicies points the user's policy at global (skipped when empty) and
+// returns a project directory holding project as its policy (likewise).
+func usePolicies(t *testing.T, global, project string) string {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	path := filepath.Join(home, ".docsgpt", FileName)
+	orig := globalPath
+	globalPath = func() string { return path }
+	t.Cleanup(func() { globalPath = orig })
+	if global != "" {
+		writeFile(t, path, global)
+	}
+
+	dir := filepath.Join(t.TempDir(), "repo")
+	os.MkdirAll(filepath.Join(dir, "src"), 0o755)
+	if project != "" {
+		writeFile(t, filepath.Join(dir, ".docsgpt", FileName), project)
+	}
+	return dir
+}
+
+func writeFile(t *testing.T, path, data string) {
+	t.Helper()
+	os.MkdirAll(filepath.Dir(path), 0o755)
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestBuiltinRules(t *testing.T) {
+	dir := usePolicies(t, "", "")
+	tests := []struct {
+		line string
+		want Verdict
+	}{
+		{"rm -rf /", Deny},
+		{"rm -fr /", Deny},
+		{"rm -r -f /*", Deny},
+		{"rm --recursive --force /usr", Deny},
+		{"rm -rf ~", Deny},
+		{"rm -rf ~/", Deny},
+		{`rm -rf "$HOME"`, Deny},
+		{"rm -rf ../../../../../../../../..", Deny},
+		{"sudo rm -rf /", Deny},
+		{"ls && sudo -E rm -rf / || true", Deny},
+		{"echo $(rm -rf ~)", Deny},
+		{"bash -c 'rm -Rf /'", Deny},
+		{"rm -rf build", Ask},
+		{"rm -rf ~/project/tmp", Ask},
+		{"rm /etc/hosts.bak", Ask},
+		{"mkfs.ext4 /dev/sdb1", Deny},
+		{"dd if=/dev/zero of=/dev/sda bs=1M", Deny},
+		{"dd if=disk.img of=backup.img", Ask},
+		{"cat image > /dev/nvme0n1", Deny},
+		{"make 2>/dev/null", Ask},
+		{"shutdown -h now", Deny},
+		{"sudo reboot", Deny},
+		{"echo reboot", Ask},
+		{"grep -r shutdown .", Ask},
+		{"init 0", Deny},
+		{":(){ :|:& };:", Deny},
+		{"chmod -R 777 /", Deny},
+		{"chmod -R 755 ./bin", Ask},
+		{`echo "unterminated`, Ask},
+	}
+	for _, tt := range tests {
+		if got := Check(tt.line, dir); got.Verdict != tt.want {
+			t.Errorf("Check(%q) = %s (%s), want %s", tt.line, got.Verdict, got.Reason, tt.want)
+		}
+	}
+}
+
+func TestUserAndProjectRules(t *testing.T) {
+	global := `
+default: ask
+rules:
+  - command: [ls, cat, "git"]
+    verdict: allow
+  - command: git
+    args: [push]
+    verdict: ask
+    reason: pushes to a remote
+  - command: git
+    args: [push]
+    flags: ["f|force"]
+    verdict: deny
+  - sudo: true
+    verdict: deny
+    reason: no sudo
+  - command: "*"
+    paths: ["~/.ssh/**"]
+    verdict: deny
+  - command: rm
+    paths: ["./src/**"]
+    verdict: allow
+`
+	project := `
+default: deny
+rules:
+  - command: make
+    verdict: allow
+  - command: cat
+    args: ["*.env"]
+    verdict: deny
+    reason: secrets
+`
+	dir := usePolicies(t, global, project)
+	tests := []struct {
+		line   string
+		want   Verdict
+		reason string
+	}{
+		{"ls -la", Allow, ""},
+		{"git status | cat", Allow, ""},
+		{"git push origin main", Ask, "pushes to a remote"},
+		{"git push --force", Deny, "git: deny by a rule in"},
+		{"git push -fu origin x", Deny, ""},
+		{"sudo ls", Deny, "no sudo"},
+		{"cat ~/.ssh/id_rsa", Deny, ""},
+		{"rm src/old.go", Allow, ""},
+		{"rm ../elsewhere", Deny, "no policy rule matches rm"},
+		// The project's allow rule is ignored, its default tightens.
+		{"make test", Deny, "no policy rule matches make"},
+		{"cat .env", Deny, "secrets"},
+		{"ls && curl x", Deny, ""},
+	}
+	for _, tt := range tests {
+		got := Check(tt.line, dir)
+		if got.Verdict != tt.want || !strings.Contains(got.Reason, tt.reason) {
+			t.Errorf("Check(%q) = %s (%s), want %s (%s)", tt.line, got.Verdict, got.Reason, tt.want, tt.reason)
+		}
+	}
+
+	p, err := Load(filepath.Join(dir, "src"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(p.Files) != 2 || p.IgnoredAllows != 1 || p.Default != Deny {
+		t.Errorf("Load = files %q, ignored %d, default %s", p.Files, p.IgnoredAllows, p.Default)
+	}
+}
+
+func TestInvalidPolicy(t *testing.T) {
+	for _, global := range []string{
+		"rules:\n  - command: ls\n    verdict: maybe\n",
+		"rules:\n  - verdict: allow\n",
+		"rules:\n  - line: '('\n    verdict: deny\n",
+		"default: sometimes\n",
+		"rules:\n  - tool: read_file\n    command: cat\n    verdict: deny\n",
+		"rules: [",
+	} {
+		dir := usePolicies(t, global, "")
+		if _, err := Load(dir); err == nil {
+			t.Errorf("Load should reject %q", global)
+		}
+		// A broken policy file leaves commands to the user, and the
+		// built-in denials still hold.
+		if got := Check("ls", dir); got.Verdict != Ask || !strings.Contains(got.Reason, "policy not loaded") {
+			t.Errorf("Check(ls) with %q = %+v", global, got)
+		}
+		if got := Check("rm -rf /", dir); got.Verdict != Deny {
+			t.Errorf("Check(rm -rf /) with %q = %+v", global, got)
+		}
+	}
+}
+
+func TestToolRules(t *testing.T) {
+	dir := usePolicies(t, `
+default: allow
+rules:
+  - tool: mcp__jira__*
+    verdict: allow
+  - tool: [mcp__jira__delete_*, http_get]
+    verdict: deny
+    reason: not from here
+`, `
+rules:
+  - tool: mcp__jira__create_issue
+    verdict: ask
+  - tool: write_file
+    verdict: allow
+`)
+	tests := []struct {
+		tool string
+		want Verdict
+	}{
+		{"mcp__jira__search", Allow},
+		{"mcp__jira__create_issue", Ask}, // the project tightens
+		{"mcp__jira__delete_issue", Deny},
+		{"http_get", Deny},
+		{"write_file", Ask},     // project allows are ignored
+		{"read_file", Allow},    // built-in
+		{"grep", Allow},         // built-in
+		{"mcp__db__query", Ask}, // the default is for commands only
+	}
+	for _, tc := range tests {
+		if got := CheckTool(tc.tool, dir); got.Verdict != tc.want {
+			t.Errorf("CheckTool(%s) = %+v, want %s", tc.tool, got, tc.want)
+		}
+	}
+	// Tool rules never judge commands.
+	if got := Check("mcp__jira__delete_issue", dir); got.Verdict != Allow {
+		t.Errorf("Check of a command named like a tool = %+v", got)
+	}
+}
@@ -0,0 +1,531 @@
+package policy
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Command is one simple command of a shell command line, after wrappers
+// such as sudo, env or nohup are peeled off.
+type Command struct {
+	Name string
+	Args []string
+	// Sudo is set when the command runs through sudo or doas.
+	Sudo bool
+	// Writes lists the targets of output redirections (> file, >> file).
+	Writes []string
+}
+
+// Parse splits a POSIX shell command line into its simple commands: the
+// parts of pipelines and lists (|, &&, ||, ;, &), subshells, command
+// substitutions ($(...) and backticks), scripts passed to sh -c or eval, and
+// commands run by find -exec or xargs. Words are unquoted but not expanded,
+// so $VAR stays literal. It is a safety analysis, not an interpreter: it
+// errs on the side of reporting more commands.
+func Parse(line string) ([]Command, error) {
+	return parseDepth(line, 0)
+}
+
+// maxParseDepth bounds nested substitutions and sh -c scripts.
+const maxParseDepth = 8
+
+func parseDepth(line string, depth int) ([]Command, error) {
+	if depth > maxParseDepth {
+		return nil, fmt.Errorf("commands nested too deeply")
+	}
+	lx := &lexer{src: []rune(line)}
+	toks, err := lx.tokens()
+	if err != nil {
+		return nil, err
+	}
+
+	var out []Command
+	for _, sub := range lx.subs {
+		cmds, err := parseDepth(sub, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, cmds...)
+	}
+
+	var words, writes []string
+	flush := func() error {
+		cmds, err := simpleCommands(words, writes, depth)
+		if err != nil {
+			return err
+		}
+		out = append(out, cmds...)
+		words, writes = nil, nil
+		return nil
+	}
+	for i := 0; i < len(toks); i++ {
+		t := toks[i]
+		switch t.kind {
+		case tokWord:
+			words = append(words, t.text)
+		case tokRedirect:
+			if i+1 < len(toks) && toks[i+1].kind == tokWord {
+				i++
+				if isOutputRedirect(t.text) && !isFDTarget(toks[i].text) {
+					writes = append(writes, toks[i].text)
+				}
+			}
+		case tokOp:
+			if err := flush(); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if err := flush(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// shellKeywords start compound commands; they are dropped so the command
+// they introduce is analyzed on its own.
+var shellKeywords = map[string]bool{
+	"if": true, "then": true, "else": true, "elif": true, "fi": true,
+	"do": true, "done": true, "while": true, "until": true,
+	"{": true, "}": true, "!": true, "esac": true, "function": true,
+}
+
+var assignmentRe = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*=`)
+
+// simpleCommands turns the words of one simple command into Commands. It
+// usually returns one, but sh -c, eval, find -exec and xargs add the
+// commands they run.
+func simpleCommands(words, writes []string, depth int) ([]Command, error) {
+	for len(words) > 0 && (shellKeywords[words[0]] || assignmentRe.MatchString(words[0])) {
+		words = words[1:]
+	}
+	if len(words) == 0 {
+		if len(writes) > 0 {
+			return []Command{{Writes: writes}}, nil
+		}
+		return nil, nil
+	}
+	switch words[0] {
+	case "for", "case", "select":
+		// Loop and case headers name variables and patterns, not commands.
+		return nil, nil
+	}
+
+	c := Command{Writes: writes}
+	words = unwrap(words, &c.Sudo)
+	if len(words) == 0 {
+		return []Command{c}, nil
+	}
+	c.Name, c.Args = words[0], words[1:]
+	out := []Command{c}
+
+	var inner []string
+	switch base := filepath.Base(c.Name); base {
+	case "sh", "bash", "zsh", "dash", "ksh", "fish":
+		for i, a := range c.Args {
+			if a == "-c" && i+1 < len(c.Args) {
+				inner = append(inner, c.Args[i+1])
+				break
+			}
+		}
+	case "eval":
+		inner = append(inner, strings.Join(c.Args, " "))
+	case "find":
+		for i := 0; i < len(c.Args); i++ {
+			switch c.Args[i] {
+			case "-exec", "-execdir", "-ok", "-okdir":
+				j := i + 1
+				for j < len(c.Args) && c.Args[j] != ";" && c.Args[j] != "+" {
+					j++
+				}
+				if j > i+1 {
+					sub := Command{Name: c.Args[i+1], Args: c.Args[i+2 : j], Sudo: c.Sudo}
+					out = append(out, sub)
+				}
+				i = j
+			}
+		}
+	case "xargs":
+		rest := skipOptions(c.Args, "aEdIiLlnPs")
+		if len(rest) > 0 {
+			sub := Command{Sudo: c.Sudo}
+			rest = unwrap(rest, &sub.Sudo)
+			if len(rest) > 0 {
+				sub.Name, sub.Args = rest[0], rest[1:]
+				out = append(out, sub)
+			}
+		}
+	}
+	for _, script := range inner {
+		cmds, err := parseDepth(script, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		for i := range cmds {
+			cmds[i].Sudo = cmds[i].Sudo || c.Sudo
+		}
+		out = append(out, cmds...)
+	}
+	return out, nil
+}
+
+// unwrap peels off commands that run another command with the same
+// arguments: sudo, doas, env, nohup, nice, time, timeout, command, exec and
+// friends. sudo and doas set *sudo.
+func unwrap(words []string, sudo *bool) []string {
+	for len(words) > 0 {
+		switch filepath.Base(words[0]) {
+		case "sudo":
+			*sudo = true
+			words = skipOptions(words[1:], "CDghpRrTtUu")
+		case "doas":
+			*sudo = true
+			words = skipOptions(words[1:], "Cu")
+		case "env":
+			words = skipOptions(words[1:], "CSu")
+			for len(words) > 0 && assignmentRe.MatchString(words[0]) {
+				words = words[1:]
+			}
+		case "nice":
+			words = skipOptions(words[1:], "n")
+		case "ionice":
+			words = skipOptions(words[1:], "cnp")
+		case "stdbuf":
+			words = skipOptions(words[1:], "eio")
+		case "timeout":
+			words = skipOptions(words[1:], "ks")
+			if len(words) > 0 {
+				words = words[1:] // the duration
+			}
+		case "nohup", "time", "command", "exec", "builtin", "caffeinate", "chronic":
+			words = skipOptions(words[1:], "")
+		default:
+			return words
+		}
+	}
+	return words
+}
+
+// skipOptions drops leading options from args. Short options listed in
+// withValue take a value, either attached (-uroot) or as the next word.
+// "--" ends the options and is dropped too.
+func skipOptions(args []string, withValue string) []string {
+	for len(args) > 0 {
+		a := args[0]
+		if a == "--" {
+			return args[1:]
+		}
+		if len(a) < 2 || a[0] != '-' {
+			return args
+		}
+		args = args[1:]
+		if a[1] == '-' {
+			continue // --long or --long=value
+		}
+		if len(a) == 2 && strings.ContainsRune(withValue, rune(a[1])) && len(args) > 0 {
+			args = args[1:]
+		}
+	}
+	return args
+}
+
+func isOutputRedirect(op string) bool {
+	return strings.ContainsRune(op, '>') && !strings.HasSuffix(op, ">&")
+}
+
+// isFDTarget reports redirect targets that name a file descriptor (2>&1,
+// >&-), not a file.
+func isFDTarget(s string) bool {
+	if s == "-" {
+		return true
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return s != ""
+}
+
+type tokKind int
+
+const (
+	tokWord tokKind = iota
+	tokOp
+	tokRedirect
+)
+
+type token struct {
+	kind tokKind
+	text string
+}
+
+// lexer splits a command line into words, operators and redirections. The
+// bodies of command substitutions are collected in subs for a separate
+// parse.
+type lexer struct {
+	src  []rune
+	pos  int
+	subs []string
+	// heredocs are the delimiters of here-documents whose bodies start on
+	// the next line.
+	heredocs []string
+}
+
+func (lx *lexer) peek(off int) rune {
+	if lx.pos+off < len(lx.src) {
+		return lx.src[lx.pos+off]
+	}
+	return 0
+}
+
+func (lx *lexer) tokens() ([]token, error) {
+	var toks []token
+	for lx.pos < len(lx.src) {
+		r := lx.src[lx.pos]
+		switch {
+		case r == '\n':
+			lx.pos++
+			toks = append(toks, token{tokOp, "\n"})
+			lx.skipHeredocs()
+		case r == ' ' || r == '\t' || r == '\r':
+			lx.pos++
+		case r == '\\' && lx.peek(1) == '\n':
+			lx.pos += 2 // line continuation
+		case r == '#':
+			for lx.pos < len(lx.src) && lx.src[lx.pos] != '\n' {
+				lx.pos++
+			}
+		case strings.ContainsRune("|&;()", r):
+			toks = append(toks, token{tokOp, lx.operator()})
+		case r == '<' || r == '>':
+			op := lx.redirect("")
+			toks = append(toks, token{tokRedirect, op})
+			if op == "<<" || op == "<<-" {
+				if err := lx.heredocDelimiter(&toks); err != nil {
+					return nil, err
+				}
+			}
+		default:
+			word, err := lx.word()
+			if err != nil {
+				return nil, err
+			}
+			// A file descriptor number glued to a redirection: 2>file.
+			if isFDTarget(word) && (lx.peek(0) == '<' || lx.peek(0) == '>') {
+				toks = append(toks, token{tokRedirect, lx.redirect(word)})
+				continue
+			}
+			toks = append(toks, token{tokWord, word})
+		}
+	}
+	return toks, nil
+}
+
+func (lx *lexer) operator() string {
+	r := lx.src[lx.pos]
+	lx.pos++
+	if (r == '|' || r == '&' || r == ';') && lx.peek(0) == r {
+		lx.pos++
+		return string([]rune{r, r})
+	}
+	if r == '|' && lx.peek(0) == '&' {
+		lx.pos++
+		return "|&"
+	}
+	if r == '&' && lx.peek(0) == '>' {
+		return lx.redirect("&")
+	}
+	return string(r)
+}
+
+// redirect reads a redirection operator such as >, >>, >|, <, <<, <<-, <<<,
+// >&, <& or <>, with prefix (an fd number or &) already consumed.
+func (lx *lexer) redirect(prefix string) string {
+	start := lx.pos
+	lx.pos++
+	switch lx.peek(0) {
+	case '>', '|', '&':
+		if lx.src[start] == '>' || lx.peek(0) == '&' {
+			lx.pos++
+		}
+	case '<':
+		if lx.src[start] == '<' {
+			lx.pos++
+			if lx.peek(0) == '<' || lx.peek(0) == '-' {
+				lx.pos++
+			}
+		} else {
+			lx.pos++
+		}
+	}
+	return prefix + string(lx.src[start:lx.pos])
+}
+
+// heredocDelimiter reads the word after << and remembers it, so the
+// here-document body (data, not commands) is skipped at the next newline.
+func (lx *lexer) heredocDelimiter(toks *[]token) error {
+	for lx.pos < len(lx.src) && (lx.src[lx.pos] == ' ' || lx.src[lx.pos] == '\t') {
+		lx.pos++
+	}
+	word, err := lx.word()
+	if err != nil {
+		return err
+	}
+	*toks = append(*toks, token{tokWord, word})
+	lx.heredocs = append(lx.heredocs, word)
+	return nil
+}
+
+func (lx *lexer) skipHeredocs() {
+	for _, delim := range lx.heredocs {
+		for lx.pos < len(lx.src) {
+			end := lx.pos
+			for end < len(lx.src) && lx.src[end] != '\n' {
+				end++
+			}
+			line := strings.TrimLeft(string(lx.src[lx.pos:end]), "\t")
+			lx.pos = min(end+1, len(lx.src))
+			if line == delim {
+				break
+			}
+		}
+	}
+	lx.heredocs = nil
+}
+
+// word reads one shell word, removing quotes and escapes. Substitutions
+// are kept in the word as written and their bodies queued in subs.
+func (lx *lexer) word() (string, error) {
+	var b strings.Builder
+	for lx.pos < len(lx.src) {
+		r := lx.src[lx.pos]
+		switch {
+		case r == ' ' || r == '\t' || r == '\n' || r == '\r' || strings.ContainsRune("|&;()<>", r):
+			return b.String(), nil
+		case r == '\\':
+			if lx.pos+1 < len(lx.src) {
+				b.WriteRune(lx.src[lx.pos+1])
+			}
+			lx.pos += 2
+		case r == '\'':
+			end := lx.find(lx.pos+1, '\'')
+			if end < 0 {
+				return "", fmt.Errorf("unterminated single quote")
+			}
+			b.WriteString(string(lx.src[lx.pos+1 : end]))
+			lx.pos = end + 1
+		case r == '"':
+			if err := lx.doubleQuoted(&b); err != nil {
+				return "", err
+			}
+		case r == '`':
+			if err := lx.backtick(&b); err != nil {
+				return "", err
+			}
+		case r == '$' && (lx.peek(1) == '(' || lx.peek(1) == '{'):
+			if err := lx.dollar(&b); err != nil {
+				return "", err
+			}
+		default:
+			b.WriteRune(r)
+			lx.pos++
+		}
+	}
+	return b.String(), nil
+}
+
+func (lx *lexer) doubleQuoted(b *strings.Builder) error {
+	lx.pos++ // opening quote
+	for lx.pos < len(lx.src) {
+		r := lx.src[lx.pos]
+		switch {
+		case r == '"':
+			lx.pos++
+			return nil
+		case r == '\\' && strings.ContainsRune("$`\"\\\n", lx.peek(1)):
+			b.WriteRune(lx.src[lx.pos+1])
+			lx.pos += 2
+		case r == '`':
+			if err := lx.backtick(b); err != nil {
+				return err
+			}
+		case r == '$' && (lx.peek(1) == '(' || lx.peek(1) == '{'):
+			if err := lx.dollar(b); err != nil {
+				return err
+			}
+		default:
+			b.WriteRune(r)
+			lx.pos++
+		}
+	}
+	return fmt.Errorf("unterminated double quote")
+}
+
+func (lx *lexer) backtick(b *strings.Builder) error {
+	var body strings.Builder
+	for i := lx.pos + 1; i < len(lx.src); i++ {
+		switch lx.src[i] {
+		case '\\':
+			if i+1 < len(lx.src) {
+				i++
+				body.WriteRune(lx.src[i])
+			}
+		case '`':
+			lx.subs = append(lx.subs, body.String())
+			b.WriteString(string(lx.src[lx.pos : i+1]))
+			lx.pos = i + 1
+			return nil
+		default:
+			body.WriteRune(lx.src[i])
+		}
+	}
+	return fmt.Errorf("unterminated backquote")
+}
+
+// dollar reads $(...), $((...)) or ${...} with nesting and quotes taken
+// into account. Command substitution bodies are queued for parsing.
+func (lx *lexer) dollar(b *strings.Builder) error {
+	open := lx.src[lx.pos+1]
+	close := ')'
+	if open == '{' {
+		close = '}'
+	}
+	depth := 0
+	for i := lx.pos + 1; i < len(lx.src); i++ {
+		switch r := lx.src[i]; r {
+		case '\\':
+			i++
+		case '\'':
+			end := lx.find(i+1, '\'')
+			if end < 0 {
+				return fmt.Errorf("unterminated single quote")
+			}
+			i = end
+		case open:
+			depth++
+		case close:
+			depth--
+			if depth == 0 {
+				if open == '(' {
+					lx.subs = append(lx.subs, string(lx.src[lx.pos+2:i]))
+				}
+				b.WriteString(string(lx.src[lx.pos : i+1]))
+				lx.pos = i + 1
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf("unterminated $%c", open)
+}
+
+func (lx *lexer) find(from int, r rune) int {
+	for i := from; i < len(lx.src); i++ {
+		if lx.src[i] == r {
+			return i
+		}
+	}
+	return -1
+}
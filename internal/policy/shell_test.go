@@ -0,0 +1,69 @@
+package policy
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// names renders commands as "name arg..." with a sudo: prefix, and
+// redirect targets as ">target".
+func names(cmds []Command) []string {
+	var out []string
+	for _, c := range cmds {
+		s := strings.TrimSpace(strings.Join(append([]string{c.Name}, c.Args...), " "))
+		if c.Sudo {
+			s = "sudo:" + s
+		}
+		for _, w := range c.Writes {
+			s += " >" + w
+		}
+		out = append(out, s)
+	}
+	return out
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		line string
+		want []string
+	}{
+		{"ls -la", []string{"ls -la"}},
+		{"echo reboot", []string{"echo reboot"}},
+		{"cat a | grep b && rm -rf x; echo done &", []string{"cat a", "grep b", "rm -rf x", "echo done"}},
+		{"false || (cd /tmp; make)", []string{"false", "cd /tmp", "make"}},
+		{"sudo -u root rm -rf /", []string{"sudo:rm -rf /"}},
+		{"FOO=1 env -i BAR=2 nohup nice -n 5 ./run.sh", []string{"./run.sh"}},
+		{"timeout 10 curl x", []string{"curl x"}},
+		{`echo "a b" 'c|d' e\ f`, []string{"echo a b c|d e f"}},
+		{"echo $(whoami) `id -u`", []string{"whoami", "id -u", "echo $(whoami) `id -u`"}},
+		{`echo "$(rm -rf ~)"`, []string{"rm -rf ~", "echo $(rm -rf ~)"}},
+		{`bash -c "shutdown now"`, []string{"bash -c shutdown now", "shutdown now"}},
+		{`sudo sh -c 'cat x > /dev/sda'`, []string{"sudo:sh -c cat x > /dev/sda", "sudo:cat x >/dev/sda"}},
+		{"find . -name '*.o' -exec rm -f {} +", []string{"find . -name *.o -exec rm -f {} +", "rm -f {}"}},
+		{"ls | xargs -n1 sudo reboot", []string{"ls", "xargs -n1 sudo reboot", "sudo:reboot"}},
+		{"go test ./... 2>&1 >out.log", []string{"go test ./... >out.log"}},
+		{"if [ -f x ]; then rm x; fi", []string{"[ -f x ]", "rm x"}},
+		{"for f in *.go; do gofmt -l $f; done", []string{"gofmt -l $f"}},
+		{"cat <<EOF > notes\nreboot\nEOF\nls", []string{"cat >notes", "ls"}},
+		{"echo hi # && reboot", []string{"echo hi"}},
+	}
+	for _, tt := range tests {
+		cmds, err := Parse(tt.line)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", tt.line, err)
+			continue
+		}
+		if got := names(cmds); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Parse(%q) = %q, want %q", tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, line := range []string{`echo "oops`, `echo 'oops`, "echo $(ls", "echo `ls"} {
+		if _, err := Parse(line); err == nil {
+			t.Errorf("Parse(%q) should fail", line)
+		}
+	}
+}
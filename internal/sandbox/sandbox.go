@@ -0,0 +1,130 @@
+// Package sandbox confines tool commands on Linux: the project directory
+// (and any extra paths) stays read-write, the rest of the filesystem is
+// read-only, and the network is off unless asked for. It uses bubblewrap
+// when it is installed and works, and otherwise Landlock, applied by
+// re-executing this binary as a small launcher (see RunHelperIfRequested).
+package sandbox
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Options configures one sandboxed command.
+type Options struct {
+	// Writable lists the directories the command may change; everything
+	// else is read-only.
+	Writable []string
+	// Network leaves network access on.
+	Network bool
+}
+
+// ParseMode parses a sandbox setting: "off" or "" (no sandbox), "on"
+// (sandbox without network) or "network" (sandbox with network access).
+func ParseMode(s string) (enabled, network bool, err error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "", "off":
+		return false, false, nil
+	case "on":
+		return true, false, nil
+	case "network":
+		return true, true, nil
+	}
+	return false, false, fmt.Errorf("invalid sandbox mode %q (use on, network or off)", s)
+}
+
+// helperArg, as the first argument, makes this binary the Landlock
+// launcher instead of the CLI.
+const helperArg = "__sandbox-exec"
+
+// RunHelperIfRequested turns the process into the sandbox launcher when it
+// was started as one, and never returns in that case. main calls it first.
+func RunHelperIfRequested() {
+	if len(os.Args) > 1 && os.Args[1] == helperArg {
+		os.Exit(runHelper(os.Args[2:]))
+	}
+}
+
+// CheckRoot refuses a writable root so broad that the sandbox would
+// protect next to nothing: the filesystem root or the home directory.
+func CheckRoot(dir string) error {
+	real := realPath(dir)
+	if filepath.Dir(real) == real {
+		return fmt.Errorf("refusing %s as the sandbox's writable root: it is the filesystem root", dir)
+	}
+	if home, err := os.UserHomeDir(); err == nil && real == realPath(home) {
+		return fmt.Errorf("refusing %s as the sandbox's writable root: it is the home directory", dir)
+	}
+	return nil
+}
+
+// CanWrite reports whether path lies below one of the writable
+// directories. Tools that write files in-process (write_file, edit_file)
+// check it, since the sandbox only confines child processes.
+func (o Options) CanWrite(path string) bool {
+	real := realPath(path)
+	for _, dir := range writableDirs(o.Writable) {
+		rel, err := filepath.Rel(dir, real)
+		if err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+// realPath resolves symlinks in the longest existing prefix of path, so a
+// file that does not exist yet is judged by the directory it would land in.
+func realPath(path string) string {
+	abs, err := filepath.Abs(expandHome(path))
+	if err != nil {
+		return path
+	}
+	rest := ""
+	for dir := abs; ; dir = filepath.Dir(dir) {
+		if real, err := filepath.EvalSymlinks(dir); err == nil {
+			return filepath.Join(real, rest)
+		}
+		if filepath.Dir(dir) == dir {
+			return abs
+		}
+		rest = filepath.Join(filepath.Base(dir), rest)
+	}
+}
+
+// expandHome expands a leading "~/", as config paths are written.
+func expandHome(p string) string {
+	if rest, ok := strings.CutPrefix(p, "~/"); ok {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, rest)
+		}
+	}
+	return p
+}
+
+// writableDirs cleans the writable paths, resolving symlinks so rules
+// apply to the real directories, and drops ones that do not exist.
+func writableDirs(paths []string) []string {
+	var out []string
+	seen := map[string]bool{}
+	for _, p := range paths {
+		if p == "" {
+			continue
+		}
+		abs, err := filepath.Abs(expandHome(p))
+		if err != nil {
+			continue
+		}
+		if real, err := filepath.EvalSymlinks(abs); err == nil {
+			abs = real
+		} else {
+			continue
+		}
+		if !seen[abs] {
+			seen[abs] = true
+			out = append(out, abs)
+		}
+	}
+	return out
+}
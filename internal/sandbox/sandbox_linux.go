@@ -0,0 +1,245 @@
+package sandbox
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"sync"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// backend is chosen once per process: "bwrap" or "landlock".
+var backend struct {
+	once sync.Once
+	name string
+	// netns reports whether a network namespace can be created for the
+	// Landlock launcher.
+	netns bool
+	abi   int
+	err   error
+}
+
+// Backend returns the sandbox mechanism in use, or why there is none.
+func Backend() (string, error) {
+	backend.once.Do(detect)
+	return backend.name, backend.err
+}
+
+func detect() {
+	self, err := os.Executable()
+	if err != nil {
+		backend.err = fmt.Errorf("sandbox: %w", err)
+		return
+	}
+	if bwrap, err := exec.LookPath("bwrap"); err == nil {
+		probe := exec.Command(bwrap, "--ro-bind", "/", "/", "--dev", "/dev", "--unshare-net", "--", self, helperArg, "--probe")
+		if probe.Run() == nil {
+			backend.name = "bwrap"
+			return
+		}
+	}
+	abi, _, errno := unix.Syscall(unix.SYS_LANDLOCK_CREATE_RULESET, 0, 0, unix.LANDLOCK_CREATE_RULESET_VERSION)
+	if errno != 0 {
+		backend.err = fmt.Errorf("sandbox: neither a working bwrap nor Landlock is available (%v)", errno)
+		return
+	}
+	backend.name, backend.abi = "landlock", int(abi)
+	probe := exec.Command(self, helperArg, "--probe")
+	probe.SysProcAttr = &syscall.SysProcAttr{}
+	isolateNetwork(probe.SysProcAttr)
+	backend.netns = probe.Run() == nil
+}
+
+// isolateNetwork gives the process a network namespace of its own, with
+// only a loopback interface that is down. Without root that needs a user
+// namespace too, mapping the user to itself.
+func isolateNetwork(attr *syscall.SysProcAttr) {
+	attr.Cloneflags |= syscall.CLONE_NEWNET
+	if uid := os.Getuid(); uid != 0 {
+		attr.Cloneflags |= syscall.CLONE_NEWUSER
+		attr.UidMappings = []syscall.SysProcIDMap{{ContainerID: uid, HostID: uid, Size: 1}}
+		attr.GidMappings = []syscall.SysProcIDMap{{ContainerID: os.Getgid(), HostID: os.Getgid(), Size: 1}}
+	}
+}
+
+// Wrap rewrites cmd, which has not been started, to run inside the
+// sandbox. It fails rather than leave the command unconfined.
+func Wrap(cmd *exec.Cmd, opts Options) error {
+	name, err := Backend()
+	if err != nil {
+		return err
+	}
+	writable := writableDirs(opts.Writable)
+	inner := append([]string{cmd.Path}, cmd.Args[1:]...)
+
+	if name == "bwrap" {
+		args := []string{"bwrap", "--die-with-parent", "--unshare-pid",
+			"--ro-bind", "/", "/", "--dev", "/dev", "--proc", "/proc", "--tmpfs", "/tmp"}
+		for _, w := range writable {
+			args = append(args, "--bind", w, w)
+		}
+		if !opts.Network {
+			args = append(args, "--unshare-net")
+		}
+		if cmd.Dir != "" {
+			args = append(args, "--chdir", cmd.Dir)
+		}
+		cmd.Path, _ = exec.LookPath("bwrap")
+		cmd.Args = append(append(args, "--"), inner...)
+		return nil
+	}
+
+	self, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	// Landlock cannot hide /tmp behind a private tmpfs, so it stays
+	// writable as it is.
+	args := []string{self, helperArg}
+	for _, w := range writableDirs(append(writable, os.TempDir())) {
+		args = append(args, "--rw", w)
+	}
+	if !opts.Network {
+		switch {
+		case backend.netns:
+			if cmd.SysProcAttr == nil {
+				cmd.SysProcAttr = &syscall.SysProcAttr{}
+			}
+			isolateNetwork(cmd.SysProcAttr)
+		case backend.abi >= 4:
+			// No namespaces: Landlock can still refuse TCP.
+			args = append(args, "--no-tcp")
+		default:
+			return errors.New("sandbox: cannot turn the network off here (no network namespaces, Landlock older than ABI 4); use --sandbox=network to allow it")
+		}
+	}
+	cmd.Path = self
+	cmd.Args = append(append(args, "--"), inner...)
+	return nil
+}
+
+// runHelper is the Landlock launcher: it restricts itself and then execs
+// the command, which inherits the restrictions.
+func runHelper(args []string) int {
+	var writable []string
+	noTCP := false
+	for len(args) > 0 {
+		a := args[0]
+		args = args[1:]
+		switch {
+		case a == "--probe":
+			return 0
+		case a == "--rw" && len(args) > 0:
+			writable = append(writable, args[0])
+			args = args[1:]
+		case a == "--no-tcp":
+			noTCP = true
+		case a == "--":
+			if len(args) == 0 {
+				break
+			}
+			// Landlock and no_new_privs apply to the calling thread, which
+			// must be the one that execs.
+			runtime.LockOSThread()
+			if err := restrict(writable, noTCP); err != nil {
+				fmt.Fprintln(os.Stderr, "sandbox:", err)
+				return 126
+			}
+			err := syscall.Exec(args[0], args, os.Environ())
+			fmt.Fprintln(os.Stderr, "sandbox:", err)
+			return 127
+		}
+	}
+	fmt.Fprintln(os.Stderr, "sandbox: no command given")
+	return 2
+}
+
+const (
+	llRead = unix.LANDLOCK_ACCESS_FS_EXECUTE | unix.LANDLOCK_ACCESS_FS_READ_FILE | unix.LANDLOCK_ACCESS_FS_READ_DIR
+	// llWrite is every right that changes the filesystem in Landlock ABI 1.
+	llWrite = unix.LANDLOCK_ACCESS_FS_WRITE_FILE | unix.LANDLOCK_ACCESS_FS_REMOVE_DIR |
+		unix.LANDLOCK_ACCESS_FS_REMOVE_FILE | unix.LANDLOCK_ACCESS_FS_MAKE_CHAR |
+		unix.LANDLOCK_ACCESS_FS_MAKE_DIR | unix.LANDLOCK_ACCESS_FS_MAKE_REG |
+		unix.LANDLOCK_ACCESS_FS_MAKE_SOCK | unix.LANDLOCK_ACCESS_FS_MAKE_FIFO |
+		unix.LANDLOCK_ACCESS_FS_MAKE_BLOCK | unix.LANDLOCK_ACCESS_FS_MAKE_SYM
+)
+
+// devices stay writable inside the sandbox; shells and build tools expect
+// to write to them.
+var devices = []string{"/dev/null", "/dev/zero", "/dev/full", "/dev/random", "/dev/urandom", "/dev/tty"}
+
+// restrict applies a Landlock ruleset to the calling thread: read and
+// execute everywhere, write only below writable and to a few devices, and
+// with noTCP no TCP bind or connect.
+func restrict(writable []string, noTCP bool) error {
+	abi, _, errno := unix.Syscall(unix.SYS_LANDLOCK_CREATE_RULESET, 0, 0, unix.LANDLOCK_CREATE_RULESET_VERSION)
+	if errno != 0 {
+		return fmt.Errorf("landlock: %w", errno)
+	}
+	write := uint64(llWrite)
+	fileWrite := uint64(unix.LANDLOCK_ACCESS_FS_WRITE_FILE)
+	if abi >= 2 {
+		write |= unix.LANDLOCK_ACCESS_FS_REFER
+	}
+	if abi >= 3 {
+		write |= unix.LANDLOCK_ACCESS_FS_TRUNCATE
+		fileWrite |= unix.LANDLOCK_ACCESS_FS_TRUNCATE
+	}
+
+	attr := unix.LandlockRulesetAttr{Access_fs: llRead | write}
+	size := unsafe.Sizeof(attr.Access_fs)
+	if noTCP {
+		if abi < 4 {
+			return errors.New("landlock: this kernel cannot restrict TCP")
+		}
+		attr.Access_net = unix.LANDLOCK_ACCESS_NET_BIND_TCP | unix.LANDLOCK_ACCESS_NET_CONNECT_TCP
+		size += unsafe.Sizeof(attr.Access_net)
+	}
+	fd, _, errno := unix.Syscall(unix.SYS_LANDLOCK_CREATE_RULESET, uintptr(unsafe.Pointer(&attr)), size, 0)
+	if errno != 0 {
+		return fmt.Errorf("landlock: create ruleset: %w", errno)
+	}
+	defer unix.Close(int(fd))
+
+	if err := allow(int(fd), "/", llRead); err != nil {
+		return err
+	}
+	for _, w := range writable {
+		if err := allow(int(fd), w, llRead|write); err != nil {
+			return err
+		}
+	}
+	for _, d := range devices {
+		// Devices that are missing or cannot be opened are skipped.
+		_ = allow(int(fd), d, unix.LANDLOCK_ACCESS_FS_READ_FILE|fileWrite)
+	}
+
+	if err := unix.Prctl(unix.PR_SET_NO_NEW_PRIVS, 1, 0, 0, 0); err != nil {
+		return fmt.Errorf("no_new_privs: %w", err)
+	}
+	if _, _, errno := unix.Syscall(unix.SYS_LANDLOCK_RESTRICT_SELF, fd, 0, 0); errno != 0 {
+		return fmt.Errorf("landlock: restrict: %w", errno)
+	}
+	return nil
+}
+
+// allow adds a rule granting access below path.
+func allow(ruleset int, path string, access uint64) error {
+	fd, err := unix.Open(path, unix.O_PATH|unix.O_CLOEXEC, 0)
+	if err != nil {
+		return fmt.Errorf("landlock: %s: %w", path, err)
+	}
+	defer unix.Close(fd)
+	rule := unix.LandlockPathBeneathAttr{Allowed_access: access, Parent_fd: int32(fd)}
+	_, _, errno := unix.Syscall6(unix.SYS_LANDLOCK_ADD_RULE, uintptr(ruleset), unix.LANDLOCK_RULE_PATH_BENEATH,
+		uintptr(unsafe.Pointer(&rule)), 0, 0, 0)
+	if errno != 0 {
+		return fmt.Errorf("landlock: %s: %w", path, errno)
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package sandbox
+
+import (
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// The Landlock launcher re-executes the current binary, which in tests is
+// the test binary.
+func TestMain(m *testing.M) {
+	RunHelperIfRequested()
+	os.Exit(m.Run())
+}
+
+func run(t *testing.T, opts Options, script string) (string, error) {
+	t.Helper()
+	cmd := exec.Command("sh", "-c", script)
+	if err := Wrap(cmd, opts); err != nil {
+		t.Fatal(err)
+	}
+	out, err := cmd.CombinedOutput()
+	return string(out), err
+}
+
+func TestSandboxFilesystem(t *testing.T) {
+	if _, err := Backend(); err != nil {
+		t.Skip(err)
+	}
+	project := t.TempDir()
+	outside := t.TempDir()
+	os.WriteFile(filepath.Join(outside, "keep"), []byte("original"), 0o644)
+	opts := Options{Writable: []string{project}}
+
+	if out, err := run(t, opts, "echo built > "+filepath.Join(project, "out")+" && mkdir "+filepath.Join(project, "sub")); err != nil {
+		t.Fatalf("write inside the project failed: %v\n%s", err, out)
+	}
+	if data, _ := os.ReadFile(filepath.Join(project, "out")); string(data) != "built\n" {
+		t.Errorf("project file = %q", data)
+	}
+	if out, err := run(t, opts, "cat "+filepath.Join(outside, "keep")); err != nil || out != "original" {
+		t.Errorf("read outside the project: %q, %v", out, err)
+	}
+
+	home, _ := os.UserHomeDir()
+	for _, script := range []string{
+		"echo x > " + filepath.Join(home, ".docsgpt-sandbox-test"),
+		"echo x > /etc/docsgpt-sandbox-test",
+		"echo x > /dev/null && touch /usr/docsgpt-sandbox-test",
+	} {
+		if out, err := run(t, opts, script); err == nil {
+			t.Errorf("%q succeeded in the sandbox: %s", script, out)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(home, ".docsgpt-sandbox-test")); err == nil {
+		os.Remove(filepath.Join(home, ".docsgpt-sandbox-test"))
+		t.Error("the sandbox wrote to the home directory")
+	}
+}
+
+func TestSandboxNetwork(t *testing.T) {
+	if _, err := Backend(); err != nil {
+		t.Skip(err)
+	}
+	if _, err := exec.LookPath("bash"); err != nil {
+		t.Skip("needs bash for /dev/tcp")
+	}
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skip(err)
+	}
+	defer ln.Close()
+	go func() {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			c.Write([]byte("hello\n"))
+			c.Close()
+		}
+	}()
+	port := ln.Addr().(*net.TCPAddr).Port
+	script := "exec bash -c 'exec 3<>/dev/tcp/127.0.0.1/" + strconv.Itoa(port) + " && head -n1 <&3'"
+
+	if out, err := run(t, Options{}, script); err == nil {
+		t.Errorf("connect succeeded with the network off: %q", out)
+	}
+	if out, err := run(t, Options{Network: true}, script); err != nil || strings.TrimSpace(out) != "hello" {
+		t.Errorf("connect with the network on: %q, %v", out, err)
+	}
+}
@@ -0,0 +1,25 @@
+//go:build !linux
+
+package sandbox
+
+import (
+	"errors"
+	"os/exec"
+)
+
+var errUnsupported = errors.New("sandbox: only available on Linux")
+
+// Backend returns the sandbox mechanism in use, or why there is none.
+func Backend() (string, error) {
+	return "", errUnsupported
+}
+
+// Wrap rewrites cmd to run inside the sandbox. Outside Linux it always
+// fails, so the command is not run unconfined.
+func Wrap(cmd *exec.Cmd, opts Options) error {
+	return errUnsupported
+}
+
+func runHelper(args []string) int {
+	return 2
+}
@@ -0,0 +1,71 @@
+package sandbox
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseMode(t *testing.T) {
+	cases := []struct {
+		in               string
+		enabled, network bool
+		wantErr          bool
+	}{
+		{"", false, false, false},
+		{"off", false, false, false},
+		{"on", true, false, false},
+		{" ON ", true, false, false},
+		{"network", true, true, false},
+		{"yes", false, false, true},
+	}
+	for _, tc := range cases {
+		enabled, network, err := ParseMode(tc.in)
+		if enabled != tc.enabled || network != tc.network || (err != nil) != tc.wantErr {
+			t.Errorf("ParseMode(%q) = %v, %v, %v", tc.in, enabled, network, err)
+		}
+	}
+}
+
+func TestCanWrite(t *testing.T) {
+	project := t.TempDir()
+	other := t.TempDir()
+	os.Mkdir(filepath.Join(project, "src"), 0o755)
+	// A symlink inside the project that points out of it.
+	escape := filepath.Join(project, "escape")
+	if err := os.Symlink(other, escape); err != nil {
+		t.Skip(err)
+	}
+	opts := Options{Writable: []string{project}}
+
+	cases := map[string]bool{
+		project:                                  true,
+		filepath.Join(project, "src", "main.go"): true,
+		filepath.Join(project, "new", "dir", "f"): true,
+		filepath.Join(project, "..", "x"):         false,
+		filepath.Join(other, "f"):                 false,
+		filepath.Join(escape, "f"):                false,
+		project + "-sibling":                      false,
+	}
+	for path, want := range cases {
+		if got := opts.CanWrite(path); got != want {
+			t.Errorf("CanWrite(%q) = %v, want %v", path, got, want)
+		}
+	}
+}
+
+func TestCheckRoot(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	project := filepath.Join(home, "src", "app")
+	os.MkdirAll(project, 0o755)
+
+	for _, dir := range []string{"/", home, home + "/", filepath.Join(project, "..", "..")} {
+		if err := CheckRoot(dir); err == nil {
+			t.Errorf("CheckRoot(%q) = nil, want it refused", dir)
+		}
+	}
+	if err := CheckRoot(project); err != nil {
+		t.Errorf("CheckRoot(%q) = %v", project, err)
+	}
+}
@@ -0,0 +1,243 @@
+// Package session persists interactive chat sessions under
+// ~/.docsgpt/sessions so a conversation (including tool calls, tool results
+// and the server-side conversation id) survives the terminal closing and can
+// be resumed later with `chat --resume`.
+package session
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"docsgpt-cli/internal/api"
+	"docsgpt-cli/internal/config"
+)
+
+// sessionsHome returns the session store root (~/.docsgpt/sessions). It is a
+// var so tests can redirect it to a temp directory.
+var sessionsHome = func() string {
+	return filepath.Join(config.Dir(), "sessions")
+}
+
+// Dir returns the directory holding saved sessions.
+func Dir() string {
+	return sessionsHome()
+}
+
+// titleMaxRunes bounds the title derived from the first user message.
+const titleMaxRunes = 60
+
+// Session is one saved chat conversation.
+type Session struct {
+	ID             string        `json:"id"`
+	Title          string        `json:"title,omitempty"`
+	KeyName        string        `json:"key_name"`
+	BaseURL        string        `json:"base_url"`
+	Model          string        `json:"model,omitempty"`
+	ConversationID string        `json:"conversation_id,omitempty"`
+	CreatedAt      time.Time     `json:"created_at"`
+	UpdatedAt      time.Time     `json:"updated_at"`
+	Messages       []api.Message `json:"messages"`
+
+	// Usage totals the tokens of every request made in the session, and
+	// CostUSD the estimated cost of the turns whose model price was known.
+	Usage   api.Usage `json:"usage"`
+	CostUSD float64   `json:"cost_usd,omitempty"`
+}
+
+// New returns an unsaved session with a fresh id.
+func New(keyName, baseURL string) *Session {
+	now := time.Now()
+	return &Session{
+		ID:        newID(now),
+		KeyName:   keyName,
+		BaseURL:   baseURL,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+}
+
+// NewID returns a fresh session id, for runs such as ask that are not saved
+// as a session but still need one (e.g. for their undo journal).
+func NewID() string {
+	return newID(time.Now())
+}
+
+// newID builds a sortable, human-typable id: a timestamp plus a short random
+// suffix so two sessions started in the same second never collide.
+func newID(t time.Time) string {
+	return fmt.Sprintf("%s-%04x", t.Format("20060102-150405"), rand.N(0x10000))
+}
+
+// validRef matches what may name a session on the command line: an id or
+// an id prefix. Anything else, such as a path, never reaches the
+// filesystem.
+var validRef = regexp.MustCompile(`^[0-9A-Za-z-]+$`)
+
+func pathFor(id string) string {
+	return filepath.Join(sessionsHome(), id+".json")
+}
+
+// UserTurns counts the user messages in the session.
+func (s *Session) UserTurns() int {
+	n := 0
+	for _, m := range s.Messages {
+		if m.Role == "user" {
+			n++
+		}
+	}
+	return n
+}
+
+// Save writes the session to disk. Sessions without a user message are not
+// worth keeping and are skipped, so merely opening chat leaves no file.
+func (s *Session) Save() error {
+	if s.UserTurns() == 0 {
+		return nil
+	}
+	if s.Title == "" {
+		s.Title = deriveTitle(s.Messages)
+	}
+	s.UpdatedAt = time.Now()
+
+	if err := os.MkdirAll(sessionsHome(), 0700); err != nil {
+		return fmt.Errorf("create sessions directory: %w", err)
+	}
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal session: %w", err)
+	}
+	// Write-then-rename so a crash mid-write never truncates a session.
+	tmp := pathFor(s.ID) + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("write session: %w", err)
+	}
+	if err := os.Rename(tmp, pathFor(s.ID)); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("write session: %w", err)
+	}
+	return nil
+}
+
+// deriveTitle uses the first user message, collapsed to one line.
+func deriveTitle(msgs []api.Message) string {
+	for _, m := range msgs {
+		if m.Role != "user" {
+			continue
+		}
+		title := strings.Join(strings.Fields(m.Content), " ")
+		if r := []rune(title); len(r) > titleMaxRunes {
+			title = string(r[:titleMaxRunes-1]) + "…"
+		}
+		return title
+	}
+	return ""
+}
+
+// List returns every saved session, most recently updated first. Files that
+// fail to parse are skipped rather than failing the whole listing.
+func List() ([]*Session, error) {
+	entries, err := os.ReadDir(sessionsHome())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var out []*Session
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".json") {
+			continue
+		}
+		s, err := readFile(filepath.Join(sessionsHome(), e.Name()))
+		if err != nil {
+			continue
+		}
+		out = append(out, s)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].UpdatedAt.After(out[j].UpdatedAt)
+	})
+	return out, nil
+}
+
+// Load resolves ref to a saved session. ref "last" (or "") picks the most
+// recently updated one; otherwise ref is an exact id or an unambiguous id
+// prefix.
+func Load(ref string) (*Session, error) {
+	id, err := Resolve(ref)
+	if err != nil {
+		return nil, err
+	}
+	return readFile(pathFor(id))
+}
+
+// Resolve maps ref ("last", an id, or an id prefix) to a stored session id.
+func Resolve(ref string) (string, error) {
+	if ref == "" || ref == "last" {
+		all, err := List()
+		if err != nil {
+			return "", err
+		}
+		if len(all) == 0 {
+			return "", fmt.Errorf("no saved sessions")
+		}
+		return all[0].ID, nil
+	}
+	if !validRef.MatchString(ref) {
+		return "", fmt.Errorf("invalid session id %q", ref)
+	}
+	if _, err := os.Stat(pathFor(ref)); err == nil {
+		return ref, nil
+	}
+
+	entries, err := os.ReadDir(sessionsHome())
+	if err != nil && !os.IsNotExist(err) {
+		return "", err
+	}
+	var matches []string
+	for _, e := range entries {
+		id, ok := strings.CutSuffix(e.Name(), ".json")
+		if ok && strings.HasPrefix(id, ref) {
+			matches = append(matches, id)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return "", fmt.Errorf("session %q not found", ref)
+	case 1:
+		return matches[0], nil
+	default:
+		return "", fmt.Errorf("session %q is ambiguous (%d matches)", ref, len(matches))
+	}
+}
+
+// Delete removes the session ref resolves to and returns its id.
+func Delete(ref string) (string, error) {
+	id, err := Resolve(ref)
+	if err != nil {
+		return "", err
+	}
+	if err := os.Remove(pathFor(id)); err != nil {
+		return "", fmt.Errorf("remove session %s: %w", id, err)
+	}
+	return id, nil
+}
+
+func readFile(path string) (*Session, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var s Session
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("parse session %s: %w", filepath.Base(path), err)
+	}
+	return &s, nil
+}
@@ -0,0 +1,149 @@
+package session
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"docsgpt-cli/internal/api"
+)
+
+func useTempHome(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	orig := sessionsHome
+	sessionsHome = func() string { return dir }
+	t.Cleanup(func() { sessionsHome = orig })
+	return dir
+}
+
+func TestSaveSkipsEmptySession(t *testing.T) {
+	dir := useTempHome(t)
+	s := New("work", "https://example.com")
+	s.Messages = []api.Message{{Role: "system", Content: "ctx"}}
+	if err := s.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Fatalf("expected no files for a session without user turns, got %d", len(entries))
+	}
+}
+
+func TestSaveLoadRoundTrip(t *testing.T) {
+	useTempHome(t)
+	s := New("work", "https://example.com")
+	s.ConversationID = "conv-1"
+	s.Messages = []api.Message{
+		{Role: "user", Content: "why does\n  the build fail?"},
+		{Role: "assistant", ToolCalls: []api.ToolCall{{ID: "c1", Function: api.FunctionCall{Name: "read_file", Arguments: `{"path":"go.mod"}`}}}},
+		{Role: "tool", ToolCallID: "c1", Content: "module x"},
+		{Role: "assistant", Content: "Missing dependency."},
+	}
+	if err := s.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := Load(s.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.ConversationID != "conv-1" || got.KeyName != "work" || got.BaseURL != "https://example.com" {
+		t.Errorf("metadata not preserved: %+v", got)
+	}
+	if len(got.Messages) != 4 || got.Messages[2].ToolCallID != "c1" || got.Messages[1].ToolCalls[0].Function.Name != "read_file" {
+		t.Errorf("messages not preserved: %+v", got.Messages)
+	}
+	if got.Title != "why does the build fail?" {
+		t.Errorf("Title = %q, want the first user message on one line", got.Title)
+	}
+}
+
+func TestResolve(t *testing.T) {
+	useTempHome(t)
+	older := &Session{ID: "20260101-100000-aaaa", UpdatedAt: time.Now().Add(-time.Hour)}
+	newer := &Session{ID: "20260102-100000-bbbb", UpdatedAt: time.Now()}
+	writeFixture(t, older)
+	writeFixture(t, newer)
+
+	tests := []struct {
+		ref     string
+		want    string
+		wantErr string
+	}{
+		{"last", newer.ID, ""},
+		{"", newer.ID, ""},
+		{older.ID, older.ID, ""},
+		{"20260101", older.ID, ""},
+		{"2026", "", "ambiguous"},
+		{"nope", "", "not found"},
+		{"../config", "", "invalid"},
+		{"20260101-100000-aaaa/../../config", "", "invalid"},
+		{"..", "", "invalid"},
+	}
+	for _, tt := range tests {
+		got, err := Resolve(tt.ref)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Resolve(%q) error = %v, want %q", tt.ref, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("Resolve(%q) = %q, %v; want %q", tt.ref, got, err, tt.want)
+		}
+	}
+}
+
+func TestDelete(t *testing.T) {
+	useTempHome(t)
+	s := New("k", "u")
+	s.Messages = []api.Message{{Role: "user", Content: "hi"}}
+	if err := s.Save(); err != nil {
+		t.Fatal(err)
+	}
+	id, err := Delete(s.ID[:10])
+	if err != nil || id != s.ID {
+		t.Fatalf("Delete = %q, %v; want %q", id, err, s.ID)
+	}
+	if all, _ := List(); len(all) != 0 {
+		t.Fatalf("List after Delete = %d sessions, want 0", len(all))
+	}
+	if _, err := Resolve("last"); err == nil {
+		t.Fatal("Resolve(last) with no sessions should error")
+	}
+}
+
+func TestDeleteRejectsPaths(t *testing.T) {
+	dir := useTempHome(t)
+	outside := filepath.Join(filepath.Dir(dir), "config.json")
+	if err := os.WriteFile(outside, []byte("{}"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Delete("../config"); err == nil {
+		t.Fatal("Delete(../config) should error")
+	}
+	if _, err := os.Stat(outside); err != nil {
+		t.Fatalf("a file outside the session store was removed: %v", err)
+	}
+	if _, err := Load("../config"); err == nil {
+		t.Fatal("Load(../config) should error")
+	}
+}
+
+// writeFixture stores s as-is, bypassing Save's UpdatedAt stamping.
+func writeFixture(t *testing.T, s *Session) {
+	t.Helper()
+	if err := os.MkdirAll(sessionsHome(), 0700); err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.Marshal(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(pathFor(s.ID), data, 0600); err != nil {
+		t.Fatal(err)
+	}
+}
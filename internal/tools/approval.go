@@ -6,29 +6,46 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"regexp"
 	"strings"
 
+	"docsgpt-cli/internal/api"
+	"docsgpt-cli/internal/approvals"
 	"docsgpt-cli/internal/display"
+	"docsgpt-cli/internal/policy"
 )
 
+// maxDiffPreview bounds the diff shown on an edit_file approval card.
+const maxDiffPreview = 40
+
+// Prompts receives approval cards and prompts. ask points it at stderr when
+// stdout carries the answer into a pipe.
+var Prompts io.Writer = os.Stdout
+
+// ApprovalScope is the session and project directory that "always allow"
+// answers are scoped to and looked up in. chat and ask set it per turn.
+var ApprovalScope approvals.Where
+
 type ApprovalResult int
 
 const (
 	Approved ApprovalResult = iota
 	Denied
 	Edited
+	// ReviewEach asks for the calls of a batch to be approved one by one.
+	ReviewEach
 )
 
 // RequestApproval displays a tool call approval card and asks the user to approve, deny, or edit.
 // Returns the result and potentially edited arguments.
 func RequestApproval(toolName string, rawArgs string) (ApprovalResult, string, error) {
 	detail, preview := extractToolDetail(toolName, rawArgs)
-	risk := display.ToolRisk(toolName)
+	risk := toolRisk(toolName)
 
 	card := display.RenderApprovalCard(toolName, detail, preview, risk)
-	fmt.Println()
-	fmt.Println(card)
-	fmt.Print("  > ")
+	fmt.Fprintln(Prompts)
+	fmt.Fprintln(Prompts, card)
+	fmt.Fprint(Prompts, "  > ")
 
 	input, err := readLine(bufio.NewReader(os.Stdin))
 	if err != nil {
@@ -43,12 +60,190 @@ func RequestApproval(toolName string, rawArgs string) (ApprovalResult, string, e
 		return Denied, rawArgs, nil
 	case "3", "e", "edit":
 		return editArgs(toolName, rawArgs)
+	case "4", "always":
+		approveAlways(toolName, rawArgs)
+		return Approved, rawArgs, nil
 	default:
-		fmt.Println(display.Muted("  Invalid choice, denying."))
+		fmt.Fprintln(Prompts, display.Muted("  Invalid choice, denying."))
 		return Denied, rawArgs, nil
 	}
 }
 
+// PreApproved returns the stored "always allow" rule that covers a call in
+// ApprovalScope, or nil if the call needs approval.
+func PreApproved(toolName, rawArgs string) *approvals.Rule {
+	r, err := approvals.Match(toolName, commandOf(toolName, rawArgs), ApprovalScope)
+	if err != nil {
+		fmt.Fprintln(Prompts, display.Warn("  Approval rules not loaded: "+err.Error()))
+		return nil
+	}
+	return r
+}
+
+// approveAlways asks which calls like this one to allow from now on, and
+// where, and stores the answer. The call itself is approved whatever the
+// answer; an invalid choice only skips saving a rule.
+func approveAlways(toolName, rawArgs string) {
+	rule := approvals.Rule{Kind: approvals.Tool, Tool: toolName}
+	if toolName == "run_command" {
+		command := strings.TrimSpace(commandOf(toolName, rawArgs))
+		prefix := suggestPrefix(command)
+		fmt.Fprintln(Prompts, "  Always allow:")
+		fmt.Fprintln(Prompts, "    [1] this exact command")
+		if prefix != "" {
+			fmt.Fprintf(Prompts, "    [2] commands starting with %q\n", prefix)
+		}
+		fmt.Fprintln(Prompts, "    [3] every run_command call")
+		fmt.Fprint(Prompts, "  > ")
+		input, err := readLine(bufio.NewReader(os.Stdin))
+		if err != nil {
+			return
+		}
+		switch strings.TrimSpace(input) {
+		case "1", "":
+			rule.Kind, rule.Pattern = approvals.Exact, command
+		case "2":
+			if prefix == "" {
+				fmt.Fprintln(Prompts, display.Muted("  Invalid choice; approved this call only."))
+				return
+			}
+			rule.Kind, rule.Pattern = approvals.Prefix, prefix
+		case "3":
+		default:
+			fmt.Fprintln(Prompts, display.Muted("  Invalid choice; approved this call only."))
+			return
+		}
+	}
+
+	project := ApprovalScope.Project
+	if project == "" {
+		project, _ = os.Getwd()
+	}
+	session := "this session"
+	if ApprovalScope.Transient {
+		session = "this run"
+	}
+	fmt.Fprintf(Prompts, "  For: [1] %s  [2] this project (%s)  [3] everywhere\n", session, project)
+	fmt.Fprint(Prompts, "  > ")
+	input, err := readLine(bufio.NewReader(os.Stdin))
+	if err != nil {
+		return
+	}
+	switch strings.TrimSpace(input) {
+	case "1", "":
+		rule.Scope, rule.Session = approvals.Session, ApprovalScope.Session
+	case "2":
+		rule.Scope, rule.Project = approvals.Project, project
+	case "3":
+		rule.Scope = approvals.Global
+	default:
+		fmt.Fprintln(Prompts, display.Muted("  Invalid choice; approved this call only."))
+		return
+	}
+
+	if rule.Scope == approvals.Session && ApprovalScope.Transient {
+		if _, err := approvals.AddTransient(rule); err != nil {
+			fmt.Fprintln(Prompts, display.Warn("  Could not keep the approval rule: "+err.Error()))
+			return
+		}
+		fmt.Fprintln(Prompts, display.Muted("  Always allowing "+rule.Describe()+" for the rest of this run."))
+		return
+	}
+	saved, err := approvals.Add(rule)
+	if err != nil {
+		fmt.Fprintln(Prompts, display.Warn("  Could not save the approval rule: "+err.Error()))
+		return
+	}
+	fmt.Fprintln(Prompts, display.Muted(fmt.Sprintf("  Always allowing %s (%s). Revoke with: docsgpt-cli approvals revoke %d",
+		saved.Describe(), saved.Place(), saved.ID)))
+}
+
+// commandOf returns the command of a run_command call, or "" for other
+// tools.
+func commandOf(toolName, rawArgs string) string {
+	if toolName != "run_command" {
+		return ""
+	}
+	var args runCommandArgs
+	json.Unmarshal([]byte(rawArgs), &args)
+	return args.Command
+}
+
+// suggestPrefix proposes the prefix for an "always allow commands starting
+// with" rule: the command name, plus its subcommand when one is followed by
+// more arguments ("go test" for "go test ./..."). It returns "" when the
+// command is not a single simple command or the prefix would be the whole
+// command.
+func suggestPrefix(command string) string {
+	cmds, err := policy.Parse(command)
+	if err != nil || len(cmds) != 1 || len(cmds[0].Writes) > 0 || cmds[0].Sudo || cmds[0].Name == "" {
+		return ""
+	}
+	c := cmds[0]
+	prefix := c.Name
+	if len(c.Args) > 1 && subcommandRe.MatchString(c.Args[0]) {
+		prefix += " " + c.Args[0]
+	}
+	if prefix == command || !strings.HasPrefix(command, prefix+" ") {
+		return ""
+	}
+	return prefix
+}
+
+// subcommandRe matches words that look like a subcommand (test, run,
+// build:prod) rather than a path, flag or value.
+var subcommandRe = regexp.MustCompile(`^[a-z][a-z0-9_:-]*$`)
+
+// RequestBatchApproval displays one approval card for several read-only
+// tool calls and asks the user to approve or deny them all, or to review
+// each call on its own card.
+func RequestBatchApproval(calls []api.ToolCall) (ApprovalResult, error) {
+	details := make([]string, len(calls))
+	risk := "safe"
+	for i, tc := range calls {
+		name := NormalizeName(tc.Function.Name)
+		details[i], _ = extractToolDetail(name, tc.Function.Arguments)
+		if r := toolRisk(name); r != "safe" {
+			risk = r
+		}
+	}
+
+	fmt.Fprintln(Prompts)
+	fmt.Fprintln(Prompts, display.RenderBatchApprovalCard(details, risk))
+	fmt.Fprint(Prompts, "  > ")
+
+	input, err := readLine(bufio.NewReader(os.Stdin))
+	if err != nil {
+		return Denied, err
+	}
+	switch strings.TrimSpace(strings.ToLower(input)) {
+	case "1", "a", "approve", "y", "yes", "":
+		return Approved, nil
+	case "2", "d", "deny", "n", "no":
+		return Denied, nil
+	case "3", "r", "review":
+		return ReviewEach, nil
+	default:
+		fmt.Fprintln(Prompts, display.Muted("  Invalid choice, denying."))
+		return Denied, nil
+	}
+}
+
+// Confirm asks a yes/no question on Prompts. Anything but an explicit yes,
+// including an empty answer, is a no.
+func Confirm(question string) (bool, error) {
+	fmt.Fprint(Prompts, "  "+question+" [y/N] ")
+	input, err := readLine(bufio.NewReader(os.Stdin))
+	if err != nil {
+		return false, err
+	}
+	switch strings.TrimSpace(strings.ToLower(input)) {
+	case "y", "yes":
+		return true, nil
+	}
+	return false, nil
+}
+
 // extractToolDetail returns a detail string and optional preview lines for the tool.
 func extractToolDetail(toolName string, rawArgs string) (string, []string) {
 	switch toolName {
@@ -71,6 +266,75 @@ func extractToolDetail(toolName string, rawArgs string) (string, []string) {
 		json.Unmarshal([]byte(rawArgs), &args)
 		return "Read: " + args.Path, nil
 
+	case "list_dir":
+		var args struct {
+			Path  string `json:"path"`
+			Depth int    `json:"depth"`
+		}
+		json.Unmarshal([]byte(rawArgs), &args)
+		detail := "List: " + orDot(args.Path)
+		if args.Depth > 1 {
+			detail += fmt.Sprintf(" (depth %d)", args.Depth)
+		}
+		return detail, nil
+
+	case "glob":
+		var args struct {
+			Pattern string `json:"pattern"`
+			Path    string `json:"path"`
+		}
+		json.Unmarshal([]byte(rawArgs), &args)
+		return "Find: " + args.Pattern + " in " + orDot(args.Path), nil
+
+	case "grep":
+		var args struct {
+			Pattern string `json:"pattern"`
+			Path    string `json:"path"`
+			Glob    string `json:"glob"`
+		}
+		json.Unmarshal([]byte(rawArgs), &args)
+		detail := "Search: /" + args.Pattern + "/ in " + orDot(args.Path)
+		if args.Glob != "" {
+			detail += " (" + args.Glob + ")"
+		}
+		return detail, nil
+
+	case "file_info":
+		var args struct {
+			Path string `json:"path"`
+		}
+		json.Unmarshal([]byte(rawArgs), &args)
+		return "Inspect: " + args.Path, nil
+
+	case "http_get":
+		var args struct {
+			URL string `json:"url"`
+		}
+		json.Unmarshal([]byte(rawArgs), &args)
+		return "GET " + args.URL, nil
+
+	case "edit_file":
+		args, old, updated, err := planEdit(rawArgs)
+		detail := "Edit: " + args.Path
+		if err != nil {
+			return detail, []string{display.Danger("cannot apply: " + err.Error())}
+		}
+		if err := checkUnchanged(args.Path, []byte(old)); err != nil {
+			return detail, []string{display.Danger(err.Error())}
+		}
+		diff := unifiedDiff(splitLines(old), splitLines(updated), 3)
+		if len(diff) == 0 {
+			return detail, []string{"(no changes)"}
+		}
+		preview := diff
+		if len(preview) > maxDiffPreview {
+			preview = append(preview[:maxDiffPreview:maxDiffPreview], fmt.Sprintf("... (%d more diff lines)", len(diff)-maxDiffPreview))
+		}
+		for i, line := range preview {
+			preview[i] = display.ColorDiffLine(line)
+		}
+		return detail, preview
+
 	case "write_file":
 		var args struct {
 			Path    string `json:"path"`
@@ -86,6 +350,9 @@ func extractToolDetail(toolName string, rawArgs string) (string, []string) {
 		return "Write to: " + args.Path, preview
 
 	default:
+		if e, ok := lookupExternal(toolName); ok && e.Detail != nil {
+			return e.Detail(rawArgs), nil
+		}
 		return "Arguments: " + rawArgs, nil
 	}
 }
@@ -100,8 +367,8 @@ func editArgs(toolName string, rawArgs string) (ApprovalResult, string, error) {
 		}
 		json.Unmarshal([]byte(rawArgs), &args)
 
-		fmt.Printf("  Edit command (current: %s)\n", args.Command)
-		fmt.Print("  $ ")
+		fmt.Fprintf(Prompts, "  Edit command (current: %s)\n", args.Command)
+		fmt.Fprint(Prompts, "  $ ")
 		newCmd, err := readLine(reader)
 		if err != nil {
 			return Denied, rawArgs, err
@@ -112,8 +379,8 @@ func editArgs(toolName string, rawArgs string) (ApprovalResult, string, error) {
 	}
 
 	// For other tools, let user edit raw JSON
-	fmt.Printf("  Edit arguments JSON (current: %s)\n", rawArgs)
-	fmt.Print("  > ")
+	fmt.Fprintf(Prompts, "  Edit arguments JSON (current: %s)\n", rawArgs)
+	fmt.Fprint(Prompts, "  > ")
 	newArgs, err := readLine(reader)
 	if err != nil {
 		return Denied, rawArgs, err
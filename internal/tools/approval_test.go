@@ -37,3 +37,23 @@ func TestReadLine(t *testing.T) {
 		}
 	}
 }
+
+func TestSuggestPrefix(t *testing.T) {
+	cases := map[string]string{
+		"go test ./...":           "go test",
+		"npm run build -- --prod": "npm run",
+		"ls -la":                  "ls",
+		"make lint":               "make",
+		"pytest tests/unit":       "pytest",
+		"ls":                      "",
+		"go test ./... | tee x":   "",
+		"sudo apt update":         "",
+		"FOO=1 go test ./...":     "",
+		"cat x > y":               "",
+	}
+	for command, want := range cases {
+		if got := suggestPrefix(command); got != want {
+			t.Errorf("suggestPrefix(%q) = %q, want %q", command, got, want)
+		}
+	}
+}
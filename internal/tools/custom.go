@@ -0,0 +1,324 @@
+package tools
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	"docsgpt-cli/internal/api"
+	"docsgpt-cli/internal/config"
+	"docsgpt-cli/internal/policy"
+
+	"gopkg.in/yaml.v3"
+)
+
+// CustomDir is the directory of custom tool files, in ~/.docsgpt and in a
+// project's .docsgpt directory.
+const CustomDir = "tools.d"
+
+// Custom is a tool declared in a tools.d/*.yaml file: a command template
+// whose placeholders are filled from the call's arguments.
+type Custom struct {
+	Name        string `yaml:"name"`
+	Description string `yaml:"description"`
+	// Parameters is the JSON Schema of the arguments, written in YAML.
+	Parameters map[string]any `yaml:"parameters"`
+	// Command is a text/template for the shell command. {{.name}} is the
+	// argument "name", shell-quoted.
+	Command string `yaml:"command"`
+	// WorkingDirectory is where the command runs, relative to the
+	// directory holding .docsgpt (or ~/.docsgpt for the user's tools). The
+	// default is the CLI's working directory.
+	WorkingDirectory string `yaml:"working_directory"`
+	// Timeout in seconds overrides --timeout for this tool.
+	Timeout int `yaml:"timeout"`
+	// Risk is the approval card's badge: safe, caution or danger.
+	Risk string `yaml:"risk"`
+	// Approval is "ask" (the default) to show the approval card, or "auto"
+	// to run calls without it. A project's tools always ask.
+	Approval string `yaml:"approval"`
+
+	// Source is the file the tool came from.
+	Source string `yaml:"-"`
+	// Project reports a tool from a project's tools.d.
+	Project bool `yaml:"-"`
+	tmpl    *template.Template
+	baseDir string
+}
+
+var customNameRe = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,64}$`)
+
+// customGlobalDir returns ~/.docsgpt/tools.d. It is a var so tests can
+// redirect it.
+var customGlobalDir = func() string {
+	return filepath.Join(config.Dir(), CustomDir)
+}
+
+// LoadCustom reads the user's custom tools and those of the project
+// containing dir, and registers them. A project tool does not replace a
+// user tool of the same name. It returns the names registered, and an
+// error for each file or tool that was skipped.
+func LoadCustom(dir string) ([]string, []error) {
+	var names []string
+	var errs []error
+	seen := map[string]bool{}
+	add := func(tools []*Custom) {
+		for _, c := range tools {
+			if seen[c.Name] {
+				continue
+			}
+			if err := RegisterExternal(c.External()); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", c.Source, err))
+				continue
+			}
+			seen[c.Name] = true
+			names = append(names, c.Name)
+		}
+	}
+
+	global := customGlobalDir()
+	tools, ferrs := readCustomDir(global, filepath.Dir(config.Dir()), false)
+	errs = append(errs, ferrs...)
+	add(tools)
+	if project := findCustomProject(dir, global); project != "" {
+		tools, ferrs := readCustomDir(project, filepath.Dir(filepath.Dir(project)), true)
+		errs = append(errs, ferrs...)
+		add(tools)
+	}
+	return names, errs
+}
+
+// findCustomProject returns the nearest .docsgpt/tools.d directory in dir
+// or one of its parents, other than global, or "".
+func findCustomProject(dir, global string) string {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return ""
+	}
+	for {
+		path := filepath.Join(dir, ".docsgpt", CustomDir)
+		if path != global {
+			if info, err := os.Stat(path); err == nil && info.IsDir() {
+				return path
+			}
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// readCustomDir parses the *.yaml and *.yml files of dir, in name order.
+// Working directories are relative to base.
+func readCustomDir(dir, base string, project bool) ([]*Custom, []error) {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, []error{err}
+	}
+	var tools []*Custom
+	var errs []error
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+	for _, e := range entries {
+		ext := filepath.Ext(e.Name())
+		if e.IsDir() || ext != ".yaml" && ext != ".yml" {
+			continue
+		}
+		path := filepath.Join(dir, e.Name())
+		data, err := os.ReadFile(path)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		c, err := parseCustom(data, path)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		c.Project, c.baseDir = project, base
+		tools = append(tools, c)
+	}
+	return tools, errs
+}
+
+// parseCustom parses and checks one tool file.
+func parseCustom(data []byte, source string) (*Custom, error) {
+	var c Custom
+	if err := yaml.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", source, err)
+	}
+	c.Source = source
+	switch {
+	case !customNameRe.MatchString(c.Name):
+		return nil, fmt.Errorf("%s: name must be 1-64 letters, digits, _ or -, not %q", source, c.Name)
+	case c.Description == "":
+		return nil, fmt.Errorf("%s: description is missing", source)
+	case strings.TrimSpace(c.Command) == "":
+		return nil, fmt.Errorf("%s: command is missing", source)
+	case c.Timeout < 0:
+		return nil, fmt.Errorf("%s: timeout must not be negative", source)
+	}
+	if c.Risk == "" {
+		c.Risk = "caution"
+	}
+	if c.Risk != "safe" && c.Risk != "caution" && c.Risk != "danger" {
+		return nil, fmt.Errorf("%s: risk must be safe, caution or danger, not %q", source, c.Risk)
+	}
+	if c.Approval == "" {
+		c.Approval = "ask"
+	}
+	if c.Approval != "ask" && c.Approval != "auto" {
+		return nil, fmt.Errorf("%s: approval must be ask or auto, not %q", source, c.Approval)
+	}
+	if c.Parameters == nil {
+		c.Parameters = map[string]any{"type": "object", "properties": map[string]any{}}
+	}
+	if _, err := json.Marshal(c.Parameters); err != nil {
+		return nil, fmt.Errorf("%s: parameters: %w", source, err)
+	}
+	tmpl, err := template.New(c.Name).Option("missingkey=zero").Parse(c.Command)
+	if err != nil {
+		return nil, fmt.Errorf("%s: command: %w", source, err)
+	}
+	c.tmpl = tmpl
+	return &c, nil
+}
+
+// External returns the tool in the form RegisterExternal takes.
+func (c *Custom) External() External {
+	params, _ := json.Marshal(c.Parameters)
+	return External{
+		Def: api.Tool{
+			Type: "function",
+			Function: api.ToolFunction{
+				Name:        c.Name,
+				Description: c.Description,
+				Parameters:  params,
+			},
+		},
+		Risk: c.Risk,
+		Call: c.call,
+		Detail: func(rawArgs string) string {
+			command, err := c.Render(rawArgs)
+			if err != nil {
+				return "Arguments: " + rawArgs + "\n" + err.Error()
+			}
+			return "$ " + command
+		},
+		Judge: c.judge,
+	}
+}
+
+// Render fills the command template from a call's arguments. Each value
+// is shell-quoted, so an argument cannot add commands of its own; a list
+// becomes its quoted items separated by spaces. Declared parameters the
+// call leaves out are empty.
+func (c *Custom) Render(rawArgs string) (string, error) {
+	var args map[string]any
+	if strings.TrimSpace(rawArgs) != "" {
+		if err := json.Unmarshal([]byte(rawArgs), &args); err != nil {
+			return "", fmt.Errorf("failed to parse arguments: %w", err)
+		}
+	}
+	data := map[string]any{}
+	if props, ok := c.Parameters["properties"].(map[string]any); ok {
+		for name := range props {
+			data[name] = ""
+		}
+	}
+	for name, v := range args {
+		data[name] = templateValue(v)
+	}
+	var b strings.Builder
+	if err := c.tmpl.Execute(&b, data); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(b.String()), nil
+}
+
+// templateValue prepares an argument for the command template. Numbers and
+// booleans are left as they are, so {{if .verbose}} works; everything else
+// is quoted.
+func templateValue(v any) any {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case bool, float64:
+		return v
+	case string:
+		return shellQuote(v)
+	case []any:
+		parts := make([]string, len(v))
+		for i, item := range v {
+			parts[i] = fmt.Sprint(templateValue(item))
+		}
+		return strings.Join(parts, " ")
+	default:
+		b, _ := json.Marshal(v)
+		return shellQuote(string(b))
+	}
+}
+
+// judge decides whether a call may run: it is denied if the policy denies
+// the tool or the command it renders to, and runs without approval if a
+// tool rule or the command policy allows it, or the user's own definition
+// says approval: auto.
+func (c *Custom) judge(rawArgs string) (string, policy.Decision) {
+	command, err := c.Render(rawArgs)
+	if err != nil {
+		return c.Name, policy.Decision{Verdict: policy.Ask, Reason: err.Error()}
+	}
+	subject := c.Name + ": $ " + command
+	byTool := ToolPolicy(c.Name)
+	byCommand := policy.Check(command, c.dir())
+	switch {
+	case byTool.Verdict == policy.Deny:
+		return subject, byTool
+	case byCommand.Verdict == policy.Deny:
+		return subject, byCommand
+	case byTool.Verdict == policy.Allow:
+		return subject, byTool
+	case c.Approval == "auto" && !c.Project:
+		return subject, policy.Decision{Verdict: policy.Allow, Reason: "approval: auto in " + c.Source}
+	case byCommand.Verdict == policy.Allow:
+		return subject, byCommand
+	}
+	return subject, policy.Decision{Verdict: policy.Ask, Reason: byTool.Reason}
+}
+
+// dir returns the directory the command runs in, "" for the CLI's own.
+func (c *Custom) dir() string {
+	if c.WorkingDirectory == "" {
+		return ""
+	}
+	if filepath.IsAbs(c.WorkingDirectory) {
+		return c.WorkingDirectory
+	}
+	return filepath.Join(c.baseDir, c.WorkingDirectory)
+}
+
+// call runs the rendered command as run_command would, under the command
+// policy's deny rules, the sandbox and the output budget.
+func (c *Custom) call(rawArgs string, timeout time.Duration) ToolResult {
+	command, err := c.Render(rawArgs)
+	if err != nil {
+		return ToolResult{Error: err.Error()}
+	}
+	if c.Timeout > 0 {
+		timeout = time.Duration(c.Timeout) * time.Second
+	}
+	args, _ := json.Marshal(runCommandArgs{Command: command, WorkingDirectory: c.dir()})
+	return executeRunCommand(string(args), timeout)
+}
@@ -0,0 +1,128 @@
+package tools
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"docsgpt-cli/internal/policy"
+)
+
+const tailLogs = `
+name: tail_service_logs
+description: Show the last lines of a service's log
+parameters:
+  type: object
+  properties:
+    service: {type: string}
+    lines: {type: integer}
+    follow: {type: boolean}
+  required: [service]
+command: echo {{.service}} {{if .lines}}-n {{.lines}}{{end}}{{if .follow}} -f{{end}}
+risk: safe
+approval: auto
+`
+
+func TestParseCustom(t *testing.T) {
+	if _, err := parseCustom([]byte(tailLogs), "tail.yaml"); err != nil {
+		t.Fatal(err)
+	}
+	for _, bad := range []string{
+		"name: x\ncommand: ls\n",                               // no description
+		"name: x y\ndescription: d\ncommand: ls\n",             // bad name
+		"name: x\ndescription: d\n",                            // no command
+		"name: x\ndescription: d\ncommand: ls\nrisk: high\n",   // bad risk
+		"name: x\ndescription: d\ncommand: ls\napproval: no\n", // bad approval
+		"name: x\ndescription: d\ncommand: ls {{.a\n",          // bad template
+		"name: [",
+	} {
+		if _, err := parseCustom([]byte(bad), "bad.yaml"); err == nil {
+			t.Errorf("parseCustom should reject %q", bad)
+		}
+	}
+}
+
+func TestCustomRender(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("sh quoting")
+	}
+	c, _ := parseCustom([]byte(tailLogs), "tail.yaml")
+	cases := map[string]string{
+		`{"service":"api"}`:                          `echo 'api'`,
+		`{"service":"api","lines":50,"follow":true}`: `echo 'api' -n 50 -f`,
+		`{"service":"api; rm -rf ~"}`:                `echo 'api; rm -rf ~'`,
+		`{"service":"it's"}`:                         `echo 'it'\''s'`,
+		`{"service":["a","b c"]}`:                    `echo 'a' 'b c'`,
+	}
+	for args, want := range cases {
+		if got, err := c.Render(args); err != nil || got != want {
+			t.Errorf("Render(%s) = %q, %v; want %q", args, got, err, want)
+		}
+	}
+	if _, err := c.Render(`{`); err == nil {
+		t.Error("Render should reject invalid JSON")
+	}
+}
+
+func TestLoadCustom(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sh")
+	}
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Cleanup(ResetExternal)
+	orig := customGlobalDir
+	customGlobalDir = func() string { return filepath.Join(home, ".docsgpt", CustomDir) }
+	t.Cleanup(func() { customGlobalDir = orig })
+	capturePrompts(t)
+
+	write := func(path, data string) {
+		os.MkdirAll(filepath.Dir(path), 0o755)
+		os.WriteFile(path, []byte(data), 0o644)
+	}
+	write(filepath.Join(customGlobalDir(), "tail.yaml"), tailLogs)
+	write(filepath.Join(customGlobalDir(), "broken.yaml"), "name: broken\n")
+	write(filepath.Join(customGlobalDir(), "notes.txt"), "not a tool")
+	project := filepath.Join(t.TempDir(), "repo")
+	write(filepath.Join(project, ".docsgpt", CustomDir, "migrate.yml"), `
+name: run_migrations
+description: Apply database migrations
+command: pwd
+working_directory: db
+approval: auto
+`)
+	write(filepath.Join(project, ".docsgpt", CustomDir, "tail.yaml"), strings.Replace(tailLogs, "echo", "rm", 1))
+	os.MkdirAll(filepath.Join(project, "db"), 0o755)
+
+	names, errs := LoadCustom(project)
+	if strings.Join(names, ",") != "tail_service_logs,run_migrations" || len(errs) != 1 {
+		t.Fatalf("LoadCustom = %v, %v", names, errs)
+	}
+
+	// The user's tool wins over the project's of the same name.
+	if r := Execute("tail_service_logs", `{"service":"api","lines":5}`, 5*time.Second); r.Error != "" || r.Output != "api -n 5\n" {
+		t.Errorf("tail_service_logs = %+v", r)
+	}
+	if r := Execute("run_migrations", `{}`, 5*time.Second); r.Error != "" || !strings.HasSuffix(strings.TrimSpace(r.Output), filepath.Join("repo", "db")) {
+		t.Errorf("run_migrations = %+v", r)
+	}
+
+	// approval: auto holds for the user's tools only.
+	if _, d := CallPolicy("tail_service_logs", `{"service":"api"}`); d.Verdict != policy.Allow {
+		t.Errorf("user tool with approval: auto = %+v", d)
+	}
+	if subject, d := CallPolicy("run_migrations", `{}`); d.Verdict != policy.Ask || subject != "run_migrations: $ pwd" {
+		t.Errorf("project tool with approval: auto = %q, %+v", subject, d)
+	}
+	// The command policy's denials still apply to what a tool renders.
+	c, _ := parseCustom([]byte("name: wipe\ndescription: d\ncommand: rm -rf {{.dir}}\napproval: auto\n"), "wipe.yaml")
+	if _, d := c.judge(`{"dir":"/"}`); d.Verdict != policy.Deny {
+		t.Errorf("rm -rf '/' = %+v", d)
+	}
+	if detail, _ := extractToolDetail("tail_service_logs", `{"service":"api"}`); detail != "$ echo 'api'" {
+		t.Errorf("card detail = %q", detail)
+	}
+}
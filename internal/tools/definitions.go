@@ -6,8 +6,13 @@ import (
 	"docsgpt-cli/internal/api"
 )
 
-// ToolDefinitions returns the tool schemas to send in chat completion requests.
+// ToolDefinitions returns the tool schemas to send in chat completion
+// requests: the built-in tools, then the registered external ones.
 func ToolDefinitions() []api.Tool {
+	return append(builtinDefinitions(), externalDefinitions()...)
+}
+
+func builtinDefinitions() []api.Tool {
 	return []api.Tool{
 		{
 			Type: "function",
@@ -34,13 +39,21 @@ func ToolDefinitions() []api.Tool {
 			Type: "function",
 			Function: api.ToolFunction{
 				Name:        "read_file",
-				Description: "Read the contents of a file on the user's local machine.",
+				Description: "Read the contents of a file on the user's local machine. Large files are returned a page at a time, ending with a note that gives the offset of the next page.",
 				Parameters: json.RawMessage(`{
 					"type": "object",
 					"properties": {
 						"path": {
 							"type": "string",
 							"description": "Path to the file to read (relative to working directory or absolute)"
+						},
+						"offset": {
+							"type": "integer",
+							"description": "Line number to start reading at, from 1 (default: 1)"
+						},
+						"limit": {
+							"type": "integer",
+							"description": "Maximum number of lines to read (default: as many as fit)"
 						}
 					},
 					"required": ["path"]
@@ -68,5 +81,168 @@ func ToolDefinitions() []api.Tool {
 				}`),
 			},
 		},
+		{
+			Type: "function",
+			Function: api.ToolFunction{
+				Name:        "edit_file",
+				Description: "Edit part of an existing file on the user's local machine, instead of rewriting it with write_file. Give either edits (exact search/replace pairs; each old_string must match the file exactly once, including whitespace, unless replace_all is set) or a unified diff. The user sees the resulting diff and is prompted to approve. Fails if the file changed since you last read it.",
+				Parameters: json.RawMessage(`{
+					"type": "object",
+					"properties": {
+						"path": {
+							"type": "string",
+							"description": "Path to the file to edit"
+						},
+						"edits": {
+							"type": "array",
+							"description": "Search/replace hunks, applied in order",
+							"items": {
+								"type": "object",
+								"properties": {
+									"old_string": {
+										"type": "string",
+										"description": "Exact text to replace, with enough surrounding lines to be unique"
+									},
+									"new_string": {
+										"type": "string",
+										"description": "Replacement text"
+									},
+									"replace_all": {
+										"type": "boolean",
+										"description": "Replace every occurrence of old_string"
+									}
+								},
+								"required": ["old_string", "new_string"]
+							}
+						},
+						"diff": {
+							"type": "string",
+							"description": "A unified diff of this one file (hunks starting with @@), as an alternative to edits"
+						}
+					},
+					"required": ["path"]
+				}`),
+			},
+		},
+		{
+			Type: "function",
+			Function: api.ToolFunction{
+				Name:        "list_dir",
+				Description: "List a directory on the user's local machine as JSON entries (path, type, size). Hidden entries, .git and node_modules are skipped. Prefer this over running ls or find.",
+				Parameters: json.RawMessage(`{
+					"type": "object",
+					"properties": {
+						"path": {
+							"type": "string",
+							"description": "Directory to list. Defaults to the working directory."
+						},
+						"depth": {
+							"type": "integer",
+							"description": "How many levels to descend (1 = direct children only, max 10). Defaults to 1."
+						},
+						"include_hidden": {
+							"type": "boolean",
+							"description": "Include entries whose names start with a dot."
+						}
+					}
+				}`),
+			},
+		},
+		{
+			Type: "function",
+			Function: api.ToolFunction{
+				Name:        "glob",
+				Description: "Find files whose paths match a glob pattern, e.g. \"**/*.go\" or \"cmd/*_test.go\". \"**\" matches any number of directories. Returns matching paths relative to the search directory.",
+				Parameters: json.RawMessage(`{
+					"type": "object",
+					"properties": {
+						"pattern": {
+							"type": "string",
+							"description": "Glob pattern, relative to path"
+						},
+						"path": {
+							"type": "string",
+							"description": "Directory to search. Defaults to the working directory."
+						}
+					},
+					"required": ["pattern"]
+				}`),
+			},
+		},
+		{
+			Type: "function",
+			Function: api.ToolFunction{
+				Name:        "grep",
+				Description: "Search file contents for a regular expression (RE2 syntax), recursively. Returns matching lines with file and line number, and optional context lines. Binary and very large files are skipped. Prefer this over running grep.",
+				Parameters: json.RawMessage(`{
+					"type": "object",
+					"properties": {
+						"pattern": {
+							"type": "string",
+							"description": "Regular expression to search for"
+						},
+						"path": {
+							"type": "string",
+							"description": "File or directory to search. Defaults to the working directory."
+						},
+						"glob": {
+							"type": "string",
+							"description": "Only search files whose name matches this glob, e.g. \"*.py\""
+						},
+						"context": {
+							"type": "integer",
+							"description": "Lines of context to include before and after each match (max 10)"
+						},
+						"ignore_case": {
+							"type": "boolean",
+							"description": "Match case-insensitively"
+						},
+						"max_matches": {
+							"type": "integer",
+							"description": "Stop after this many matches (default 100, max 500)"
+						}
+					},
+					"required": ["pattern"]
+				}`),
+			},
+		},
+		{
+			Type: "function",
+			Function: api.ToolFunction{
+				Name:        "file_info",
+				Description: "Get metadata for a path on the user's local machine: type, size, permissions, modification time, and the line count of text files.",
+				Parameters: json.RawMessage(`{
+					"type": "object",
+					"properties": {
+						"path": {
+							"type": "string",
+							"description": "Path to inspect"
+						}
+					},
+					"required": ["path"]
+				}`),
+			},
+		},
+		{
+			Type: "function",
+			Function: api.ToolFunction{
+				Name:        "http_get",
+				Description: "Fetch a URL with an HTTP GET request from the user's machine. Returns the status, content type and the start of the body (binary bodies are omitted).",
+				Parameters: json.RawMessage(`{
+					"type": "object",
+					"properties": {
+						"url": {
+							"type": "string",
+							"description": "The http or https URL to fetch"
+						},
+						"max_bytes": {
+							"type": "integer",
+							"description": "Maximum bytes of the body to return (default 8192, max 65536)"
+						}
+					},
+					"required": ["url"]
+				}`),
+			},
+		},
 	}
 }
@@ -0,0 +1,157 @@
+package tools
+
+import "fmt"
+
+// maxDiffCells bounds the LCS table of the part of two files that differs;
+// past it, the changed region is shown as a plain removal and addition.
+const maxDiffCells = 4 << 20
+
+// diffOp is one line of an edit script: ' ' kept, '-' removed, '+' added.
+type diffOp struct {
+	kind byte
+	text string
+}
+
+// UnifiedDiff returns the unified diff between two versions of a file's
+// content, with context unchanged lines around each change.
+func UnifiedDiff(old, new string, context int) []string {
+	return unifiedDiff(splitLines(old), splitLines(new), context)
+}
+
+// unifiedDiff returns the unified diff of a and b as lines starting with
+// "@@", " ", "-" or "+", with context unchanged lines around each change.
+// It returns nil when a and b are equal.
+func unifiedDiff(a, b []string, context int) []string {
+	ops := diffOps(a, b)
+
+	var out []string
+	for i := 0; i < len(ops); {
+		if ops[i].kind == ' ' {
+			i++
+			continue
+		}
+		// A hunk runs from context lines before this change to context
+		// lines after the last change that is within 2*context of it.
+		start := max(i-context, 0)
+		end := i
+		for j := i; j < len(ops); j++ {
+			if ops[j].kind != ' ' {
+				end = j + 1
+			} else if j-end >= 2*context {
+				break
+			}
+		}
+		end = min(end+context, len(ops))
+
+		aLine, bLine := 1, 1
+		for _, op := range ops[:start] {
+			if op.kind != '+' {
+				aLine++
+			}
+			if op.kind != '-' {
+				bLine++
+			}
+		}
+		aCount, bCount := 0, 0
+		for _, op := range ops[start:end] {
+			if op.kind != '+' {
+				aCount++
+			}
+			if op.kind != '-' {
+				bCount++
+			}
+		}
+		out = append(out, fmt.Sprintf("@@ -%s +%s @@", hunkRange(aLine, aCount), hunkRange(bLine, bCount)))
+		for _, op := range ops[start:end] {
+			out = append(out, string(op.kind)+op.text)
+		}
+		i = end
+	}
+	return out
+}
+
+// hunkRange formats a hunk header range the way diff -u does: an empty
+// range names the line before it.
+func hunkRange(line, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", line-1)
+	}
+	if count == 1 {
+		return fmt.Sprint(line)
+	}
+	return fmt.Sprintf("%d,%d", line, count)
+}
+
+// diffOps computes a line edit script from a to b. The common prefix and
+// suffix are matched directly, so the LCS table only covers the region that
+// changed.
+func diffOps(a, b []string) []diffOp {
+	pre := 0
+	for pre < len(a) && pre < len(b) && a[pre] == b[pre] {
+		pre++
+	}
+	suf := 0
+	for suf < len(a)-pre && suf < len(b)-pre && a[len(a)-1-suf] == b[len(b)-1-suf] {
+		suf++
+	}
+
+	var ops []diffOp
+	for _, s := range a[:pre] {
+		ops = append(ops, diffOp{' ', s})
+	}
+	ops = append(ops, lcsOps(a[pre:len(a)-suf], b[pre:len(b)-suf])...)
+	for _, s := range a[len(a)-suf:] {
+		ops = append(ops, diffOp{' ', s})
+	}
+	return ops
+}
+
+// lcsOps diffs two short slices through their longest common subsequence.
+func lcsOps(a, b []string) []diffOp {
+	var ops []diffOp
+	if len(a)*len(b) > maxDiffCells {
+		for _, s := range a {
+			ops = append(ops, diffOp{'-', s})
+		}
+		for _, s := range b {
+			ops = append(ops, diffOp{'+', s})
+		}
+		return ops
+	}
+	// lcs[i][j] is the LCS length of a[i:] and b[j:].
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			ops = append(ops, diffOp{' ', a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			ops = append(ops, diffOp{'-', a[i]})
+			i++
+		default:
+			ops = append(ops, diffOp{'+', b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		ops = append(ops, diffOp{'-', a[i]})
+	}
+	for ; j < len(b); j++ {
+		ops = append(ops, diffOp{'+', b[j]})
+	}
+	return ops
+}
@@ -0,0 +1,271 @@
+package tools
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// seenFiles records the content hash of each file as the model last saw
+// it: read with read_file, or written with write_file or edit_file.
+// edit_file refuses to patch a file that has changed since.
+var (
+	seenMu    sync.Mutex
+	seenFiles = map[string][sha256.Size]byte{}
+)
+
+func seenKey(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return filepath.Clean(path)
+}
+
+// noteSeen records data as the model's view of the file at path.
+func noteSeen(path string, data []byte) {
+	seenMu.Lock()
+	defer seenMu.Unlock()
+	seenFiles[seenKey(path)] = sha256.Sum256(data)
+}
+
+// checkUnchanged reports an error when the file at path, now holding data,
+// differs from what the model last saw. Files the model has not seen pass:
+// the edit's own context lines still have to match.
+func checkUnchanged(path string, data []byte) error {
+	seenMu.Lock()
+	defer seenMu.Unlock()
+	sum, ok := seenFiles[seenKey(path)]
+	if ok && sum != sha256.Sum256(data) {
+		return fmt.Errorf("%s has changed since it was last read; read it again before editing", path)
+	}
+	return nil
+}
+
+// fileEdit is one search/replace hunk of an edit_file call.
+type fileEdit struct {
+	OldString  string `json:"old_string"`
+	NewString  string `json:"new_string"`
+	ReplaceAll bool   `json:"replace_all"`
+}
+
+type editFileArgs struct {
+	Path  string     `json:"path"`
+	Edits []fileEdit `json:"edits"`
+	Diff  string     `json:"diff"`
+}
+
+// planEdit reads the file an edit_file call targets and computes its new
+// content, without writing anything. The approval card previews the plan,
+// and executeEditFile applies it.
+func planEdit(rawArgs string) (args editFileArgs, old, updated string, err error) {
+	if err = json.Unmarshal([]byte(rawArgs), &args); err != nil {
+		return args, "", "", fmt.Errorf("failed to parse arguments: %w", err)
+	}
+	if args.Path == "" {
+		return args, "", "", errors.New("path is required")
+	}
+	if (len(args.Edits) == 0) == (args.Diff == "") {
+		return args, "", "", errors.New("give either edits or diff")
+	}
+	data, err := os.ReadFile(args.Path)
+	if err != nil {
+		return args, "", "", err
+	}
+	old = string(data)
+
+	// Work on LF line endings and restore CRLF on the way out, so hunks
+	// written with "\n" match a Windows file.
+	crlf := strings.Contains(old, "\r\n")
+	content := old
+	if crlf {
+		content = strings.ReplaceAll(content, "\r\n", "\n")
+	}
+	if args.Diff != "" {
+		content, err = applyUnifiedDiff(content, args.Diff)
+	} else {
+		content, err = applyEdits(content, args.Edits)
+	}
+	if err != nil {
+		return args, old, "", err
+	}
+	if crlf {
+		content = strings.ReplaceAll(content, "\n", "\r\n")
+	}
+	return args, old, content, nil
+}
+
+func executeEditFile(rawArgs string) ToolResult {
+	args, old, updated, err := planEdit(rawArgs)
+	if err != nil {
+		return ToolResult{Error: err.Error()}
+	}
+	if err := checkUnchanged(args.Path, []byte(old)); err != nil {
+		return ToolResult{Error: err.Error()}
+	}
+	if updated == old {
+		return ToolResult{Output: "No changes: the edit leaves " + args.Path + " as it is."}
+	}
+	if err := writeToolFile("edit_file", args.Path, []byte(updated)); err != nil {
+		return ToolResult{Error: err.Error()}
+	}
+	noteSeen(args.Path, []byte(updated))
+
+	added, removed := 0, 0
+	for _, line := range unifiedDiff(splitLines(old), splitLines(updated), 0) {
+		switch line[0] {
+		case '+':
+			added++
+		case '-':
+			removed++
+		}
+	}
+	return ToolResult{Output: fmt.Sprintf("Edited %s: %d lines added, %d removed", args.Path, added, removed)}
+}
+
+// applyEdits applies search/replace hunks in order. Each old_string must
+// match exactly once unless replace_all is set.
+func applyEdits(content string, edits []fileEdit) (string, error) {
+	for i, e := range edits {
+		if e.OldString == "" {
+			return "", fmt.Errorf("edit %d: old_string is empty", i+1)
+		}
+		switch n := strings.Count(content, e.OldString); {
+		case n == 0:
+			return "", fmt.Errorf("edit %d: old_string not found in the file", i+1)
+		case n > 1 && !e.ReplaceAll:
+			return "", fmt.Errorf("edit %d: old_string matches %d places; include more surrounding lines or set replace_all", i+1, n)
+		}
+		content = strings.ReplaceAll(content, e.OldString, e.NewString)
+	}
+	return content, nil
+}
+
+var hunkHeaderRe = regexp.MustCompile(`^@@ -(\d+)(?:,(\d+))? \+\d+(?:,\d+)? @@`)
+
+// applyUnifiedDiff applies a unified diff for a single file. File headers
+// are skipped. Each hunk is placed where its context and removed lines
+// match, nearest the line its header names, so a diff made against a
+// slightly shifted copy still applies; a hunk that matches nowhere fails
+// the whole diff.
+func applyUnifiedDiff(content, diff string) (string, error) {
+	lines := splitLines(content)
+	trailingNewline := content == "" || strings.HasSuffix(content, "\n")
+
+	type hunk struct {
+		start    int
+		old, new []string
+	}
+	var hunks []*hunk
+	var cur *hunk
+	for _, line := range strings.Split(strings.ReplaceAll(diff, "\r\n", "\n"), "\n") {
+		if m := hunkHeaderRe.FindStringSubmatch(line); m != nil {
+			// start is the 0-based index of the hunk's first old line; an
+			// empty old range names the line before it.
+			start, _ := strconv.Atoi(m[1])
+			if m[2] != "0" {
+				start--
+			}
+			cur = &hunk{start: start}
+			hunks = append(hunks, cur)
+			continue
+		}
+		if cur == nil {
+			continue // diff --git, index, ---/+++ headers
+		}
+		switch {
+		case line == "":
+			// Blank context lines often lose their leading space.
+			cur.old = append(cur.old, "")
+			cur.new = append(cur.new, "")
+		case line[0] == ' ':
+			cur.old = append(cur.old, line[1:])
+			cur.new = append(cur.new, line[1:])
+		case line[0] == '-':
+			cur.old = append(cur.old, line[1:])
+		case line[0] == '+':
+			cur.new = append(cur.new, line[1:])
+		case line[0] == '\\':
+			// "\ No newline at end of file"
+		default:
+			cur = nil
+		}
+	}
+	if len(hunks) == 0 {
+		return "", errors.New("diff has no hunks")
+	}
+
+	var out []string
+	pos := 0
+	for i, h := range hunks {
+		// A trailing blank line in a hunk is usually the diff's own final
+		// newline, not a context line.
+		for len(h.old) > 0 && len(h.new) > 0 && h.old[len(h.old)-1] == "" && h.new[len(h.new)-1] == "" &&
+			findBlock(lines, h.old, pos, h.start) < 0 {
+			h.old, h.new = h.old[:len(h.old)-1], h.new[:len(h.new)-1]
+		}
+		at := findBlock(lines, h.old, pos, h.start)
+		if at < 0 {
+			return "", fmt.Errorf("hunk %d (at line %d) does not match the file", i+1, h.start+1)
+		}
+		out = append(out, lines[pos:at]...)
+		out = append(out, h.new...)
+		pos = at + len(h.old)
+	}
+	out = append(out, lines[pos:]...)
+
+	result := strings.Join(out, "\n")
+	if trailingNewline && len(out) > 0 {
+		result += "\n"
+	}
+	return result, nil
+}
+
+// findBlock returns the index at or after from where block occurs in lines,
+// choosing the occurrence nearest want, or -1 when there is none. An empty
+// block goes at want.
+func findBlock(lines, block []string, from, want int) int {
+	if len(block) == 0 {
+		return min(max(want, from), len(lines))
+	}
+	best := -1
+	for i := from; i+len(block) <= len(lines); i++ {
+		if !equalLines(lines[i:i+len(block)], block) {
+			continue
+		}
+		if best < 0 || abs(i-want) < abs(best-want) {
+			best = i
+		}
+	}
+	return best
+}
+
+func equalLines(a, b []string) bool {
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// splitLines splits content into lines without their terminators.
+func splitLines(content string) []string {
+	if content == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+}
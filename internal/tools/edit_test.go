@@ -0,0 +1,176 @@
+package tools
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"docsgpt-cli/internal/sandbox"
+)
+
+const sample = "package main\n\nimport \"fmt\"\n\nfunc main() {\n\tfmt.Println(\"hello\")\n}\n"
+
+func TestApplyEdits(t *testing.T) {
+	tests := []struct {
+		name    string
+		edits   []fileEdit
+		want    string
+		wantErr string
+	}{
+		{"single", []fileEdit{{OldString: `"hello"`, NewString: `"bye"`}}, strings.Replace(sample, "hello", "bye", 1), ""},
+		{"sequential", []fileEdit{{OldString: "hello", NewString: "a"}, {OldString: "a\")", NewString: "b\")"}}, strings.Replace(sample, "hello", "b", 1), ""},
+		{"not found", []fileEdit{{OldString: "nope", NewString: "x"}}, "", "not found"},
+		{"ambiguous", []fileEdit{{OldString: "main", NewString: "x"}}, "", "matches 2 places"},
+		{"replace all", []fileEdit{{OldString: "main", NewString: "app", ReplaceAll: true}}, strings.ReplaceAll(sample, "main", "app"), ""},
+		{"empty old", []fileEdit{{OldString: "", NewString: "x"}}, "", "empty"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := applyEdits(sample, tt.edits)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("got %q, %v; want %q", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyUnifiedDiff(t *testing.T) {
+	tests := []struct {
+		name    string
+		diff    string
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "with headers",
+			diff: "--- a/main.go\n+++ b/main.go\n@@ -5,3 +5,3 @@\n func main() {\n-\tfmt.Println(\"hello\")\n+\tfmt.Println(\"bye\")\n }\n",
+			want: strings.Replace(sample, "hello", "bye", 1),
+		},
+		{
+			name: "wrong line numbers still apply nearby",
+			diff: "@@ -40,2 +40,2 @@\n-import \"fmt\"\n+import \"os\"\n \n",
+			want: strings.Replace(sample, `"fmt"`, `"os"`, 1),
+		},
+		{
+			name: "pure insertion",
+			diff: "@@ -1,0 +2,1 @@\n+// Package main greets.\n",
+			want: strings.Replace(sample, "package main\n", "package main\n// Package main greets.\n", 1),
+		},
+		{
+			name: "blank context without its space",
+			diff: "@@ -2,3 +2,3 @@\n\n-import \"fmt\"\n+import \"log\"\n\n",
+			want: strings.Replace(sample, `"fmt"`, `"log"`, 1),
+		},
+		{
+			name:    "does not match",
+			diff:    "@@ -1,1 +1,1 @@\n-package other\n+package main\n",
+			wantErr: true,
+		},
+		{
+			name:    "no hunks",
+			diff:    "just text",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := applyUnifiedDiff(sample, tt.diff)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUnifiedDiff(t *testing.T) {
+	a := splitLines("a\nb\nc\nd\ne\nf\ng\nh\ni\nj\n")
+	b := splitLines("a\nB\nc\nd\ne\nf\ng\nh\ni\nj\nk\n")
+	got := strings.Join(unifiedDiff(a, b, 1), "\n")
+	want := "@@ -1,3 +1,3 @@\n a\n-b\n+B\n c\n@@ -10 +10,2 @@\n j\n+k"
+	if got != want {
+		t.Errorf("diff:\n%s\nwant:\n%s", got, want)
+	}
+	if d := unifiedDiff(a, a, 3); d != nil {
+		t.Errorf("equal inputs should give no diff, got %q", d)
+	}
+}
+
+func TestEditFileRefusesStaleFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "main.go")
+	if err := os.WriteFile(path, []byte(sample), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	args := func(old, new string) string {
+		b, _ := json.Marshal(map[string]any{"path": path, "edits": []fileEdit{{OldString: old, NewString: new}}})
+		return string(b)
+	}
+
+	if res := Execute("read_file", `{"path":`+jsonString(path)+`}`, time.Second); res.Error != "" {
+		t.Fatal(res.Error)
+	}
+	if res := Execute("edit_file", args("hello", "hi"), time.Second); res.Error != "" {
+		t.Fatalf("edit after read: %s", res.Error)
+	}
+	// The tool's own edit updates what the model has seen.
+	if res := Execute("edit_file", args("hi", "hey"), time.Second); res.Error != "" {
+		t.Fatalf("second edit: %s", res.Error)
+	}
+
+	// Someone else changes the file.
+	os.WriteFile(path, []byte(sample+"// touched\n"), 0o644)
+	res := Execute("edit_file", args("hello", "hi"), time.Second)
+	if !strings.Contains(res.Error, "changed since it was last read") {
+		t.Fatalf("stale edit: error = %q", res.Error)
+	}
+	if data, _ := os.ReadFile(path); !strings.Contains(string(data), "touched") || !strings.Contains(string(data), "hello") {
+		t.Errorf("a refused edit must leave the file alone, got %q", data)
+	}
+}
+
+func TestEditFileKeepsCRLF(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "win.txt")
+	os.WriteFile(path, []byte("one\r\ntwo\r\nthree\r\n"), 0o644)
+	res := Execute("edit_file", `{"path":`+jsonString(path)+`,"edits":[{"old_string":"one\ntwo","new_string":"one\n2"}]}`, time.Second)
+	if res.Error != "" {
+		t.Fatal(res.Error)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "one\r\n2\r\nthree\r\n" {
+		t.Errorf("content = %q", data)
+	}
+}
+
+func TestWriteFileStaysInSandbox(t *testing.T) {
+	project, outside := t.TempDir(), t.TempDir()
+	Sandbox = &sandbox.Options{Writable: []string{project}}
+	t.Cleanup(func() { Sandbox = nil })
+
+	inside := filepath.Join(project, "ok.txt")
+	if res := Execute("write_file", `{"path":`+jsonString(inside)+`,"content":"x"}`, time.Second); res.Error != "" {
+		t.Fatalf("write inside the project: %s", res.Error)
+	}
+	escaped := filepath.Join(outside, "no.txt")
+	res := Execute("write_file", `{"path":`+jsonString(escaped)+`,"content":"x"}`, time.Second)
+	if !strings.Contains(res.Error, "outside the sandbox") {
+		t.Errorf("write outside the project: error = %q", res.Error)
+	}
+	if _, err := os.Stat(escaped); err == nil {
+		t.Error("the file outside the project was written")
+	}
+}
+
+func jsonString(s string) string {
+	b, _ := json.Marshal(s)
+	return string(b)
+}
@@ -1,24 +1,69 @@
 package tools
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
-	"regexp"
 	"runtime"
 	"time"
 
-	"docsgpt-cli/internal/display"
+	"docsgpt-cli/internal/api"
+	"docsgpt-cli/internal/policy"
+	"docsgpt-cli/internal/redact"
+	"docsgpt-cli/internal/sandbox"
+	"docsgpt-cli/internal/undo"
 )
 
-// stripToolSuffix removes server-appended suffixes like "_ct0" from tool names.
-// The DocsGPT server renames tools (e.g., "write_file" → "write_file_ct0").
-var toolSuffixRe = regexp.MustCompile(`_ct\d+$`)
-
+// NormalizeName removes server-appended suffixes like "_ct0" from tool
+// names; see api.NormalizeToolName.
 func NormalizeName(name string) string {
-	return toolSuffixRe.ReplaceAllString(name, "")
+	return api.NormalizeToolName(name)
+}
+
+// readOnlyTools lists the tools that only inspect the machine. Calls to
+// them cannot affect one another, so several in a row are approved together
+// and run concurrently.
+var readOnlyTools = map[string]bool{
+	"read_file": true,
+	"list_dir":  true,
+	"glob":      true,
+	"grep":      true,
+	"file_info": true,
+}
+
+// IsReadOnly reports whether the named tool only reads.
+func IsReadOnly(name string) bool {
+	name = NormalizeName(name)
+	if e, ok := lookupExternal(name); ok {
+		return e.ReadOnly
+	}
+	return readOnlyTools[name]
+}
+
+// Journal, when set, backs up the prior content of every file write_file
+// and edit_file change, so the change can be undone.
+var Journal *undo.Journal
+
+// Sandbox, when set, confines run_command to it, and write_file and
+// edit_file to its writable directories. nil runs commands unconfined.
+var Sandbox *sandbox.Options
+
+// writeToolFile writes a file on behalf of tool, through Journal when set.
+func writeToolFile(tool, path string, data []byte) error {
+	if Sandbox != nil && !Sandbox.CanWrite(path) {
+		return fmt.Errorf("%s is outside the sandbox's writable directories", path)
+	}
+	if err := checkRedactionMarkers(path, data); err != nil {
+		return err
+	}
+	if Journal != nil {
+		return Journal.Write(path, tool, data, 0644)
+	}
+	return os.WriteFile(path, data, 0644)
 }
 
 type ToolResult struct {
@@ -33,38 +78,108 @@ func (r ToolResult) String() string {
 	return r.Output
 }
 
+// Redactor, when set, masks the secrets in every tool result.
+var Redactor *redact.Redactor
+
+// checkRedactionMarkers refuses to write data to path when it holds more
+// redaction markers than the file does now. The model only ever saw the
+// marker in place of a secret, so writing it back would destroy the
+// secret.
+func checkRedactionMarkers(path string, data []byte) error {
+	if Redactor == nil {
+		return nil
+	}
+	added := bytes.Count(data, []byte(redact.Marker))
+	if added == 0 {
+		return nil
+	}
+	if old, err := os.ReadFile(path); err == nil {
+		added -= bytes.Count(old, []byte(redact.Marker))
+	}
+	if added > 0 {
+		return fmt.Errorf("the new content of %s has %s markers where secrets were masked from you; writing them would replace the real values. Leave those lines out of the change (edit_file with hunks that avoid them) or ask the user to make it", path, redact.Marker+"…]")
+	}
+	return nil
+}
+
 // Execute runs a tool by name with the given arguments JSON and timeout.
 // Tool names are normalized to strip server-appended suffixes (e.g., "_ct0").
+// Secrets in the result are masked by Redactor.
 func Execute(name string, rawArgs string, timeout time.Duration) ToolResult {
-	switch NormalizeName(name) {
+	normalized := NormalizeName(name)
+	r := execute(normalized, name, rawArgs, timeout)
+	where := "tool result of " + normalized
+	r.Output = Redactor.Redact(where, r.Output)
+	r.Error = Redactor.Redact(where, r.Error)
+	return r
+}
+
+func execute(normalized, name, rawArgs string, timeout time.Duration) ToolResult {
+	// Like denied commands, tools the policy denies never run.
+	if normalized != "run_command" {
+		if d := ToolPolicy(normalized); d.Verdict == policy.Deny {
+			return ToolResult{Error: "tool call blocked by policy: " + d.Reason}
+		}
+	}
+	switch normalized {
 	case "run_command":
 		return executeRunCommand(rawArgs, timeout)
 	case "read_file":
 		return executeReadFile(rawArgs)
 	case "write_file":
 		return executeWriteFile(rawArgs)
+	case "edit_file":
+		return executeEditFile(rawArgs)
+	case "list_dir":
+		return executeListDir(rawArgs)
+	case "glob":
+		return executeGlob(rawArgs)
+	case "grep":
+		return executeGrep(rawArgs)
+	case "file_info":
+		return executeFileInfo(rawArgs)
+	case "http_get":
+		return executeHTTPGet(rawArgs, timeout)
 	default:
+		if e, ok := lookupExternal(normalized); ok {
+			return e.Call(rawArgs, timeout)
+		}
 		return ToolResult{Error: fmt.Sprintf("unknown tool: %s", name)}
 	}
 }
 
-func executeRunCommand(rawArgs string, timeout time.Duration) ToolResult {
-	var args struct {
-		Command          string `json:"command"`
-		WorkingDirectory string `json:"working_directory"`
+type runCommandArgs struct {
+	Command          string `json:"command"`
+	WorkingDirectory string `json:"working_directory"`
+}
+
+// CommandPolicy judges the command of a run_command call against the
+// command policy (see package policy) for its working directory, and
+// returns the command with the decision.
+func CommandPolicy(rawArgs string) (string, policy.Decision) {
+	var args runCommandArgs
+	if err := json.Unmarshal([]byte(rawArgs), &args); err != nil {
+		return "", policy.Decision{Verdict: policy.Ask, Reason: "cannot parse arguments: " + err.Error()}
 	}
+	return args.Command, policy.Check(args.Command, args.WorkingDirectory)
+}
+
+func executeRunCommand(rawArgs string, timeout time.Duration) ToolResult {
+	var args runCommandArgs
 	if err := json.Unmarshal([]byte(rawArgs), &args); err != nil {
 		return ToolResult{Error: "failed to parse arguments: " + err.Error()}
 	}
 
-	// Safety check
-	safe, reason := IsSafe(args.Command)
-	if !safe {
-		return ToolResult{Error: fmt.Sprintf("command blocked: %s", reason)}
+	// Denied commands never run, however the call got here (an edited
+	// command included). Ask and allow are for the caller's approval step.
+	if d := policy.Check(args.Command, args.WorkingDirectory); d.Verdict == policy.Deny {
+		return ToolResult{Error: "command blocked by policy: " + d.Reason}
 	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), timeout)
 	defer cancel()
+	ctx, stop := context.WithCancelCause(ctx)
+	defer stop(nil)
 
 	var cmd *exec.Cmd
 	if runtime.GOOS == "windows" {
@@ -76,20 +191,32 @@ func executeRunCommand(rawArgs string, timeout time.Duration) ToolResult {
 	if args.WorkingDirectory != "" {
 		cmd.Dir = args.WorkingDirectory
 	}
-
-	// Stream output to terminal while capturing
-	output, err := cmd.CombinedOutput()
-	outStr := TruncateOutput(string(output), maxOutputBytes)
-
-	// Print output in real-time style
-	if len(output) > 0 {
-		fmt.Print(display.Muted(string(output)))
+	setProcessGroup(cmd)
+	if Sandbox != nil {
+		// Never fall back to running the command unconfined.
+		if err := sandbox.Wrap(cmd, *Sandbox); err != nil {
+			return ToolResult{Error: err.Error()}
+		}
 	}
+	// A background process that inherited the output pipes must not hold
+	// the call open once the command itself is done.
+	cmd.WaitDelay = time.Second
 
-	if err != nil {
-		if ctx.Err() == context.DeadlineExceeded {
-			return ToolResult{Output: outStr, Error: "command timed out"}
-		}
+	// Stream output to the terminal as it comes, while capturing it.
+	out := &streamWriter{w: Prompts}
+	cmd.Stdout, cmd.Stderr = out, out
+	untrack := track(stop)
+	err := cmd.Run()
+	untrack()
+	out.Flush()
+	outStr := TruncateOutput(out.String(), OutputBudget)
+
+	switch cause := context.Cause(ctx); {
+	case errors.Is(cause, errInterrupted):
+		return ToolResult{Output: outStr, Error: "interrupted by the user (Ctrl-C); the output is what the command printed before it was stopped"}
+	case errors.Is(cause, context.DeadlineExceeded):
+		return ToolResult{Output: outStr, Error: "command timed out"}
+	case err != nil && !errors.Is(err, exec.ErrWaitDelay):
 		return ToolResult{Output: outStr, Error: err.Error()}
 	}
 
@@ -98,18 +225,33 @@ func executeRunCommand(rawArgs string, timeout time.Duration) ToolResult {
 
 func executeReadFile(rawArgs string) ToolResult {
 	var args struct {
-		Path string `json:"path"`
+		Path   string `json:"path"`
+		Offset int    `json:"offset"`
+		Limit  int    `json:"limit"`
 	}
 	if err := json.Unmarshal([]byte(rawArgs), &args); err != nil {
 		return ToolResult{Error: "failed to parse arguments: " + err.Error()}
 	}
+	if args.Offset < 0 || args.Limit < 0 {
+		return ToolResult{Error: "offset and limit must not be negative"}
+	}
 
 	data, err := os.ReadFile(args.Path)
 	if err != nil {
 		return ToolResult{Error: err.Error()}
 	}
+	noteSeen(args.Path, data)
 
-	return ToolResult{Output: TruncateOutput(string(data), maxOutputBytes)}
+	if args.Offset <= 1 && args.Limit == 0 && len(data) <= OutputBudget {
+		return ToolResult{Output: string(data)}
+	}
+	// Large files and line ranges are read a page at a time; the note
+	// says where the next page starts.
+	page, note := pageLines(string(data), args.Offset, args.Limit, max(OutputBudget-markerRoom, 1))
+	if note != "" {
+		page += "\n" + note
+	}
+	return ToolResult{Output: page}
 }
 
 func executeWriteFile(rawArgs string) ToolResult {
@@ -121,9 +263,10 @@ func executeWriteFile(rawArgs string) ToolResult {
 		return ToolResult{Error: "failed to parse arguments: " + err.Error()}
 	}
 
-	if err := os.WriteFile(args.Path, []byte(args.Content), 0644); err != nil {
+	if err := writeToolFile("write_file", args.Path, []byte(args.Content)); err != nil {
 		return ToolResult{Error: err.Error()}
 	}
+	noteSeen(args.Path, []byte(args.Content))
 
 	return ToolResult{Output: fmt.Sprintf("Successfully wrote %d bytes to %s", len(args.Content), args.Path)}
 }
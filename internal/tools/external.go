@@ -0,0 +1,119 @@
+package tools
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"docsgpt-cli/internal/api"
+	"docsgpt-cli/internal/display"
+	"docsgpt-cli/internal/policy"
+)
+
+// External is a tool the CLI does not implement itself, such as one served
+// by an MCP server. Its calls go through the same approval card, approval
+// rules and policy tool rules as the built-in tools.
+type External struct {
+	Def api.Tool
+	// ReadOnly marks a tool that only reads, so its calls can share a batch
+	// approval card with other read-only calls.
+	ReadOnly bool
+	Call     func(rawArgs string, timeout time.Duration) ToolResult
+
+	// Risk is the approval card's risk badge: safe, caution (the default)
+	// or danger.
+	Risk string
+	// Detail, when set, describes a call on the approval card in place of
+	// its raw arguments.
+	Detail func(rawArgs string) string
+	// Judge, when set, replaces the policy tool rules in deciding whether
+	// a call may run; see CallPolicy.
+	Judge func(rawArgs string) (string, policy.Decision)
+}
+
+// externals holds the registered external tools by name.
+var externals struct {
+	sync.RWMutex
+	byName map[string]External
+}
+
+// RegisterExternal adds an external tool. A name already taken, by a
+// built-in tool or another external one, is an error.
+func RegisterExternal(e External) error {
+	name := e.Def.Function.Name
+	for _, def := range builtinDefinitions() {
+		if def.Function.Name == name {
+			return fmt.Errorf("tool %s clashes with a built-in tool", name)
+		}
+	}
+	externals.Lock()
+	defer externals.Unlock()
+	if _, ok := externals.byName[name]; ok {
+		return fmt.Errorf("tool %s is already registered", name)
+	}
+	if externals.byName == nil {
+		externals.byName = map[string]External{}
+	}
+	externals.byName[name] = e
+	return nil
+}
+
+// ResetExternal removes every external tool.
+func ResetExternal() {
+	externals.Lock()
+	externals.byName = nil
+	externals.Unlock()
+}
+
+func lookupExternal(name string) (External, bool) {
+	externals.RLock()
+	defer externals.RUnlock()
+	e, ok := externals.byName[name]
+	return e, ok
+}
+
+// externalDefinitions returns the external tools' definitions, by name.
+func externalDefinitions() []api.Tool {
+	externals.RLock()
+	defer externals.RUnlock()
+	defs := make([]api.Tool, 0, len(externals.byName))
+	for _, e := range externals.byName {
+		defs = append(defs, e.Def)
+	}
+	sort.Slice(defs, func(i, j int) bool { return defs[i].Function.Name < defs[j].Function.Name })
+	return defs
+}
+
+// toolRisk returns the risk badge for a tool's approval card.
+func toolRisk(name string) string {
+	if e, ok := lookupExternal(name); ok {
+		if e.Risk != "" {
+			return e.Risk
+		}
+		return "caution"
+	}
+	return display.ToolRisk(name)
+}
+
+// ToolPolicy judges a call to a tool other than run_command by the policy
+// tool rules for the working directory.
+func ToolPolicy(name string) policy.Decision {
+	cwd, _ := os.Getwd()
+	return policy.CheckTool(name, cwd)
+}
+
+// CallPolicy judges a tool call by the command policy: run_command by its
+// command, any other tool by the tool rules. It returns what was judged,
+// "$ command" or the tool name, with the decision.
+func CallPolicy(name, rawArgs string) (string, policy.Decision) {
+	if name == "run_command" {
+		command, d := CommandPolicy(rawArgs)
+		return "$ " + command, d
+	}
+	if e, ok := lookupExternal(name); ok && e.Judge != nil {
+		return e.Judge(rawArgs)
+	}
+	return name, ToolPolicy(name)
+}
@@ -0,0 +1,59 @@
+package tools
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"docsgpt-cli/internal/api"
+)
+
+func TestExternalTools(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Cleanup(ResetExternal)
+	os.MkdirAll(filepath.Join(home, ".docsgpt"), 0o755)
+	os.WriteFile(filepath.Join(home, ".docsgpt", "policy.yaml"), []byte("rules:\n  - tool: mcp__jira__delete_*\n    verdict: deny\n"), 0o644)
+
+	external := func(name string, readOnly bool) External {
+		return External{
+			Def:      api.Tool{Type: "function", Function: api.ToolFunction{Name: name}},
+			ReadOnly: readOnly,
+			Call: func(rawArgs string, timeout time.Duration) ToolResult {
+				return ToolResult{Output: name + " got " + rawArgs}
+			},
+		}
+	}
+	if err := RegisterExternal(external("mcp__jira__search", true)); err != nil {
+		t.Fatal(err)
+	}
+	if err := RegisterExternal(external("mcp__jira__delete_issue", false)); err != nil {
+		t.Fatal(err)
+	}
+	if err := RegisterExternal(external("mcp__jira__search", true)); err == nil {
+		t.Error("registering a name twice should fail")
+	}
+	if err := RegisterExternal(external("read_file", true)); err == nil {
+		t.Error("an external tool must not shadow a built-in one")
+	}
+
+	defs := ToolDefinitions()
+	if n := len(defs); defs[n-2].Function.Name != "mcp__jira__delete_issue" || defs[n-1].Function.Name != "mcp__jira__search" {
+		t.Errorf("definitions end with %s, %s", defs[n-2].Function.Name, defs[n-1].Function.Name)
+	}
+	if !IsReadOnly("mcp__jira__search") || IsReadOnly("mcp__jira__delete_issue") {
+		t.Error("IsReadOnly should follow the tool's ReadOnly")
+	}
+
+	if r := Execute("mcp__jira__search_ct0", `{"q":"bug"}`, time.Second); r.Output != `mcp__jira__search got {"q":"bug"}` {
+		t.Errorf("search = %+v", r)
+	}
+	if r := Execute("mcp__jira__delete_issue", `{}`, time.Second); !strings.Contains(r.Error, "blocked by policy") {
+		t.Errorf("a denied tool ran: %+v", r)
+	}
+	if r := Execute("mcp__gone__x", `{}`, time.Second); !strings.Contains(r.Error, "unknown tool") {
+		t.Errorf("unknown tool = %+v", r)
+	}
+}
@@ -0,0 +1,440 @@
+package tools
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	// maxListEntries bounds list_dir and glob before their results are
+	// fitted to OutputBudget.
+	maxListEntries = 2000
+	maxListDepth   = 10
+	// maxGrepMatches and maxGrepContext bound grep's arguments.
+	maxGrepMatches = 500
+	maxGrepContext = 10
+	// maxScanBytes skips files too large to search or count lines in.
+	maxScanBytes = 5 << 20
+)
+
+// skipDirs are never descended into by list_dir, glob and grep.
+var skipDirs = map[string]bool{".git": true, "node_modules": true}
+
+type dirEntry struct {
+	Path string `json:"path"`
+	Type string `json:"type"`
+	Size int64  `json:"size,omitempty"`
+}
+
+func executeListDir(rawArgs string) ToolResult {
+	var args struct {
+		Path          string `json:"path"`
+		Depth         int    `json:"depth"`
+		IncludeHidden bool   `json:"include_hidden"`
+	}
+	if err := json.Unmarshal([]byte(rawArgs), &args); err != nil {
+		return ToolResult{Error: "failed to parse arguments: " + err.Error()}
+	}
+	root := orDot(args.Path)
+	depth := min(max(args.Depth, 1), maxListDepth)
+
+	var entries []dirEntry
+	limited := false
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if path == root {
+				return err
+			}
+			return nil // unreadable entries are left out
+		}
+		if path == root {
+			return nil
+		}
+		rel, _ := filepath.Rel(root, path)
+		if (!args.IncludeHidden && strings.HasPrefix(d.Name(), ".")) || (d.IsDir() && skipDirs[d.Name()]) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if len(entries) == maxListEntries {
+			limited = true
+			return filepath.SkipAll
+		}
+		entries = append(entries, newDirEntry(filepath.ToSlash(rel), d))
+		if d.IsDir() && strings.Count(rel, string(filepath.Separator))+1 >= depth {
+			return filepath.SkipDir
+		}
+		return nil
+	})
+	if err != nil {
+		return ToolResult{Error: err.Error()}
+	}
+	return ToolResult{Output: encodeFitting(len(entries), func(n int) any {
+		return struct {
+			Path      string     `json:"path"`
+			Depth     int        `json:"depth"`
+			Entries   []dirEntry `json:"entries"`
+			Truncated bool       `json:"truncated,omitempty"`
+		}{root, depth, nonNil(entries[:n]), limited || n < len(entries)}
+	})}
+}
+
+func newDirEntry(rel string, d fs.DirEntry) dirEntry {
+	e := dirEntry{Path: rel, Type: "file"}
+	switch {
+	case d.Type()&fs.ModeSymlink != 0:
+		e.Type = "symlink"
+	case d.IsDir():
+		e.Type = "dir"
+	case !d.Type().IsRegular():
+		e.Type = "other"
+	default:
+		if info, err := d.Info(); err == nil {
+			e.Size = info.Size()
+		}
+	}
+	return e
+}
+
+func executeGlob(rawArgs string) ToolResult {
+	var args struct {
+		Pattern string `json:"pattern"`
+		Path    string `json:"path"`
+	}
+	if err := json.Unmarshal([]byte(rawArgs), &args); err != nil {
+		return ToolResult{Error: "failed to parse arguments: " + err.Error()}
+	}
+	if args.Pattern == "" {
+		return ToolResult{Error: "pattern is required"}
+	}
+	pattern := strings.Split(filepath.ToSlash(args.Pattern), "/")
+	for _, seg := range pattern {
+		if _, err := filepath.Match(seg, ""); err != nil {
+			return ToolResult{Error: fmt.Sprintf("invalid pattern %q: %v", args.Pattern, err)}
+		}
+	}
+	root := orDot(args.Path)
+
+	var matches []string
+	limited := false
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if path == root {
+				return err
+			}
+			return nil
+		}
+		if path == root {
+			return nil
+		}
+		if d.IsDir() && skipDirs[d.Name()] {
+			return filepath.SkipDir
+		}
+		rel, _ := filepath.Rel(root, path)
+		if matchSegments(pattern, strings.Split(filepath.ToSlash(rel), "/")) {
+			if len(matches) == maxListEntries {
+				limited = true
+				return filepath.SkipAll
+			}
+			matches = append(matches, filepath.ToSlash(rel))
+		}
+		return nil
+	})
+	if err != nil {
+		return ToolResult{Error: err.Error()}
+	}
+	return ToolResult{Output: encodeFitting(len(matches), func(n int) any {
+		return struct {
+			Pattern   string   `json:"pattern"`
+			Path      string   `json:"path"`
+			Matches   []string `json:"matches"`
+			Truncated bool     `json:"truncated,omitempty"`
+		}{args.Pattern, root, nonNil(matches[:n]), limited || n < len(matches)}
+	})}
+}
+
+// matchSegments matches a slash-separated path against a glob split the
+// same way. A "**" segment matches any number of path segments; the others
+// follow filepath.Match, so "*" never crosses a slash.
+func matchSegments(pattern, path []string) bool {
+	if len(pattern) == 0 {
+		return len(path) == 0
+	}
+	if pattern[0] == "**" {
+		for i := 0; i <= len(path); i++ {
+			if matchSegments(pattern[1:], path[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(path) == 0 {
+		return false
+	}
+	ok, _ := filepath.Match(pattern[0], path[0])
+	return ok && matchSegments(pattern[1:], path[1:])
+}
+
+type grepMatch struct {
+	File   string   `json:"file"`
+	Line   int      `json:"line"`
+	Text   string   `json:"text"`
+	Before []string `json:"before,omitempty"`
+	After  []string `json:"after,omitempty"`
+}
+
+func executeGrep(rawArgs string) ToolResult {
+	var args struct {
+		Pattern    string `json:"pattern"`
+		Path       string `json:"path"`
+		Glob       string `json:"glob"`
+		Context    int    `json:"context"`
+		IgnoreCase bool   `json:"ignore_case"`
+		MaxMatches int    `json:"max_matches"`
+	}
+	if err := json.Unmarshal([]byte(rawArgs), &args); err != nil {
+		return ToolResult{Error: "failed to parse arguments: " + err.Error()}
+	}
+	expr := args.Pattern
+	if args.IgnoreCase {
+		expr = "(?i)" + expr
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return ToolResult{Error: "invalid pattern: " + err.Error()}
+	}
+	if _, err := filepath.Match(args.Glob, ""); err != nil {
+		return ToolResult{Error: fmt.Sprintf("invalid glob %q: %v", args.Glob, err)}
+	}
+	root := orDot(args.Path)
+	context := min(max(args.Context, 0), maxGrepContext)
+	limit := args.MaxMatches
+	if limit <= 0 || limit > maxGrepMatches {
+		limit = 100
+	}
+
+	var matches []grepMatch
+	searched := 0
+	limited := false
+	err = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if path == root {
+				return err
+			}
+			return nil
+		}
+		if d.IsDir() {
+			if path != root && skipDirs[d.Name()] {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		if args.Glob != "" {
+			if ok, _ := filepath.Match(args.Glob, d.Name()); !ok {
+				return nil
+			}
+		}
+		name := filepath.ToSlash(path)
+		found, ok := grepFile(path, name, re, context, limit-len(matches))
+		if !ok {
+			return nil
+		}
+		searched++
+		matches = append(matches, found...)
+		if len(matches) >= limit {
+			limited = true
+			return filepath.SkipAll
+		}
+		return nil
+	})
+	if err != nil {
+		return ToolResult{Error: err.Error()}
+	}
+	return ToolResult{Output: encodeFitting(len(matches), func(n int) any {
+		return struct {
+			Pattern       string      `json:"pattern"`
+			Matches       []grepMatch `json:"matches"`
+			FilesSearched int         `json:"files_searched"`
+			Truncated     bool        `json:"truncated,omitempty"`
+		}{args.Pattern, nonNil(matches[:n]), searched, limited || n < len(matches)}
+	})}
+}
+
+// grepFile returns up to limit matches of re in the file at path, each with
+// context lines around it. ok is false for files that were not searched:
+// unreadable, too large, or binary.
+func grepFile(path, name string, re *regexp.Regexp, context, limit int) (matches []grepMatch, ok bool) {
+	data, ok := readText(path)
+	if !ok {
+		return nil, false
+	}
+	lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	for i, line := range lines {
+		if len(matches) == limit {
+			break
+		}
+		if !re.MatchString(line) {
+			continue
+		}
+		m := grepMatch{File: name, Line: i + 1, Text: line}
+		if context > 0 {
+			m.Before = lines[max(i-context, 0):i]
+			m.After = lines[i+1 : min(i+1+context, len(lines))]
+		}
+		matches = append(matches, m)
+	}
+	return matches, true
+}
+
+// readText reads a file for searching; ok is false when it can't be read,
+// is over maxScanBytes, or looks binary (a NUL in its first 8KB).
+func readText(path string) (data []byte, ok bool) {
+	info, err := os.Stat(path)
+	if err != nil || info.Size() > maxScanBytes {
+		return nil, false
+	}
+	data, err = os.ReadFile(path)
+	if err != nil || bytes.IndexByte(data[:min(len(data), 8<<10)], 0) >= 0 {
+		return nil, false
+	}
+	return data, true
+}
+
+func executeFileInfo(rawArgs string) ToolResult {
+	var args struct {
+		Path string `json:"path"`
+	}
+	if err := json.Unmarshal([]byte(rawArgs), &args); err != nil {
+		return ToolResult{Error: "failed to parse arguments: " + err.Error()}
+	}
+	info, err := os.Lstat(args.Path)
+	if err != nil {
+		return ToolResult{Error: err.Error()}
+	}
+	out := struct {
+		Path          string `json:"path"`
+		AbsPath       string `json:"abs_path"`
+		Type          string `json:"type"`
+		Size          int64  `json:"size"`
+		Mode          string `json:"mode"`
+		Modified      string `json:"modified"`
+		SymlinkTarget string `json:"symlink_target,omitempty"`
+		Lines         *int   `json:"lines,omitempty"`
+		Binary        bool   `json:"binary,omitempty"`
+		Entries       *int   `json:"entries,omitempty"`
+	}{
+		Path:     args.Path,
+		Type:     "file",
+		Size:     info.Size(),
+		Mode:     info.Mode().String(),
+		Modified: info.ModTime().UTC().Format(time.RFC3339),
+	}
+	out.AbsPath, _ = filepath.Abs(args.Path)
+	switch mode := info.Mode(); {
+	case mode&fs.ModeSymlink != 0:
+		out.Type = "symlink"
+		out.SymlinkTarget, _ = os.Readlink(args.Path)
+	case mode.IsDir():
+		out.Type = "dir"
+		if entries, err := os.ReadDir(args.Path); err == nil {
+			n := len(entries)
+			out.Entries = &n
+		}
+	case !mode.IsRegular():
+		out.Type = "other"
+	case info.Size() <= maxScanBytes:
+		if n, binary, err := countLines(args.Path); err == nil {
+			if binary {
+				out.Binary = true
+			} else {
+				out.Lines = &n
+			}
+		}
+	}
+	b, _ := json.Marshal(out)
+	return ToolResult{Output: string(b)}
+}
+
+// countLines counts the lines of a text file, reporting binary when a NUL
+// byte shows up.
+func countLines(path string) (n int, binary bool, err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, false, err
+	}
+	defer f.Close()
+	r := bufio.NewReader(f)
+	var last byte = '\n'
+	buf := make([]byte, 32<<10)
+	for {
+		k, err := r.Read(buf)
+		if bytes.IndexByte(buf[:k], 0) >= 0 {
+			return 0, true, nil
+		}
+		n += bytes.Count(buf[:k], []byte{'\n'})
+		if k > 0 {
+			last = buf[k-1]
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return 0, false, err
+		}
+	}
+	if last != '\n' {
+		n++ // unterminated last line
+	}
+	return n, false, nil
+}
+
+// encodeFitting marshals build(n) for the largest n <= total whose encoding
+// fits in OutputBudget, so a long listing is cut short but stays valid
+// JSON. build reports truncation itself when n < total.
+func encodeFitting(total int, build func(n int) any) string {
+	encode := func(n int) []byte {
+		b, _ := json.Marshal(build(n))
+		return b
+	}
+	if b := encode(total); len(b) <= OutputBudget {
+		return string(b)
+	}
+	lo, hi := 0, total
+	for lo < hi {
+		mid := (lo + hi + 1) / 2
+		if len(encode(mid)) <= OutputBudget {
+			lo = mid
+		} else {
+			hi = mid - 1
+		}
+	}
+	return string(encode(lo))
+}
+
+// nonNil makes an empty result encode as [] rather than null.
+func nonNil[T any](s []T) []T {
+	if s == nil {
+		return []T{}
+	}
+	return s
+}
+
+func orDot(path string) string {
+	if path == "" {
+		return "."
+	}
+	return path
+}
@@ -0,0 +1,277 @@
+package tools
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"docsgpt-cli/internal/sandbox"
+)
+
+// writeTree creates files (path → content) under a temp dir and returns it.
+func writeTree(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+// run executes a tool with args marshalled to JSON and decodes its output.
+func run(t *testing.T, name string, args map[string]any, out any) {
+	t.Helper()
+	raw, _ := json.Marshal(args)
+	res := Execute(name, string(raw), 5*time.Second)
+	if res.Error != "" {
+		t.Fatalf("%s: %s", name, res.Error)
+	}
+	if err := json.Unmarshal([]byte(res.Output), out); err != nil {
+		t.Fatalf("%s output is not JSON: %v\n%s", name, err, res.Output)
+	}
+}
+
+var tree = map[string]string{
+	"main.go":             "package main\n\nfunc main() {\n\tprintln(\"hi\")\n}\n",
+	"README.md":           "# demo\n",
+	".env":                "SECRET=1\n",
+	"cmd/run.go":          "package cmd\n\n// TODO: flags\n",
+	"cmd/run_test.go":     "package cmd\n",
+	"cmd/sub/deep.go":     "package sub // TODO later\n",
+	".git/config":         "[core]\n",
+	"node_modules/x.js":   "// TODO\n",
+	"data/blob.bin":       "\x00\x01TODO",
+	"docs/guide/intro.md": "todo: write\n",
+}
+
+func TestListDir(t *testing.T) {
+	dir := writeTree(t, tree)
+	tests := []struct {
+		name string
+		args map[string]any
+		want []string
+	}{
+		{"top level", map[string]any{"path": dir}, []string{"README.md", "cmd", "data", "docs", "main.go"}},
+		{"depth 2", map[string]any{"path": dir, "depth": 2}, []string{"README.md", "cmd", "cmd/run.go", "cmd/run_test.go", "cmd/sub", "data", "data/blob.bin", "docs", "docs/guide", "main.go"}},
+		{"hidden", map[string]any{"path": dir, "include_hidden": true}, []string{".env", "README.md", "cmd", "data", "docs", "main.go"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out struct {
+				Entries []dirEntry
+			}
+			run(t, "list_dir", tt.args, &out)
+			var got []string
+			for _, e := range out.Entries {
+				got = append(got, e.Path)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("entries = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGlob(t *testing.T) {
+	dir := writeTree(t, tree)
+	tests := []struct {
+		pattern string
+		want    []string
+	}{
+		{"*.go", []string{"main.go"}},
+		{"**/*.go", []string{"cmd/run.go", "cmd/run_test.go", "cmd/sub/deep.go", "main.go"}},
+		{"cmd/*_test.go", []string{"cmd/run_test.go"}},
+		{"docs/**", []string{"docs", "docs/guide", "docs/guide/intro.md"}},
+		{"**/*.js", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.pattern, func(t *testing.T) {
+			var out struct {
+				Matches []string
+			}
+			run(t, "glob", map[string]any{"pattern": tt.pattern, "path": dir}, &out)
+			if strings.Join(out.Matches, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("matches = %v, want %v", out.Matches, tt.want)
+			}
+		})
+	}
+}
+
+func TestGrep(t *testing.T) {
+	dir := writeTree(t, tree)
+	var out struct {
+		Matches       []grepMatch
+		FilesSearched int `json:"files_searched"`
+	}
+	run(t, "grep", map[string]any{"pattern": "todo", "path": dir, "ignore_case": true, "glob": "*.go", "context": 1}, &out)
+	if len(out.Matches) != 2 || out.FilesSearched != 4 {
+		t.Fatalf("matches = %+v, files searched = %d; want 2 matches in 4 .go files", out.Matches, out.FilesSearched)
+	}
+	m := out.Matches[0]
+	if !strings.HasSuffix(m.File, "cmd/run.go") || m.Line != 3 || m.Text != "// TODO: flags" {
+		t.Errorf("first match = %+v", m)
+	}
+	if len(m.Before) != 1 || m.Before[0] != "" || len(m.After) != 0 {
+		t.Errorf("context = %q / %q, want one line before and none after", m.Before, m.After)
+	}
+
+	// Binary files, .git and node_modules are not searched.
+	run(t, "grep", map[string]any{"pattern": "TODO|core", "path": dir}, &out)
+	for _, m := range out.Matches {
+		if strings.Contains(m.File, ".git/") || strings.Contains(m.File, "node_modules") || strings.HasSuffix(m.File, ".bin") {
+			t.Errorf("unexpected match in %s", m.File)
+		}
+	}
+
+	res := Execute("grep", `{"pattern":"("}`, time.Second)
+	if !strings.Contains(res.Error, "invalid pattern") {
+		t.Errorf("bad regex: error = %q", res.Error)
+	}
+}
+
+func TestFileInfo(t *testing.T) {
+	dir := writeTree(t, tree)
+	var out struct {
+		Type   string
+		Size   int64
+		Lines  *int
+		Binary bool
+	}
+	run(t, "file_info", map[string]any{"path": filepath.Join(dir, "main.go")}, &out)
+	if out.Type != "file" || out.Size != int64(len(tree["main.go"])) || out.Lines == nil || *out.Lines != 5 {
+		t.Errorf("main.go info = %+v", out)
+	}
+	out.Lines = nil
+	run(t, "file_info", map[string]any{"path": filepath.Join(dir, "data/blob.bin")}, &out)
+	if !out.Binary || out.Lines != nil {
+		t.Errorf("blob.bin info = %+v, want binary without a line count", out)
+	}
+	run(t, "file_info", map[string]any{"path": filepath.Join(dir, "cmd")}, &out)
+	if out.Type != "dir" {
+		t.Errorf("cmd type = %q", out.Type)
+	}
+	if res := Execute("file_info", `{"path":"/does/not/exist"}`, time.Second); res.Error == "" {
+		t.Error("missing path should be an error")
+	}
+}
+
+func TestHTTPGet(t *testing.T) {
+	HTTPAllowPrivate = true
+	t.Cleanup(func() { HTTPAllowPrivate = false })
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/text":
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			fmt.Fprint(w, strings.Repeat("a", 100))
+		case "/image":
+			w.Header().Set("Content-Type", "image/png")
+			w.Write([]byte{0x89, 'P', 'N', 'G'})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	var out struct {
+		Status    int
+		Body      string
+		Binary    bool
+		Truncated bool
+	}
+	run(t, "http_get", map[string]any{"url": srv.URL + "/text", "max_bytes": 10}, &out)
+	if out.Status != 200 || out.Body != strings.Repeat("a", 10) || !out.Truncated {
+		t.Errorf("text = %+v", out)
+	}
+	out = struct {
+		Status    int
+		Body      string
+		Binary    bool
+		Truncated bool
+	}{}
+	run(t, "http_get", map[string]any{"url": srv.URL + "/image"}, &out)
+	if !out.Binary || out.Body != "" {
+		t.Errorf("image = %+v, want binary without a body", out)
+	}
+	run(t, "http_get", map[string]any{"url": srv.URL + "/missing"}, &out)
+	if out.Status != 404 {
+		t.Errorf("status = %d, want 404", out.Status)
+	}
+	if res := Execute("http_get", `{"url":"file:///etc/passwd"}`, time.Second); !strings.Contains(res.Error, "only http and https") {
+		t.Errorf("file URL: error = %q", res.Error)
+	}
+}
+
+func TestHTTPGetRefusesPrivateAddresses(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("internal"))
+	}))
+	defer srv.Close()
+
+	for _, url := range []string{srv.URL, "http://169.254.169.254/latest/meta-data/", "http://[::1]:1/", "http://10.0.0.1:1/", "http://100.100.100.200:1/"} {
+		res := Execute("http_get", `{"url":`+jsonString(url)+`}`, time.Second)
+		if !strings.Contains(res.Error, "local or private address") {
+			t.Errorf("%s: result = %+v, want it refused", url, res)
+		}
+	}
+	// Through a proxy, the proxy's address would be checked, not the target's.
+	if httpClient.Transport.(*http.Transport).Proxy != nil {
+		t.Error("http_get must not use a proxy from the environment")
+	}
+
+	if IsReadOnly("http_get") {
+		t.Error("http_get sends data off the machine and should not be batched as read-only")
+	}
+}
+
+func TestHTTPGetSandboxWithoutNetwork(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer srv.Close()
+	HTTPAllowPrivate = true
+	Sandbox = &sandbox.Options{Writable: []string{t.TempDir()}}
+	t.Cleanup(func() { HTTPAllowPrivate, Sandbox = false, nil })
+
+	if res := Execute("http_get", `{"url":`+jsonString(srv.URL)+`}`, time.Second); !strings.Contains(res.Error, "no network access") {
+		t.Errorf("sandbox without network: result = %+v, want it refused", res)
+	}
+	Sandbox.Network = true
+	if res := Execute("http_get", `{"url":`+jsonString(srv.URL)+`}`, time.Second); res.Error != "" {
+		t.Errorf("sandbox with network: error = %q", res.Error)
+	}
+}
+
+func TestEncodeFittingStaysValidJSON(t *testing.T) {
+	items := make([]string, 5000)
+	for i := range items {
+		items[i] = fmt.Sprintf("entry-%04d", i)
+	}
+	out := encodeFitting(len(items), func(n int) any {
+		return struct {
+			Items     []string `json:"items"`
+			Truncated bool     `json:"truncated,omitempty"`
+		}{items[:n], n < len(items)}
+	})
+	if len(out) > OutputBudget {
+		t.Fatalf("output is %d bytes, over the %d budget", len(out), OutputBudget)
+	}
+	var doc struct {
+		Items     []string
+		Truncated bool
+	}
+	if err := json.Unmarshal([]byte(out), &doc); err != nil || !doc.Truncated || len(doc.Items) == 0 {
+		t.Errorf("doc = %d items, truncated %v, err %v", len(doc.Items), doc.Truncated, err)
+	}
+}
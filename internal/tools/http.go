@@ -0,0 +1,158 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	// defaultHTTPBytes and maxHTTPBytes bound how much of a response body
+	// http_get reads.
+	defaultHTTPBytes = 8 << 10
+	maxHTTPBytes     = 64 << 10
+	// maxHTTPTimeout caps http_get however long the command timeout is.
+	maxHTTPTimeout = 30 * time.Second
+)
+
+// HTTPAllowPrivate lets http_get reach loopback, private and link-local
+// addresses, such as a dev server on localhost. Off, those are refused so
+// the model cannot probe the local network or a cloud metadata endpoint.
+var HTTPAllowPrivate bool
+
+// httpClient is the client behind http_get; redirects are followed up to
+// the standard library's limit of ten. Addresses are checked when they are
+// dialed, after DNS resolution, so neither a redirect nor a name pointing
+// at 127.0.0.1 gets around HTTPAllowPrivate. It ignores HTTP_PROXY and
+// HTTPS_PROXY: through a proxy, the dialed address would be the proxy's and
+// the target would go unchecked.
+var httpClient = &http.Client{
+	Transport: &http.Transport{
+		Proxy: nil,
+		DialContext: (&net.Dialer{
+			Timeout: maxHTTPTimeout,
+			Control: checkDialAddress,
+		}).DialContext,
+		TLSHandshakeTimeout: 10 * time.Second,
+	},
+}
+
+// sharedAddressSpace is 100.64.0.0/10, the carrier-grade NAT range, which
+// net.IP.IsPrivate leaves out.
+var sharedAddressSpace = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+// checkDialAddress refuses connections to non-public addresses unless
+// HTTPAllowPrivate is set.
+func checkDialAddress(network, address string, _ syscall.RawConn) error {
+	if HTTPAllowPrivate {
+		return nil
+	}
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() ||
+		sharedAddressSpace.Contains(ip) {
+		return fmt.Errorf("%s is a local or private address; set http_allow_private in the config to allow it", host)
+	}
+	return nil
+}
+
+func executeHTTPGet(rawArgs string, timeout time.Duration) ToolResult {
+	var args struct {
+		URL      string `json:"url"`
+		MaxBytes int    `json:"max_bytes"`
+	}
+	if err := json.Unmarshal([]byte(rawArgs), &args); err != nil {
+		return ToolResult{Error: "failed to parse arguments: " + err.Error()}
+	}
+	// http_get runs in-process, outside the sandbox, so it honors the
+	// sandbox's network setting itself.
+	if Sandbox != nil && !Sandbox.Network {
+		return ToolResult{Error: "the sandbox has no network access; http_get is unavailable (use --sandbox=network to allow it)"}
+	}
+	u, err := url.Parse(args.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return ToolResult{Error: fmt.Sprintf("invalid URL %q: only http and https URLs are supported", args.URL)}
+	}
+	limit := args.MaxBytes
+	if limit <= 0 {
+		limit = defaultHTTPBytes
+	}
+	limit = min(limit, maxHTTPBytes)
+
+	ctx, cancel := context.WithTimeout(context.Background(), min(timeout, maxHTTPTimeout))
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return ToolResult{Error: err.Error()}
+	}
+	req.Header.Set("User-Agent", "docsgpt-cli")
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return ToolResult{Error: "request timed out"}
+		}
+		return ToolResult{Error: err.Error()}
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, int64(limit)+1))
+	if err != nil {
+		return ToolResult{Error: "reading response: " + err.Error()}
+	}
+	out := struct {
+		URL         string `json:"url"`
+		Status      int    `json:"status"`
+		ContentType string `json:"content_type,omitempty"`
+		Body        string `json:"body,omitempty"`
+		Binary      bool   `json:"binary,omitempty"`
+		Truncated   bool   `json:"truncated,omitempty"`
+	}{
+		URL:         resp.Request.URL.String(),
+		Status:      resp.StatusCode,
+		ContentType: resp.Header.Get("Content-Type"),
+	}
+	if len(body) > limit {
+		body = body[:limit]
+		out.Truncated = true
+	}
+	if isTextType(out.ContentType, body) {
+		out.Body = strings.ToValidUTF8(string(body), "")
+	} else {
+		out.Binary = true
+	}
+	b, _ := json.Marshal(out)
+	return ToolResult{Output: string(b)}
+}
+
+// isTextType reports whether a response body can be shown as text: a
+// text-like content type, or none at all with a valid UTF-8 body. A body cut
+// mid-rune still counts as valid.
+func isTextType(contentType string, body []byte) bool {
+	mt, _, _ := mime.ParseMediaType(contentType)
+	switch {
+	case strings.HasPrefix(mt, "text/"),
+		strings.HasSuffix(mt, "json"), strings.HasSuffix(mt, "xml"),
+		mt == "application/javascript", mt == "application/x-yaml", mt == "application/yaml":
+		return true
+	case mt == "":
+		trimmed := body
+		for i := 0; i < utf8.UTFMax && len(trimmed) > 0 && !utf8.Valid(trimmed); i++ {
+			trimmed = trimmed[:len(trimmed)-1]
+		}
+		return utf8.Valid(trimmed)
+	}
+	return false
+}
@@ -0,0 +1,116 @@
+package tools
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// DefaultOutputBudget is the default size, in bytes, of one tool result.
+const DefaultOutputBudget = 10 * 1024 // 10KB
+
+// OutputBudget caps the size of one tool result: command output is cut to
+// its head and tail, read_file pages, and the JSON tools list fewer
+// entries. The CLI sets it from the output_budget setting.
+var OutputBudget = DefaultOutputBudget
+
+// markerRoom is set aside from the budget for the elided-lines marker.
+const markerRoom = 220
+
+// TruncateOutput shortens output to at most maxBytes (OutputBudget when
+// maxBytes <= 0) by keeping its beginning and its end — the end of a build
+// log or test run is usually what matters — and replacing the middle with
+// a marker saying how much was left out.
+func TruncateOutput(output string, maxBytes int) string {
+	if maxBytes <= 0 {
+		maxBytes = OutputBudget
+	}
+	if len(output) <= maxBytes {
+		return output
+	}
+	room := max(maxBytes-markerRoom, 0)
+	headLen := room * 2 / 5
+	head := output[:cutHead(output, headLen)]
+	tail := output[cutTail(output, len(output)-(room-headLen)):]
+	elided := output[len(head) : len(output)-len(tail)]
+
+	marker := fmt.Sprintf("... [%d lines (%d bytes) elided; to see them, filter the output (grep, tail) or redirect it to a file and page through it with read_file offset/limit] ...\n",
+		strings.Count(elided, "\n"), len(elided))
+	if head != "" && !strings.HasSuffix(head, "\n") {
+		marker = "\n" + marker
+	}
+	return head + marker + tail
+}
+
+// cutHead returns where to end a head of about n bytes of s: after the
+// last newline in its second half, else at a rune boundary.
+func cutHead(s string, n int) int {
+	if i := strings.LastIndexByte(s[:n], '\n'); i >= n/2 {
+		return i + 1
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return n
+}
+
+// cutTail returns where to start a tail beginning about at byte i of s:
+// after the first newline in the first half of what follows, else at a
+// rune boundary.
+func cutTail(s string, i int) int {
+	if j := strings.IndexByte(s[i:], '\n'); j >= 0 && j < (len(s)-i)/2 {
+		return i + j + 1
+	}
+	for i < len(s) && !utf8.RuneStart(s[i]) {
+		i++
+	}
+	return i
+}
+
+// pageLines returns lines offset.. (1-based) of content, at most limit of
+// them (0 means no limit), cut to whole lines that fit in budget, and a
+// note telling the model how to read on when lines were left out. A
+// single line longer than the budget is cut short.
+func pageLines(content string, offset, limit, budget int) (page, note string) {
+	lines := strings.SplitAfter(content, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	total := len(lines)
+	if total == 0 {
+		return "", ""
+	}
+	if offset < 1 {
+		offset = 1
+	}
+	if offset > total {
+		return "", fmt.Sprintf("[the file has %d lines; offset %d is past the end]", total, offset)
+	}
+	end := total
+	if limit > 0 {
+		end = min(offset-1+limit, total)
+	}
+
+	var b strings.Builder
+	last := offset - 1
+	for _, line := range lines[offset-1 : end] {
+		if b.Len()+len(line) > budget {
+			if b.Len() == 0 {
+				// Keep what fits of an oversized line.
+				b.WriteString(line[:cutHead(line, budget)])
+				b.WriteString("\n")
+				last++
+				note = fmt.Sprintf("[line %d is longer than the output budget and was cut] ", last)
+			}
+			break
+		}
+		b.WriteString(line)
+		last++
+	}
+	if last < total {
+		note += fmt.Sprintf("[showing lines %d-%d of %d; call read_file with offset %d to read on]", offset, last, total, last+1)
+	} else if offset > 1 || note != "" {
+		note += fmt.Sprintf("[showing lines %d-%d of %d]", offset, last, total)
+	}
+	return b.String(), note
+}
@@ -0,0 +1,25 @@
+//go:build !windows
+
+package tools
+
+import (
+	"os/exec"
+	"strings"
+	"syscall"
+)
+
+// setProcessGroup starts the command in a process group of its own and
+// makes cancelling it kill the whole group, so a timeout or Ctrl-C also
+// stops whatever the shell started. Being outside the terminal's foreground
+// group, the command does not see the terminal's Ctrl-C itself.
+func setProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+}
+
+// shellQuote quotes s as one word for sh.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
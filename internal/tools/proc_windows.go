@@ -0,0 +1,27 @@
+//go:build windows
+
+package tools
+
+import (
+	"os/exec"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// setProcessGroup starts the command in a process group of its own, which
+// keeps the console's Ctrl-C from reaching it, and makes cancelling it kill
+// its whole process tree.
+func setProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{CreationFlags: syscall.CREATE_NEW_PROCESS_GROUP}
+	cmd.Cancel = func() error {
+		return exec.Command("taskkill", "/T", "/F", "/PID", strconv.Itoa(cmd.Process.Pid)).Run()
+	}
+}
+
+// shellQuote quotes s as one argument for cmd.exe. cmd has no quoting that
+// stops %VAR% expansion, so percent signs are dropped.
+func shellQuote(s string) string {
+	s = strings.ReplaceAll(s, "%", "")
+	return `"` + strings.ReplaceAll(s, `"`, `""`) + `"`
+}
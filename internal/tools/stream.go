@@ -0,0 +1,101 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	"docsgpt-cli/internal/display"
+)
+
+// errInterrupted is the cancel cause of a command stopped by Interrupt.
+var errInterrupted = errors.New("interrupted")
+
+// running holds the stop function of the run_command in progress.
+var running struct {
+	sync.Mutex
+	stop func()
+}
+
+// Interrupt stops the run_command in progress, if any, and reports whether
+// there was one. Ctrl-C handlers call it first, so Ctrl-C during a command
+// ends just the command — the model still gets its output so far — rather
+// than the whole turn.
+func Interrupt() bool {
+	running.Lock()
+	defer running.Unlock()
+	if running.stop == nil {
+		return false
+	}
+	running.stop()
+	return true
+}
+
+// track makes cancel(errInterrupted) the target of Interrupt until the
+// returned func is called.
+func track(cancel context.CancelCauseFunc) (untrack func()) {
+	running.Lock()
+	running.stop = func() { cancel(errInterrupted) }
+	running.Unlock()
+	return func() {
+		running.Lock()
+		running.stop = nil
+		running.Unlock()
+	}
+}
+
+// maxPendingLine bounds a line held back while waiting for its newline,
+// such as a progress bar redrawn with \r.
+const maxPendingLine = 4096
+
+// streamWriter echoes a command's output to w one line at a time as it
+// arrives, and keeps all of it for the tool result. stdout and stderr share
+// one streamWriter, so the captured output keeps their interleaving.
+type streamWriter struct {
+	mu      sync.Mutex
+	w       io.Writer
+	all     bytes.Buffer
+	pending []byte
+}
+
+func (s *streamWriter) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.all.Write(p)
+	s.pending = append(s.pending, p...)
+	for {
+		i := bytes.IndexByte(s.pending, '\n')
+		if i < 0 {
+			break
+		}
+		fmt.Fprintln(s.w, display.Muted(string(s.pending[:i])))
+		s.pending = s.pending[i+1:]
+	}
+	if len(s.pending) > maxPendingLine {
+		s.flushLocked()
+	}
+	return len(p), nil
+}
+
+// Flush prints a last line that did not end in a newline.
+func (s *streamWriter) Flush() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.flushLocked()
+}
+
+func (s *streamWriter) flushLocked() {
+	if len(s.pending) > 0 {
+		fmt.Fprintln(s.w, display.Muted(string(s.pending)))
+		s.pending = nil
+	}
+}
+
+func (s *streamWriter) String() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.all.String()
+}
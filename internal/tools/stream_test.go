@@ -0,0 +1,130 @@
+package tools
+
+import (
+	"bytes"
+	"encoding/json"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"docsgpt-cli/internal/display"
+)
+
+// syncBuffer is a bytes.Buffer safe to read while a command writes to it.
+type syncBuffer struct {
+	mu sync.Mutex
+	b  bytes.Buffer
+}
+
+func (s *syncBuffer) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.b.Write(p)
+}
+
+func (s *syncBuffer) String() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.b.String()
+}
+
+// capturePrompts points Prompts at a buffer for the test.
+func capturePrompts(t *testing.T) *syncBuffer {
+	t.Helper()
+	display.InitTheme("dark")
+	buf := &syncBuffer{}
+	orig := Prompts
+	Prompts = buf
+	t.Cleanup(func() { Prompts = orig })
+	return buf
+}
+
+func runCommandArgsJSON(command string) string {
+	b, _ := json.Marshal(map[string]string{"command": command})
+	return string(b)
+}
+
+func TestRunCommandStreamsOutput(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sh")
+	}
+	prompts := capturePrompts(t)
+	done := make(chan ToolResult)
+	go func() {
+		done <- Execute("run_command", runCommandArgsJSON("echo first; echo oops >&2; sleep 2; echo last"), 10*time.Second)
+	}()
+
+	// Lines show up while the command is still running.
+	deadline := time.Now().Add(time.Second)
+	for !strings.Contains(prompts.String(), "oops") {
+		if time.Now().After(deadline) {
+			t.Fatalf("no streamed output after 1s: %q", prompts.String())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	select {
+	case r := <-done:
+		t.Fatalf("command finished too early: %+v", r)
+	default:
+	}
+
+	r := <-done
+	if r.Error != "" || r.Output != "first\noops\nlast\n" {
+		t.Errorf("result = %+v", r)
+	}
+}
+
+func TestInterruptStopsOnlyTheCommand(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sh")
+	}
+	prompts := capturePrompts(t)
+	if Interrupt() {
+		t.Fatal("Interrupt with no command running should report false")
+	}
+	done := make(chan ToolResult)
+	start := time.Now()
+	go func() {
+		// The background sleep holds the output pipe too; killing the
+		// process group must take it down with the shell.
+		done <- Execute("run_command", runCommandArgsJSON("echo partial; sleep 30 & sleep 30"), 60*time.Second)
+	}()
+	for !strings.Contains(prompts.String(), "partial") {
+		if time.Since(start) > 2*time.Second {
+			t.Fatal("command did not start")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if !Interrupt() {
+		t.Fatal("Interrupt should find the running command")
+	}
+
+	select {
+	case r := <-done:
+		if !strings.Contains(r.Error, "interrupted") || r.Output != "partial\n" {
+			t.Errorf("result = %+v", r)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("interrupted command still running after 5s")
+	}
+	if Interrupt() {
+		t.Error("Interrupt after the command ended should report false")
+	}
+}
+
+func TestRunCommandTimeout(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sh")
+	}
+	capturePrompts(t)
+	start := time.Now()
+	r := Execute("run_command", runCommandArgsJSON("echo before; sleep 30"), 300*time.Millisecond)
+	if r.Error != "command timed out" || r.Output != "before\n" {
+		t.Errorf("result = %+v", r)
+	}
+	if d := time.Since(start); d > 5*time.Second {
+		t.Errorf("timeout took %v", d)
+	}
+}
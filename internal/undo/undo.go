@@ -0,0 +1,296 @@
+// Package undo keeps the prior content of every file a tool writes, per
+// session, under ~/.docsgpt/undo/<session>/, so the writes can be listed
+// with a diff and reverted — from chat with /undo and /changes, or later
+// with `docsgpt-cli undo --session <id>`.
+package undo
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"docsgpt-cli/internal/config"
+)
+
+// undoHome returns the backup root (~/.docsgpt/undo). It is a var so tests
+// can redirect it to a temp directory.
+var undoHome = func() string {
+	return filepath.Join(config.Dir(), "undo")
+}
+
+// journalFile lists a session's writes; backups sit next to it as <seq>.bak.
+const journalFile = "journal.json"
+
+// ErrNothingToUndo reports a session with no write left to revert.
+var ErrNothingToUndo = errors.New("nothing to undo")
+
+// Entry is one file write made by a tool.
+type Entry struct {
+	Seq  int       `json:"seq"`
+	Path string    `json:"path"`
+	Tool string    `json:"tool"`
+	Time time.Time `json:"time"`
+	// Existed is false when the write created the file; undoing it then
+	// removes the file.
+	Existed bool        `json:"existed"`
+	Mode    fs.FileMode `json:"mode,omitempty"`
+	// After is the SHA-256 of what the tool wrote, so an undo can tell
+	// whether the file was changed again since.
+	After  string `json:"after"`
+	Undone bool   `json:"undone,omitempty"`
+}
+
+// Journal records the writes of one session. It is safe for concurrent use.
+type Journal struct {
+	ID  string
+	dir string
+	mu  sync.Mutex
+}
+
+// Open returns the journal of session id. Nothing is created on disk until
+// the first write.
+func Open(id string) *Journal {
+	return &Journal{ID: id, dir: filepath.Join(undoHome(), id)}
+}
+
+// Write backs up the current content of path (if any), then writes data to
+// it and records the write. A failed backup leaves the file untouched.
+func (j *Journal) Write(path, tool string, data []byte, perm fs.FileMode) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+	entries, err := j.read()
+	if err != nil {
+		return err
+	}
+	e := Entry{Seq: len(entries) + 1, Path: abs, Tool: tool, Time: time.Now(), After: digest(data)}
+
+	prior, err := os.ReadFile(abs)
+	switch {
+	case err == nil:
+		e.Existed = true
+		if info, err := os.Stat(abs); err == nil {
+			e.Mode = info.Mode().Perm()
+		}
+		if err := os.MkdirAll(j.dir, 0700); err != nil {
+			return fmt.Errorf("create undo directory: %w", err)
+		}
+		if err := os.WriteFile(j.backupPath(e.Seq), prior, 0600); err != nil {
+			return fmt.Errorf("back up %s: %w", path, err)
+		}
+	case !errors.Is(err, fs.ErrNotExist):
+		return err
+	}
+
+	if err := os.WriteFile(abs, data, perm); err != nil {
+		return err
+	}
+	return j.write(append(entries, e))
+}
+
+// Entries returns the session's writes, oldest first.
+func (j *Journal) Entries() ([]Entry, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.read()
+}
+
+// Undo reverts the most recent write not yet undone: the backup is restored,
+// or a file the write created is removed. Unless force is set, a file that
+// was changed again after the write is left alone and an error explains
+// why.
+func (j *Journal) Undo(force bool) (Entry, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	entries, err := j.read()
+	if err != nil {
+		return Entry{}, err
+	}
+	i := len(entries) - 1
+	for i >= 0 && entries[i].Undone {
+		i--
+	}
+	if i < 0 {
+		return Entry{}, ErrNothingToUndo
+	}
+	e := entries[i]
+
+	current, err := os.ReadFile(e.Path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return e, err
+	}
+	if !force && (err != nil || digest(current) != e.After) {
+		return e, fmt.Errorf("%s has changed since %s wrote it; use --force to restore the earlier version anyway", e.Path, e.Tool)
+	}
+
+	if e.Existed {
+		prior, err := os.ReadFile(j.backupPath(e.Seq))
+		if err != nil {
+			return e, fmt.Errorf("read backup of %s: %w", e.Path, err)
+		}
+		mode := e.Mode
+		if mode == 0 {
+			mode = 0644
+		}
+		if err := os.WriteFile(e.Path, prior, mode); err != nil {
+			return e, err
+		}
+	} else if err := os.Remove(e.Path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return e, err
+	}
+
+	entries[i].Undone = true
+	return entries[i], j.write(entries)
+}
+
+// Change summarizes what a session did to one file: its content before the
+// session's first write and now. Either side is nil when the file did not
+// exist.
+type Change struct {
+	Path     string
+	Writes   int
+	Original []byte
+	Current  []byte
+}
+
+// Equal reports whether the file ended up as it was before the session.
+func (c Change) Equal() bool {
+	return (c.Original == nil) == (c.Current == nil) && bytes.Equal(c.Original, c.Current)
+}
+
+// Changes lists every file the session wrote, in the order first touched,
+// counting only writes that have not been undone.
+func (j *Journal) Changes() ([]Change, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	entries, err := j.read()
+	if err != nil {
+		return nil, err
+	}
+	var out []Change
+	index := map[string]int{}
+	for _, e := range entries {
+		if e.Undone {
+			continue
+		}
+		if i, ok := index[e.Path]; ok {
+			out[i].Writes++
+			continue
+		}
+		c := Change{Path: e.Path, Writes: 1}
+		if e.Existed {
+			if c.Original, err = os.ReadFile(j.backupPath(e.Seq)); err != nil {
+				return nil, fmt.Errorf("read backup of %s: %w", e.Path, err)
+			}
+		}
+		index[e.Path] = len(out)
+		out = append(out, c)
+	}
+	for i := range out {
+		if data, err := os.ReadFile(out[i].Path); err == nil {
+			out[i].Current = data
+		}
+	}
+	return out, nil
+}
+
+func (j *Journal) backupPath(seq int) string {
+	return filepath.Join(j.dir, fmt.Sprintf("%d.bak", seq))
+}
+
+func (j *Journal) read() ([]Entry, error) {
+	data, err := os.ReadFile(filepath.Join(j.dir, journalFile))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var entries []Entry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("parse undo journal of %s: %w", j.ID, err)
+	}
+	return entries, nil
+}
+
+func (j *Journal) write(entries []Entry) error {
+	if err := os.MkdirAll(j.dir, 0700); err != nil {
+		return fmt.Errorf("create undo directory: %w", err)
+	}
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return err
+	}
+	// Write-then-rename so a crash mid-write never loses the journal.
+	path := filepath.Join(j.dir, journalFile)
+	if err := os.WriteFile(path+".tmp", data, 0600); err != nil {
+		return fmt.Errorf("write undo journal: %w", err)
+	}
+	return os.Rename(path+".tmp", path)
+}
+
+func digest(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// Resolve maps ref ("last", an id, or an unambiguous id prefix) to a
+// session that has an undo journal. "last" is the one written most
+// recently.
+func Resolve(ref string) (string, error) {
+	entries, err := os.ReadDir(undoHome())
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return "", err
+	}
+	type journal struct {
+		id  string
+		mod time.Time
+	}
+	var all []journal
+	for _, e := range entries {
+		info, err := os.Stat(filepath.Join(undoHome(), e.Name(), journalFile))
+		if e.IsDir() && err == nil {
+			all = append(all, journal{e.Name(), info.ModTime()})
+		}
+	}
+	if ref == "" || ref == "last" {
+		if len(all) == 0 {
+			return "", errors.New("no session has file changes to undo")
+		}
+		sort.Slice(all, func(i, j int) bool { return all[i].mod.After(all[j].mod) })
+		return all[0].id, nil
+	}
+	var matches []string
+	for _, j := range all {
+		if j.id == ref {
+			return ref, nil
+		}
+		if strings.HasPrefix(j.id, ref) {
+			matches = append(matches, j.id)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return "", fmt.Errorf("no file changes recorded for session %q", ref)
+	case 1:
+		return matches[0], nil
+	default:
+		return "", fmt.Errorf("session %q is ambiguous (%d matches)", ref, len(matches))
+	}
+}
@@ -0,0 +1,131 @@
+package undo
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func useTempHome(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	orig := undoHome
+	undoHome = func() string { return dir }
+	t.Cleanup(func() { undoHome = orig })
+	return dir
+}
+
+func readString(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestWriteAndUndo(t *testing.T) {
+	useTempHome(t)
+	work := t.TempDir()
+	existing := filepath.Join(work, "main.go")
+	created := filepath.Join(work, "new.txt")
+	os.WriteFile(existing, []byte("v0"), 0o644)
+
+	j := Open("s1")
+	for _, w := range []struct{ path, data string }{{existing, "v1"}, {created, "fresh"}, {existing, "v2"}} {
+		if err := j.Write(w.path, "write_file", []byte(w.data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	changes, err := j.Changes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 2 || changes[0].Writes != 2 || string(changes[0].Original) != "v0" || string(changes[0].Current) != "v2" ||
+		changes[1].Original != nil || string(changes[1].Current) != "fresh" {
+		t.Fatalf("changes = %+v", changes)
+	}
+
+	// Undo walks back newest first: v2 → v1, then the created file goes.
+	if e, err := j.Undo(false); err != nil || e.Seq != 3 {
+		t.Fatalf("undo 1 = %+v, %v", e, err)
+	}
+	if got := readString(t, existing); got != "v1" {
+		t.Errorf("after undo 1: %q, want v1", got)
+	}
+	if _, err := j.Undo(false); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(created); !os.IsNotExist(err) {
+		t.Errorf("created file should be removed, stat err = %v", err)
+	}
+	if _, err := j.Undo(false); err != nil {
+		t.Fatal(err)
+	}
+	if got := readString(t, existing); got != "v0" {
+		t.Errorf("after undo 3: %q, want v0", got)
+	}
+	if _, err := j.Undo(false); !errors.Is(err, ErrNothingToUndo) {
+		t.Errorf("err = %v, want ErrNothingToUndo", err)
+	}
+	if changes, _ := j.Changes(); len(changes) != 0 {
+		t.Errorf("undone writes should not be listed, got %+v", changes)
+	}
+}
+
+func TestUndoRefusesModifiedFile(t *testing.T) {
+	useTempHome(t)
+	path := filepath.Join(t.TempDir(), "f")
+	os.WriteFile(path, []byte("before"), 0o644)
+	j := Open("s1")
+	if err := j.Write(path, "edit_file", []byte("tool"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	os.WriteFile(path, []byte("user edit"), 0o644)
+
+	if _, err := j.Undo(false); err == nil || !strings.Contains(err.Error(), "has changed since") {
+		t.Fatalf("err = %v, want a refusal", err)
+	}
+	if got := readString(t, path); got != "user edit" {
+		t.Errorf("refused undo touched the file: %q", got)
+	}
+	if _, err := j.Undo(true); err != nil {
+		t.Fatal(err)
+	}
+	if got := readString(t, path); got != "before" {
+		t.Errorf("forced undo: %q, want before", got)
+	}
+}
+
+func TestResolve(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "f")
+	home := useTempHome(t)
+	for i, id := range []string{"20250102-090000-cccc", "20250101-120000-aaaa", "20250101-120000-aabb"} {
+		if err := Open(id).Write(path, "write_file", []byte(id), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		// "last" goes by when a journal was written, not by id.
+		mod := time.Now().Add(time.Duration(-i) * time.Hour)
+		os.Chtimes(filepath.Join(home, id, journalFile), mod, mod)
+	}
+	tests := []struct {
+		ref, want string
+		wantErr   bool
+	}{
+		{"last", "20250102-090000-cccc", false},
+		{"20250102", "20250102-090000-cccc", false},
+		{"20250101-120000-aabb", "20250101-120000-aabb", false},
+		{"20250101-120000-aa", "", true},
+		{"nope", "", true},
+	}
+	for _, tt := range tests {
+		got, err := Resolve(tt.ref)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("Resolve(%q) = %q, %v; want %q (err %v)", tt.ref, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
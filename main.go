@@ -1,7 +1,11 @@
 package main
 
-import "docsgpt-cli/cmd"
+import (
+	"docsgpt-cli/cmd"
+	"docsgpt-cli/internal/sandbox"
+)
 
 func main() {
-    cmd.Execute()
+	sandbox.RunHelperIfRequested()
+	cmd.Execute()
 }